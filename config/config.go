@@ -1,29 +1,44 @@
 package config
 
 import (
-	"github.com/BurntSushi/toml"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
 	"github.com/pkg/errors"
 
-	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/logger"
 )
 
 // Config is marshallable content of config.toml
 type Config struct {
-	Alias map[string][]string `toml:"alias"`
-	Build configBuild         `toml:"build"`
-	Get   configGet           `toml:"get"`
-	Edit  configEdit          `toml:"edit"`
+	Alias  map[string][]string `toml:"alias"`
+	Build  configBuild         `toml:"build"`
+	Get    configGet           `toml:"get"`
+	Edit   configEdit          `toml:"edit"`
+	System configSystem        `toml:"system"`
+	HTTP   configHTTP          `toml:"http"`
+	Log    configLog           `toml:"log"`
+	// Plugconf is a config for plugconf templates
+	Plugconf configPlugconf `toml:"plugconf"`
+	// Credentials are credentials of each host (e.g. "github.example.com")
+	Credentials map[string]configCredential `toml:"credentials"`
 }
 
 // configBuild is a config for 'volt build'.
 type configBuild struct {
-	Strategy string `toml:"strategy"`
+	Strategy   string `toml:"strategy"`
+	PerProfile *bool  `toml:"per_profile"`
+	Helptags   *bool  `toml:"helptags"`
 }
 
 // configGet is a config for 'volt get'.
 type configGet struct {
-	CreateSkeletonPlugconf *bool `toml:"create_skeleton_plugconf"`
-	FallbackGitCmd         *bool `toml:"fallback_git_cmd"`
+	CreateSkeletonPlugconf *bool  `toml:"create_skeleton_plugconf"`
+	FallbackGitCmd         *bool  `toml:"fallback_git_cmd"`
+	GitBackend             string `toml:"git_backend"`
 }
 
 // configEdit is a config for 'volt edit'.
@@ -31,11 +46,78 @@ type configEdit struct {
 	Editor string `toml:"editor"`
 }
 
+// configSystem is a config for system repositories.
+type configSystem struct {
+	// ReposPath is the search path of system repositories
+	ReposPath []string `toml:"repos_path"`
+}
+
+// configPlugconf is a config for plugconf templates.
+type configPlugconf struct {
+	// TemplateSources are local directories, "file://" paths, or git
+	// repository URLs, which are tried in order
+	TemplateSources []string `toml:"template_sources"`
+}
+
+// DefaultTemplateSource is the default source of plugconf templates.
+// The whole repository is cloned to $VOLTPATH/cache at the first time a
+// plugconf is created by get.git_backend ([http] settings apply to go-git).
+const DefaultTemplateSource = "https://github.com/vim-volt/plugconf-templates"
+
+var scpLikeURLRx = regexp.MustCompile(`^[\w.-]+@[\w.-]+:`)
+
+// configHTTP is a config for HTTP(S) access of volt and go-git.
+type configHTTP struct {
+	// Proxy is a proxy URL. If empty, $HTTP_PROXY, $HTTPS_PROXY and
+	// $NO_PROXY are used
+	Proxy string `toml:"proxy"`
+	// NoProxy are hosts (or domains) which are accessed without proxy
+	NoProxy []string `toml:"no_proxy"`
+	// Timeout is a duration string (e.g. "30s") of connecting and waiting
+	// for response
+	Timeout string `toml:"timeout"`
+	// CAFile is a PEM file of CA certificates added to system ones
+	CAFile string `toml:"ca_file"`
+	// InsecureHosts are hosts which are accessed without verifying TLS
+	// certificates
+	InsecureHosts []string `toml:"insecure_hosts"`
+}
+
+// configLog is a config for the log file.
+type configLog struct {
+	// File is a path of the log file, which records all messages including
+	// debug ones. If empty, messages are not recorded
+	File string `toml:"file"`
+	// Format is "text" or "json"
+	Format string `toml:"format"`
+}
+
+// configCredential is a credential to access private repositories.
+type configCredential struct {
+	// Username is optional because token authentication of most git hosting
+	// services accepts any user name
+	Username string `toml:"username"`
+	Token    string `toml:"token"`
+}
+
 const (
 	// SymlinkBuilder creates symlinks when 'volt build'.
 	SymlinkBuilder = "symlink"
 	// CopyBuilder copies/creates regular files when 'volt build'.
 	CopyBuilder = "copy"
+	// HardlinkStoreBuilder creates hard links to files of $VOLTPATH/store
+	// when 'volt build'.
+	HardlinkStoreBuilder = "hardlink-store"
+)
+
+const (
+	// AutoGitBackend uses go-git, and falls back to git command if
+	// get.fallback_git_cmd is true.
+	AutoGitBackend = "auto"
+	// GoGitBackend uses go-git.
+	GoGitBackend = "gogit"
+	// CLIGitBackend executes git command.
+	CLIGitBackend = "cli"
 )
 
 func initialConfigTOML() *Config {
@@ -43,56 +125,106 @@ func initialConfigTOML() *Config {
 	falseValue := false
 	return &Config{
 		Build: configBuild{
-			Strategy: SymlinkBuilder,
+			Strategy:   SymlinkBuilder,
+			PerProfile: &falseValue,
+			Helptags:   &trueValue,
 		},
 		Get: configGet{
 			CreateSkeletonPlugconf: &trueValue,
 			FallbackGitCmd:         &falseValue,
+			GitBackend:             AutoGitBackend,
 		},
 		Edit: configEdit{
 			Editor: "",
 		},
+		System: configSystem{
+			ReposPath: []string{},
+		},
+		Plugconf: configPlugconf{
+			TemplateSources: []string{DefaultTemplateSource},
+		},
+		HTTP: configHTTP{
+			Proxy:         "",
+			NoProxy:       []string{},
+			Timeout:       "60s",
+			CAFile:        "",
+			InsecureHosts: []string{},
+		},
+		Log: configLog{
+			File:   "",
+			Format: logger.TextFormat,
+		},
+		Credentials: map[string]configCredential{},
 	}
 }
 
-// Read reads from config.toml and returns Config
+// Read reads config files and environment variables (see readLayers()),
+// and returns Config
 func Read() (*Config, error) {
-	// Return initial lock.json struct if lockfile does not exist
-	configFile := pathutil.ConfigTOML()
-	initCfg := initialConfigTOML()
-	if !pathutil.Exists(configFile) {
-		return initCfg, nil
-	}
+	return readWith("", "")
+}
 
-	var cfg Config
-	if _, err := toml.DecodeFile(configFile, &cfg); err != nil {
+// readWith is Read, but content is used as config file "file" instead of
+// reading it. If file is empty, all config files are read.
+func readWith(file, content string) (*Config, error) {
+	layers, err := readLayersWith(file, content)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := decodeValues(effectiveValues(layers))
+	if err != nil {
 		return nil, err
 	}
-	merge(&cfg, initCfg)
-	if err := validate(&cfg); err != nil {
+	if err := validate(cfg); err != nil {
 		return nil, err
 	}
-	return &cfg, nil
+	return cfg, nil
 }
 
-func merge(cfg, initCfg *Config) {
-	if cfg.Build.Strategy == "" {
-		cfg.Build.Strategy = initCfg.Build.Strategy
+func validate(cfg *Config) error {
+	switch cfg.Build.Strategy {
+	case SymlinkBuilder, CopyBuilder, HardlinkStoreBuilder:
+	default:
+		return errors.Errorf("build.strategy is %q: valid values are %q, %q or %q", cfg.Build.Strategy, SymlinkBuilder, CopyBuilder, HardlinkStoreBuilder)
 	}
-	if cfg.Get.CreateSkeletonPlugconf == nil {
-		cfg.Get.CreateSkeletonPlugconf = initCfg.Get.CreateSkeletonPlugconf
+	switch cfg.Get.GitBackend {
+	case AutoGitBackend, GoGitBackend, CLIGitBackend:
+	default:
+		return errors.Errorf("get.git_backend is %q: valid values are %q, %q or %q", cfg.Get.GitBackend, AutoGitBackend, GoGitBackend, CLIGitBackend)
 	}
-	if cfg.Get.FallbackGitCmd == nil {
-		cfg.Get.FallbackGitCmd = initCfg.Get.FallbackGitCmd
+	for _, dir := range cfg.System.ReposPath {
+		if !filepath.IsAbs(dir) {
+			return errors.Errorf("system.repos_path must be absolute paths: %q", dir)
+		}
 	}
-	if cfg.Edit.Editor == "" {
-		cfg.Edit.Editor = initCfg.Edit.Editor
+	for _, src := range cfg.Plugconf.TemplateSources {
+		if !filepath.IsAbs(src) && !strings.Contains(src, "://") && !scpLikeURLRx.MatchString(src) {
+			return errors.Errorf("plugconf.template_sources must be absolute paths or URLs: %q", src)
+		}
 	}
-}
-
-func validate(cfg *Config) error {
-	if cfg.Build.Strategy != "symlink" && cfg.Build.Strategy != "copy" {
-		return errors.Errorf("build.strategy is %q: valid values are %q or %q", cfg.Build.Strategy, "symlink", "copy")
+	if cfg.HTTP.Proxy != "" {
+		if u, err := url.Parse(cfg.HTTP.Proxy); err != nil || u.Scheme == "" || u.Host == "" {
+			return errors.Errorf("http.proxy is %q: it must be an URL like \"http://proxy.example.com:8080\"", cfg.HTTP.Proxy)
+		}
+	}
+	if d, err := time.ParseDuration(cfg.HTTP.Timeout); err != nil || d <= 0 {
+		return errors.Errorf("http.timeout is %q: it must be a positive duration like \"30s\"", cfg.HTTP.Timeout)
+	}
+	if cfg.HTTP.CAFile != "" && !filepath.IsAbs(cfg.HTTP.CAFile) {
+		return errors.Errorf("http.ca_file must be an absolute path: %q", cfg.HTTP.CAFile)
+	}
+	if cfg.Log.File != "" && !filepath.IsAbs(cfg.Log.File) {
+		return errors.Errorf("log.file must be an absolute path: %q", cfg.Log.File)
+	}
+	switch cfg.Log.Format {
+	case logger.TextFormat, logger.JSONFormat:
+	default:
+		return errors.Errorf("log.format is %q: valid values are %q or %q", cfg.Log.Format, logger.TextFormat, logger.JSONFormat)
+	}
+	for host, cred := range cfg.Credentials {
+		if cred.Token == "" {
+			return errors.Errorf("credentials.%q: token is empty", host)
+		}
 	}
 	return nil
 }
@@ -0,0 +1,382 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// setUpConfigEnv creates system, user, and project config files in a temp
+// directory, and changes current directory to the project directory.
+func setUpConfigEnv(t *testing.T, system, user, project string) func() {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "volt-test-config-")
+	if err != nil {
+		t.Fatal("failed to create temp dir: " + err.Error())
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal("failed to get current directory: " + err.Error())
+	}
+	env := map[string]string{
+		"VOLTPATH":           filepath.Join(dir, "volt"),
+		"VOLT_SYSTEM_CONFIG": filepath.Join(dir, "system", "config.toml"),
+	}
+	files := map[string]string{
+		env["VOLT_SYSTEM_CONFIG"]:                     system,
+		filepath.Join(env["VOLTPATH"], "config.toml"): user,
+		filepath.Join(dir, "project", ".volt.toml"):   project,
+	}
+	for file, content := range files {
+		os.MkdirAll(filepath.Dir(file), 0755)
+		if content == "" {
+			continue
+		}
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal("failed to write config: " + err.Error())
+		}
+	}
+	oldEnv := make(map[string]string)
+	for name, value := range env {
+		oldEnv[name] = os.Getenv(name)
+		os.Setenv(name, value)
+	}
+	os.Chdir(filepath.Join(dir, "project", ""))
+	return func() {
+		os.Chdir(wd)
+		for name, value := range oldEnv {
+			os.Setenv(name, value)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestLayers(t *testing.T) {
+	const system = `
+[build]
+strategy = "copy"
+helptags = false
+
+[http]
+timeout = "10s"
+
+[credentials."git.example.com"]
+token = "system-token"
+`
+	const user = `
+[build]
+strategy = "hardlink-store"
+
+[alias]
+up = ["get", "-u"]
+
+[credentials."git.example.com"]
+token = "user-token"
+`
+	const project = `
+[build]
+per_profile = true
+
+[credentials."git.example.com"]
+token = "project-token"
+`
+	teardown := setUpConfigEnv(t, system, user, project)
+	defer teardown()
+	defer os.Unsetenv("VOLT_HTTP_TIMEOUT")
+	os.Setenv("VOLT_HTTP_TIMEOUT", "20s")
+	defer os.Unsetenv("VOLT_HTTP_NO_PROXY")
+	os.Setenv("VOLT_HTTP_NO_PROXY", "localhost, example.com")
+
+	var tests = []struct {
+		key    string
+		value  interface{}
+		origin string
+	}{
+		{"build.strategy", "hardlink-store", filepath.Join(os.Getenv("VOLTPATH"), "config.toml")},
+		{"build.helptags", false, os.Getenv("VOLT_SYSTEM_CONFIG")},
+		{"build.per_profile", true, ".volt.toml"},
+		{"get.git_backend", AutoGitBackend, DefaultOrigin},
+		{"http.timeout", "20s", "$VOLT_HTTP_TIMEOUT"},
+		{"http.no_proxy", []string{"localhost", "example.com"}, "$VOLT_HTTP_NO_PROXY"},
+		{"alias.up", []string{"get", "-u"}, filepath.Join(os.Getenv("VOLTPATH"), "config.toml")},
+		// Credentials of project config file are ignored
+		{`credentials."git.example.com".token`, "user-token", filepath.Join(os.Getenv("VOLTPATH"), "config.toml")},
+		{"credentials.git.example.com.token", "user-token", filepath.Join(os.Getenv("VOLTPATH"), "config.toml")},
+	}
+	for _, tt := range tests {
+		v, err := Get(tt.key)
+		if err != nil {
+			t.Errorf("Get(%q) returned an error: %s", tt.key, err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(v.Value, tt.value) || filepath.Base(v.Origin) != filepath.Base(tt.origin) {
+			t.Errorf("Get(%q): expected %v from %s but got %v from %s", tt.key, tt.value, tt.origin, v.Value, v.Origin)
+		}
+	}
+
+	cfg, err := Read()
+	if err != nil {
+		t.Fatal("Read() returned an error: " + err.Error())
+	}
+	if cfg.Build.Strategy != HardlinkStoreBuilder || *cfg.Build.PerProfile != true || *cfg.Build.Helptags != false ||
+		cfg.HTTP.Timeout != "20s" || cfg.Credentials["git.example.com"].Token != "user-token" ||
+		!reflect.DeepEqual(cfg.Alias["up"], []string{"get", "-u"}) ||
+		!reflect.DeepEqual(cfg.Plugconf.TemplateSources, []string{DefaultTemplateSource}) {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestLayersProjectAllowList(t *testing.T) {
+	const user = `
+[edit]
+editor = "vim"
+`
+	const project = `
+[build]
+strategy = "copy"
+
+[edit]
+editor = "sh -c 'echo pwned'"
+
+[http]
+insecure_hosts = ["github.com"]
+
+[log]
+file = "/tmp/volt.log"
+`
+	teardown := setUpConfigEnv(t, "", user, project)
+	defer teardown()
+
+	cfg, err := Read()
+	if err != nil {
+		t.Fatal("Read() returned an error: " + err.Error())
+	}
+	// Allowed keys are read from project config file
+	if cfg.Build.Strategy != CopyBuilder {
+		t.Errorf("expected build.strategy = %q but got %q", CopyBuilder, cfg.Build.Strategy)
+	}
+	// Other keys are ignored
+	if cfg.Edit.Editor != "vim" {
+		t.Errorf("expected edit.editor = %q but got %q", "vim", cfg.Edit.Editor)
+	}
+	if len(cfg.HTTP.InsecureHosts) != 0 {
+		t.Errorf("expected http.insecure_hosts to be empty but got %v", cfg.HTTP.InsecureHosts)
+	}
+	if cfg.Log.File != "" {
+		t.Errorf("expected log.file to be empty but got %q", cfg.Log.File)
+	}
+	for _, key := range []string{"edit.editor", "http.insecure_hosts", "log.file"} {
+		if v, err := Get(key); err == nil && filepath.Base(v.Origin) == ".volt.toml" {
+			t.Errorf("expected %s not to be read from .volt.toml", key)
+		}
+	}
+
+	if err := CheckProjectKey("build.per_profile"); err != nil {
+		t.Errorf("CheckProjectKey(%q) returned an error: %s", "build.per_profile", err.Error())
+	}
+	if err := CheckProjectKey("edit.editor"); err == nil {
+		t.Errorf("expected CheckProjectKey(%q) to return an error", "edit.editor")
+	}
+}
+
+func TestLayersInvalidValue(t *testing.T) {
+	var tests = []struct {
+		user string
+		env  string
+	}{
+		{"[build]\nstrategy = true\n", ""},
+		{"[build]\nstrategy = \"unknown\"\n", ""},
+		{"[http]\nno_proxy = [1, 2]\n", ""},
+		{"", "maybe"},
+	}
+	for _, tt := range tests {
+		func() {
+			teardown := setUpConfigEnv(t, "", tt.user, "")
+			defer teardown()
+			if tt.env != "" {
+				defer os.Unsetenv("VOLT_BUILD_HELPTAGS")
+				os.Setenv("VOLT_BUILD_HELPTAGS", tt.env)
+			}
+			if _, err := Read(); err == nil {
+				t.Errorf("expected an error for config %q and env %q but got nil", tt.user, tt.env)
+			}
+		}()
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	var tests = []struct {
+		key      string
+		expected keyPath
+	}{
+		{"build.strategy", keyPath{"build", "strategy"}},
+		{`credentials."git.example.com".token`, keyPath{"credentials", "git.example.com", "token"}},
+		{`credentials.'git.example.com'.token`, keyPath{"credentials", "git.example.com", "token"}},
+		{` build . strategy `, keyPath{"build", "strategy"}},
+		{"build..strategy", nil},
+		{`build."strategy`, nil},
+	}
+	for _, tt := range tests {
+		actual, err := parseKey(tt.key)
+		if tt.expected == nil {
+			if err == nil {
+				t.Errorf("parseKey(%q): expected an error but got %v", tt.key, actual)
+			}
+			continue
+		}
+		if err != nil || !actual.equals(tt.expected) {
+			t.Errorf("parseKey(%q): expected %v but got %v, %v", tt.key, tt.expected, actual, err)
+		}
+		if roundTrip, err := parseKey(actual.String()); err != nil || !roundTrip.equals(actual) {
+			t.Errorf("parseKey(%q): expected %v but got %v, %v", actual.String(), actual, roundTrip, err)
+		}
+	}
+}
+
+func TestSuggestKey(t *testing.T) {
+	var tests = []struct {
+		key      keyPath
+		expected string
+	}{
+		{keyPath{"build", "stratgy"}, "build.strategy"},
+		{keyPath{"buidl", "strategy"}, "build.strategy"},
+		{keyPath{"http", "timeuot"}, "http.timeout"},
+		{keyPath{"credentials", "git.example.com", "tokne"}, `credentials."git.example.com".token`},
+		{keyPath{"foo", "bar"}, ""},
+	}
+	for _, tt := range tests {
+		if actual := suggestKey(tt.key); actual != tt.expected {
+			t.Errorf("suggestKey(%v): expected %q but got %q", tt.key, tt.expected, actual)
+		}
+	}
+}
+
+func TestSetUnset(t *testing.T) {
+	var tests = []struct {
+		content  string
+		key      string
+		value    string // unset if empty
+		expected string
+	}{
+		// Replaces the value, and keeps comments
+		{
+			"# my config\n[build]\n# strategy\nstrategy = \"copy\" # comment\nhelptags = false\n",
+			"build.strategy", "symlink",
+			"# my config\n[build]\n# strategy\nstrategy = \"symlink\"\nhelptags = false\n",
+		},
+		// Adds the key to the table
+		{
+			"[build]\nstrategy = \"copy\"\n\n[get]\nfallback_git_cmd = true\n",
+			"build.helptags", "false",
+			"[build]\nstrategy = \"copy\"\nhelptags = false\n\n[get]\nfallback_git_cmd = true\n",
+		},
+		// Adds the table
+		{
+			"[build]\nstrategy = \"copy\"\n",
+			"credentials.git.example.com.token", "xxx",
+			"[build]\nstrategy = \"copy\"\n\n[credentials.\"git.example.com\"]\ntoken = \"xxx\"\n",
+		},
+		{
+			"[credentials.git.example.com]\ntoken = \"xxx\"\n",
+			"credentials.git.example.com.token", "yyy",
+			"[credentials.git.example.com]\ntoken = \"yyy\"\n",
+		},
+		{
+			"",
+			"http.no_proxy", "localhost,example.com",
+			"[http]\nno_proxy = [\"localhost\", \"example.com\"]\n",
+		},
+		// Replaces multi-line array
+		{
+			"[http]\nno_proxy = [\n  \"a\", # ]\n  \"b\",\n]\ntimeout = \"10s\"\n",
+			"http.no_proxy", `["c"]`,
+			"[http]\nno_proxy = [\"c\"]\ntimeout = \"10s\"\n",
+		},
+		// Unsets the key
+		{
+			"[alias]\nup = [\"get\", \"-u\"]\nupdate = [\"get\", \"-u\"]\n",
+			"alias.up", "",
+			"[alias]\nupdate = [\"get\", \"-u\"]\n",
+		},
+	}
+	for _, tt := range tests {
+		func() {
+			teardown := setUpConfigEnv(t, "", tt.content, "")
+			defer teardown()
+			file := filepath.Join(os.Getenv("VOLTPATH"), "config.toml")
+			var err error
+			if tt.value != "" {
+				err = Set(file, tt.key, tt.value)
+			} else {
+				err = Unset(file, tt.key)
+			}
+			if err != nil {
+				t.Errorf("failed to set %s to %q: %s", tt.key, tt.value, err.Error())
+				return
+			}
+			content, _ := ioutil.ReadFile(file)
+			if string(content) != tt.expected {
+				t.Errorf("set %s to %q: expected\n%s\nbut got\n%s", tt.key, tt.value, tt.expected, string(content))
+			}
+		}()
+	}
+}
+
+func TestSetInvalid(t *testing.T) {
+	const content = "[build]\nstrategy = \"copy\"\n"
+	var tests = []struct {
+		key   string
+		value string
+	}{
+		{"build.stratgy", "copy"},
+		{"build.strategy", "unknown"},
+		{"build.helptags", "maybe"},
+	}
+	for _, tt := range tests {
+		func() {
+			teardown := setUpConfigEnv(t, "", content, "")
+			defer teardown()
+			file := filepath.Join(os.Getenv("VOLTPATH"), "config.toml")
+			if err := Set(file, tt.key, tt.value); err == nil {
+				t.Errorf("set %s to %q: expected an error but got nil", tt.key, tt.value)
+			}
+			if actual, _ := ioutil.ReadFile(file); string(actual) != content {
+				t.Errorf("set %s to %q: config was changed:\n%s", tt.key, tt.value, string(actual))
+			}
+
+			// Project config file is not created
+			project, err := ProjectConfigTOML()
+			if err != nil {
+				t.Fatal("failed to get project config file: " + err.Error())
+			}
+			if err := Set(project, tt.key, tt.value); err == nil {
+				t.Errorf("set %s to %q in %s: expected an error but got nil", tt.key, tt.value, project)
+			}
+			if _, err := os.Stat(project); !os.IsNotExist(err) {
+				t.Errorf("set %s to %q: %s was created", tt.key, tt.value, project)
+			}
+		}()
+	}
+}
+
+func TestSetKeepsMode(t *testing.T) {
+	teardown := setUpConfigEnv(t, "", "[build]\nstrategy = \"copy\"\n", "")
+	defer teardown()
+	file := filepath.Join(os.Getenv("VOLTPATH"), "config.toml")
+	// config.toml may have credentials
+	if err := os.Chmod(file, 0600); err != nil {
+		t.Fatal("failed to chmod: " + err.Error())
+	}
+	if err := Set(file, "credentials.git.example.com.token", "xxx"); err != nil {
+		t.Fatal("failed to set token: " + err.Error())
+	}
+	fi, err := os.Stat(file)
+	if err != nil {
+		t.Fatal("failed to stat: " + err.Error())
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600 but got %v", fi.Mode().Perm())
+	}
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/fileutil"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// Set sets key to value in config file. value is parsed according to the
+// type of key (an array is a TOML array or comma-separated values).
+// Comments and other keys in the file are left as they are.
+// If the file becomes invalid by the change, the file is not changed.
+func Set(file, key, value string) error {
+	sk, normalized, err := lookupKey(key)
+	if err != nil {
+		return err
+	}
+	v, err := sk.typ.parse(value)
+	if err != nil {
+		return errors.Wrap(err, normalized.String())
+	}
+	lines, err := readLines(file)
+	if err != nil {
+		return err
+	}
+
+	line := keyPath{normalized[len(normalized)-1]}.String() + " = " + formatValue(v)
+	start, end, insertAt := findKey(lines, normalized)
+	switch {
+	case start >= 0:
+		lines = append(lines[:start], append([]string{line}, lines[end:]...)...)
+	case insertAt >= 0:
+		lines = append(lines[:insertAt], append([]string{line}, lines[insertAt:]...)...)
+	default:
+		if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) != "" {
+			lines = append(lines, "")
+		}
+		lines = append(lines, "["+normalized[:len(normalized)-1].String()+"]", line)
+	}
+	return writeLines(file, lines, normalized, v)
+}
+
+// Unset removes key from config file.
+func Unset(file, key string) error {
+	_, normalized, err := lookupKey(key)
+	if err != nil {
+		return err
+	}
+	lines, err := readLines(file)
+	if err != nil {
+		return err
+	}
+	start, end, _ := findKey(lines, normalized)
+	if start < 0 {
+		return errors.Errorf("%s is not set in %s", normalized, file)
+	}
+	lines = append(lines[:start], lines[end:]...)
+	return writeLines(file, lines, normalized, nil)
+}
+
+func readLines(file string) ([]string, error) {
+	content, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n"), nil
+}
+
+// writeLines writes lines to file after checking the value of key is
+// expected (nil means key does not exist).
+func writeLines(file string, lines []string, key keyPath, expected interface{}) error {
+	content := strings.Join(lines, "\n") + "\n"
+	l, err := decodeLayer(content, file, false)
+	if err != nil {
+		return errors.Wrap(err, "could not edit "+file)
+	}
+	var actual interface{}
+	for _, v := range l.values {
+		if v.key.equals(key) {
+			actual = v.Value
+		}
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		return errors.Errorf("could not edit %s automatically: please edit it manually", file)
+	}
+
+	// Check if all config files are still valid before writing the file
+	_, errBefore := Read()
+	if errBefore == nil {
+		if _, err := readWith(file, content); err != nil {
+			return err
+		}
+	}
+	perm := os.FileMode(0644)
+	if fi, err := os.Stat(file); err == nil {
+		perm = fi.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return fileutil.WriteFileAtomic(file, []byte(content), perm)
+}
+
+// findKey returns the range of lines [start, end) where key is defined, and
+// the index of line where key can be inserted into the table of key.
+// start is -1 if key is not found, and insertAt is -1 if the table is not
+// found.
+func findKey(lines []string, key keyPath) (start, end, insertAt int) {
+	start, end, insertAt = -1, -1, -1
+	name := key[len(key)-1]
+	inTable := false
+	for i := 0; i < len(lines); i++ {
+		s := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(s, "[") {
+			// Both [credentials."github.com"] and [credentials.github.com]
+			// are the table of `credentials."github.com".token`
+			header, err := parseTableHeader(s)
+			_, normalized := lookupSchema(append(header, name))
+			inTable = err == nil && normalized.equals(key)
+			if inTable {
+				insertAt = i + 1
+			}
+			continue
+		}
+		if !inTable || s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		eq := indexOutsideQuotes(s, '=')
+		if eq < 0 {
+			continue
+		}
+		// A value may continue to the following lines (e.g. multi-line array)
+		next := i + 1 + continuedLines(s[eq+1:], lines[i+1:])
+		if k, err := parseKey(s[:eq]); err == nil && k.equals(keyPath{name}) {
+			start, end = i, next
+		}
+		insertAt = next
+		i = next - 1
+	}
+	return
+}
+
+// parseTableHeader parses "[table]" line. "[[array]]" is not supported.
+func parseTableHeader(s string) (keyPath, error) {
+	if strings.HasPrefix(s, "[[") {
+		return nil, errors.New("array of tables is not supported")
+	}
+	closing := indexOutsideQuotes(s, ']')
+	if closing < 0 {
+		return nil, errors.Errorf("invalid table header: %s", s)
+	}
+	return parseKey(s[1:closing])
+}
+
+// indexOutsideQuotes returns the index of the first c which is not in quotes.
+func indexOutsideQuotes(s string, c byte) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == '\\' && quote == '"' {
+				i++
+			} else if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			quote = s[i]
+		case s[i] == c:
+			return i
+		}
+	}
+	return -1
+}
+
+// continuedLines returns the number of following lines of a value which
+// starts with value, by counting brackets of arrays.
+func continuedLines(value string, following []string) int {
+	depth := bracketDepth(value, 0)
+	n := 0
+	for depth > 0 && n < len(following) {
+		depth = bracketDepth(following[n], depth)
+		n++
+	}
+	return n
+}
+
+func bracketDepth(s string, depth int) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == '\\' && quote == '"' {
+				i++
+			} else if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			quote = s[i]
+		case s[i] == '#':
+			return depth
+		case s[i] == '[':
+			depth++
+		case s[i] == ']':
+			depth--
+		}
+	}
+	return depth
+}
+
+// ProjectConfigTOML returns the project config file to be changed, which is
+// the nearest ".volt.toml", or ".volt.toml" in current directory.
+func ProjectConfigTOML() (string, error) {
+	if file := pathutil.LookUpProjectConfigTOML(); file != "" {
+		return file, nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(wd, pathutil.ProjectConfigTOMLName), nil
+}
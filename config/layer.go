@@ -0,0 +1,312 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// DefaultOrigin is the origin of built-in default values.
+const DefaultOrigin = "default"
+
+// Value is an effective value of a key, and where it came from.
+type Value struct {
+	// Key is a dotted key (e.g. "build.strategy")
+	Key string
+	// Value is string, bool, or []string
+	Value interface{}
+	// Origin is DefaultOrigin, a file path, or an environment variable
+	// (e.g. "$VOLT_BUILD_STRATEGY")
+	Origin string
+
+	key    keyPath
+	schema *schemaKey
+}
+
+// String returns the value in TOML syntax. Secret values like tokens are
+// masked.
+func (v *Value) String() string {
+	if v.schema.secret {
+		return `"****"`
+	}
+	return formatValue(v.Value)
+}
+
+// formatValue returns v in TOML syntax.
+func formatValue(v interface{}) string {
+	var buf bytes.Buffer
+	toml.NewEncoder(&buf).Encode(map[string]interface{}{"v": v})
+	return strings.TrimSuffix(strings.TrimPrefix(buf.String(), "v = "), "\n")
+}
+
+// layer is values read from one source (e.g. a config file).
+type layer struct {
+	values []*Value
+}
+
+// readLayers reads the following layers in order of precedence (lowest
+// first): built-in defaults, system config file, user config file
+// ($VOLTPATH/config.toml), project config file (.volt.toml), and
+// environment variables.
+func readLayers() ([]*layer, error) {
+	return readLayersWith("", "")
+}
+
+// readLayersWith is readLayers, but content is decoded as config file "file"
+// instead of reading it, even if file does not exist yet.
+func readLayersWith(file, content string) ([]*layer, error) {
+	layers := make([]*layer, 0, 5)
+	def, err := defaultLayer()
+	if err != nil {
+		return nil, err
+	}
+	layers = append(layers, def)
+	project := pathutil.LookUpProjectConfigTOML()
+	if project == "" && file != "" {
+		// file may be a new project config file
+		if p, err := ProjectConfigTOML(); err == nil && p == file {
+			project = file
+		}
+	}
+	files := []struct {
+		path    string
+		project bool
+	}{
+		{pathutil.SystemConfigTOML(), false},
+		{pathutil.ConfigTOML(), false},
+		{project, true},
+	}
+	for _, f := range files {
+		var l *layer
+		var err error
+		switch {
+		case f.path == "":
+			continue
+		case f.path == file:
+			l, err = decodeLayer(content, f.path, f.project)
+			if err != nil {
+				err = errors.Wrap(err, "could not read "+f.path)
+			}
+		case pathutil.Exists(f.path):
+			l, err = readFileLayer(f.path, f.project)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, l)
+	}
+	env, err := envLayer()
+	if err != nil {
+		return nil, err
+	}
+	return append(layers, env), nil
+}
+
+func defaultLayer() (*layer, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(initialConfigTOML()); err != nil {
+		return nil, err
+	}
+	return decodeLayer(buf.String(), DefaultOrigin, false)
+}
+
+// readFileLayer reads a config file. If project is true, keys which are not
+// allowed in project config files are ignored because they may be written by
+// others.
+func readFileLayer(path string, project bool) (*layer, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	l, err := decodeLayer(string(content), path, project)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read "+path)
+	}
+	return l, nil
+}
+
+func decodeLayer(content, origin string, project bool) (*layer, error) {
+	var m map[string]interface{}
+	if _, err := toml.Decode(content, &m); err != nil {
+		return nil, err
+	}
+	l := &layer{}
+	if err := l.flatten(m, nil, origin, project); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// flatten adds values of a decoded TOML table m to l.
+// Unknown keys are warned.
+func (l *layer) flatten(m map[string]interface{}, prefix keyPath, origin string, project bool) error {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key := append(append(keyPath{}, prefix...), name)
+		sk, normalized := lookupSchema(key)
+		if sk == nil {
+			if table, ok := m[name].(map[string]interface{}); ok {
+				if err := l.flatten(table, key, origin, project); err != nil {
+					return err
+				}
+				continue
+			}
+			warnOnce(origin+":"+key.String(), "%s in %s", unknownKeyError(key).Error(), origin)
+			continue
+		}
+		if project && !sk.project {
+			warnOnce(origin+":"+normalized.String(), "%s in %s is ignored (only %s can be set in project config file)",
+				normalized, origin, projectKeyNames())
+			continue
+		}
+		v, err := sk.typ.convert(m[name])
+		if err != nil {
+			return errors.Errorf("%s in %s %s", normalized, origin, err.Error())
+		}
+		l.values = append(l.values, &Value{Key: normalized.String(), Value: v, Origin: origin, key: normalized, schema: sk})
+	}
+	return nil
+}
+
+// envLayer reads "$VOLT_{TABLE}_{KEY}" environment variables
+// (e.g. "$VOLT_BUILD_STRATEGY" for "build.strategy").
+func envLayer() (*layer, error) {
+	l := &layer{}
+	for i := range schema {
+		if strings.Contains(schema[i].name, "*") {
+			continue
+		}
+		name := envName(schema[i].name)
+		s, exists := os.LookupEnv(name)
+		if !exists {
+			continue
+		}
+		v, err := schema[i].typ.parse(s)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid $"+name)
+		}
+		key := keyPath(strings.Split(schema[i].name, "."))
+		l.values = append(l.values, &Value{Key: key.String(), Value: v, Origin: "$" + name, key: key, schema: &schema[i]})
+	}
+	return l, nil
+}
+
+func envName(name string) string {
+	return "VOLT_" + strings.ToUpper(strings.Replace(name, ".", "_", -1))
+}
+
+// effectiveValues returns values which are not overridden by upper layers,
+// in the order of schema.
+func effectiveValues(layers []*layer) []*Value {
+	values := make(map[string]*Value)
+	for _, l := range layers {
+		for _, v := range l.values {
+			values[v.Key] = v
+		}
+	}
+	result := make([]*Value, 0, len(values))
+	for _, v := range values {
+		result = append(result, v)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].schema != result[j].schema {
+			return schemaIndex(result[i].schema) < schemaIndex(result[j].schema)
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+func schemaIndex(sk *schemaKey) int {
+	for i := range schema {
+		if &schema[i] == sk {
+			return i
+		}
+	}
+	return len(schema)
+}
+
+// decodeValues converts values to Config.
+func decodeValues(values []*Value) (*Config, error) {
+	root := make(map[string]interface{})
+	for _, v := range values {
+		table := root
+		for _, name := range v.key[:len(v.key)-1] {
+			sub, ok := table[name].(map[string]interface{})
+			if !ok {
+				sub = make(map[string]interface{})
+				table[name] = sub
+			}
+			table = sub
+		}
+		table[v.key[len(v.key)-1]] = v.Value
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(root); err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if _, err := toml.Decode(buf.String(), &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// List returns all effective values.
+func List() ([]*Value, error) {
+	layers, err := readLayers()
+	if err != nil {
+		return nil, err
+	}
+	return effectiveValues(layers), nil
+}
+
+// Get returns the effective value of key.
+func Get(key string) (*Value, error) {
+	_, normalized, err := lookupKey(key)
+	if err != nil {
+		return nil, err
+	}
+	values, err := List()
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		if v.key.equals(normalized) {
+			return v, nil
+		}
+	}
+	return nil, errors.Errorf("%s is not set", normalized)
+}
+
+var warned = struct {
+	sync.Mutex
+	m map[string]bool
+}{m: make(map[string]bool)}
+
+// warnOnce shows a warning once in a process, because config files are read
+// several times.
+func warnOnce(id, format string, args ...interface{}) {
+	warned.Lock()
+	defer warned.Unlock()
+	if warned.m[id] {
+		return
+	}
+	warned.m[id] = true
+	logger.Warnf(format, args...)
+}
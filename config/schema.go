@@ -0,0 +1,377 @@
+package config
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+)
+
+// keyType is the type of a value of config.toml.
+type keyType int
+
+const (
+	stringType keyType = iota
+	boolType
+	stringListType
+)
+
+func (t keyType) String() string {
+	switch t {
+	case boolType:
+		return "boolean"
+	case stringListType:
+		return "array of strings"
+	default:
+		return "string"
+	}
+}
+
+// convert converts a decoded TOML value to string, bool, or []string.
+func (t keyType) convert(v interface{}) (interface{}, error) {
+	switch t {
+	case stringType:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case boolType:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case stringListType:
+		switch list := v.(type) {
+		case []string:
+			return list, nil
+		case []interface{}:
+			result := make([]string, 0, len(list))
+			for i := range list {
+				s, ok := list[i].(string)
+				if !ok {
+					return nil, errors.Errorf("must be %s", t)
+				}
+				result = append(result, s)
+			}
+			return result, nil
+		}
+	}
+	return nil, errors.Errorf("must be %s", t)
+}
+
+// parse parses s given by command-line or environment variable.
+// An array is a TOML array (e.g. `["a", "b"]`), or comma-separated values
+// (e.g. "a,b").
+func (t keyType) parse(s string) (interface{}, error) {
+	switch t {
+	case boolType:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, errors.Errorf("%q is not %s", s, t)
+		}
+		return b, nil
+	case stringListType:
+		if strings.HasPrefix(strings.TrimSpace(s), "[") {
+			var v struct{ V interface{} }
+			if _, err := toml.Decode("v = "+s, &v); err != nil {
+				return nil, errors.Errorf("%q is not %s", s, t)
+			}
+			return t.convert(v.V)
+		}
+		list := make([]string, 0)
+		for _, e := range strings.Split(s, ",") {
+			if e = strings.TrimSpace(e); e != "" {
+				list = append(list, e)
+			}
+		}
+		return list, nil
+	default:
+		return s, nil
+	}
+}
+
+// schemaKey describes a key of config.toml.
+// "*" in name is a key of user-defined table (e.g. "alias.*" matches to
+// "alias.up").
+// Only keys which have project = true are read from project config file,
+// because it may be written by anyone who controls the checked-out
+// repository. Such keys must not run commands, change paths to write, or
+// change network settings.
+type schemaKey struct {
+	name    string
+	typ     keyType
+	secret  bool
+	project bool
+}
+
+var schema = []schemaKey{
+	{name: "build.strategy", typ: stringType, project: true},
+	{name: "build.per_profile", typ: boolType, project: true},
+	{name: "build.helptags", typ: boolType, project: true},
+	{name: "get.create_skeleton_plugconf", typ: boolType, project: true},
+	{name: "get.fallback_git_cmd", typ: boolType},
+	{name: "get.git_backend", typ: stringType},
+	{name: "edit.editor", typ: stringType},
+	{name: "system.repos_path", typ: stringListType},
+	{name: "plugconf.template_sources", typ: stringListType},
+	{name: "http.proxy", typ: stringType},
+	{name: "http.no_proxy", typ: stringListType},
+	{name: "http.timeout", typ: stringType},
+	{name: "http.ca_file", typ: stringType},
+	{name: "http.insecure_hosts", typ: stringListType},
+	{name: "log.file", typ: stringType},
+	{name: "log.format", typ: stringType},
+	{name: "alias.*", typ: stringListType},
+	{name: "credentials.*.username", typ: stringType},
+	{name: "credentials.*.token", typ: stringType, secret: true},
+}
+
+// KeyInfo is the name and the type of a key of config.toml.
+type KeyInfo struct {
+	Name string
+	Type string
+	// Project is true if the key can be set in project config file
+	Project bool
+}
+
+// Keys returns all keys of config.toml.
+func Keys() []KeyInfo {
+	keys := make([]KeyInfo, 0, len(schema))
+	for i := range schema {
+		keys = append(keys, KeyInfo{Name: schema[i].name, Type: schema[i].typ.String(), Project: schema[i].project})
+	}
+	return keys
+}
+
+// projectKeyNames returns the keys which can be set in project config file.
+func projectKeyNames() string {
+	names := make([]string, 0, len(schema))
+	for i := range schema {
+		if schema[i].project {
+			names = append(names, schema[i].name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// CheckProjectKey returns an error if key cannot be set in project config
+// file.
+func CheckProjectKey(key string) error {
+	sk, normalized, err := lookupKey(key)
+	if err != nil {
+		return err
+	}
+	if !sk.project {
+		return errors.Errorf("%s cannot be set in project config file (only %s can be set)", normalized, projectKeyNames())
+	}
+	return nil
+}
+
+// keyPath is a key of config.toml split into table names and a key
+// (e.g. `credentials."github.example.com".token` is
+// {"credentials", "github.example.com", "token"}).
+type keyPath []string
+
+var rxBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// String returns the dotted key. Each part is quoted if needed.
+func (k keyPath) String() string {
+	parts := make([]string, 0, len(k))
+	for _, p := range k {
+		if !rxBareKey.MatchString(p) {
+			p = strconv.Quote(p)
+		}
+		parts = append(parts, p)
+	}
+	return strings.Join(parts, ".")
+}
+
+func (k keyPath) equals(k2 keyPath) bool {
+	if len(k) != len(k2) {
+		return false
+	}
+	for i := range k {
+		if k[i] != k2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// parseKey parses a dotted key. Quoted parts may contain dots.
+func parseKey(s string) (keyPath, error) {
+	var key keyPath
+	var part bytes.Buffer
+	quoted := false
+	var quote byte
+	flush := func() error {
+		p := strings.TrimSpace(part.String())
+		part.Reset()
+		if p == "" {
+			return errors.Errorf("invalid key: %q", s)
+		}
+		if quoted {
+			if p[0] == '\'' {
+				p = strings.Trim(p, "'")
+			} else if unquoted, err := strconv.Unquote(p); err == nil {
+				p = unquoted
+			} else {
+				return errors.Errorf("invalid key: %q", s)
+			}
+		}
+		key = append(key, p)
+		quoted = false
+		return nil
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			part.WriteByte(c)
+			if c == '\\' && quote == '"' && i+1 < len(s) {
+				i++
+				part.WriteByte(s[i])
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			quoted = true
+			part.WriteByte(c)
+		case c == '.':
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		default:
+			part.WriteByte(c)
+		}
+	}
+	if quote != 0 {
+		return nil, errors.Errorf("invalid key: %q", s)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// lookupSchema returns the schema of key and the normalized key.
+// "*" of the schema matches to one or more parts of key, so that both
+// `credentials."github.example.com".token` and
+// "credentials.github.example.com.token" are accepted.
+// If key is unknown, it returns nil.
+func lookupSchema(key keyPath) (*schemaKey, keyPath) {
+	for i := range schema {
+		pattern := strings.Split(schema[i].name, ".")
+		if normalized := matchPattern(pattern, key); normalized != nil {
+			return &schema[i], normalized
+		}
+	}
+	return nil, nil
+}
+
+func matchPattern(pattern []string, key keyPath) keyPath {
+	if len(key) < len(pattern) {
+		return nil
+	}
+	// The number of parts matched to "*"
+	wildcard := len(key) - len(pattern) + 1
+	normalized := make(keyPath, 0, len(pattern))
+	k := 0
+	for _, p := range pattern {
+		if p == "*" {
+			normalized = append(normalized, strings.Join(key[k:k+wildcard], "."))
+			k += wildcard
+			continue
+		}
+		if k >= len(key) || key[k] != p {
+			return nil
+		}
+		normalized = append(normalized, p)
+		k++
+	}
+	if k != len(key) {
+		return nil
+	}
+	return normalized
+}
+
+// lookupKey parses s and returns the schema and the normalized key.
+func lookupKey(s string) (*schemaKey, keyPath, error) {
+	key, err := parseKey(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	sk, normalized := lookupSchema(key)
+	if sk == nil {
+		return nil, nil, unknownKeyError(key)
+	}
+	return sk, normalized, nil
+}
+
+func unknownKeyError(key keyPath) error {
+	if suggestion := suggestKey(key); suggestion != "" {
+		return errors.Errorf("unknown key %q (did you mean %q?)", key.String(), suggestion)
+	}
+	return errors.Errorf("unknown key %q", key.String())
+}
+
+// suggestKey returns the key of schema which is the most similar to key.
+// If there is no similar key, it returns empty string.
+func suggestKey(key keyPath) string {
+	name := strings.Join(key, ".")
+	best, bestDist := "", 4
+	for i := range schema {
+		pattern := strings.Split(schema[i].name, ".")
+		// Fill "*" with the corresponding part of key
+		candidate := make(keyPath, 0, len(pattern))
+		for j, p := range pattern {
+			if p == "*" {
+				if j >= len(key) {
+					break
+				}
+				p = key[j]
+			}
+			candidate = append(candidate, p)
+		}
+		if len(candidate) != len(pattern) {
+			continue
+		}
+		if dist := levenshtein(name, strings.Join(candidate, ".")); dist < bestDist {
+			best, bestDist = candidate.String(), dist
+		}
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,61 @@
+package fileutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// WriteFileAtomic writes data to filename like ioutil.WriteFile, but never
+// leaves a truncated file even if the process crashes or the disk is full.
+// The data is written to a temporary file in the same directory, and the
+// temporary file is renamed to filename after it is synced to the disk.
+// If filename is a symbolic link, the link target is replaced.
+func WriteFileAtomic(filename string, data []byte, perm os.FileMode) (err error) {
+	if target, e := filepath.EvalSymlinks(filename); e == nil {
+		filename = target
+	}
+	dir := filepath.Dir(filename)
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), filename); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir syncs dir to make the rename of the entry in dir durable.
+func syncDir(dir string) error {
+	// Directories cannot be opened for syncing on Windows
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,132 @@
+package fileutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		old  []byte // file does not exist if nil
+		mode os.FileMode
+	}{
+		{"new file", nil, 0644},
+		{"new file with mode", nil, 0600},
+		{"existing file", []byte("old content which is longer than new one"), 0644},
+		{"existing file with mode", []byte("old"), 0600},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := tempDir(t)
+			defer os.RemoveAll(dir)
+			file := filepath.Join(dir, "file")
+			if tt.old != nil {
+				if err := ioutil.WriteFile(file, tt.old, 0644); err != nil {
+					t.Fatal("failed to write file: " + err.Error())
+				}
+			}
+
+			if err := WriteFileAtomic(file, []byte("new"), tt.mode); err != nil {
+				t.Fatal("WriteFileAtomic() failed: " + err.Error())
+			}
+
+			if content, err := ioutil.ReadFile(file); err != nil || string(content) != "new" {
+				t.Errorf("expected %q but got %q, %v", "new", string(content), err)
+			}
+			if runtime.GOOS != "windows" {
+				fi, err := os.Stat(file)
+				if err != nil {
+					t.Fatal("failed to stat file: " + err.Error())
+				}
+				if fi.Mode().Perm() != tt.mode {
+					t.Errorf("expected mode %v but got %v", tt.mode, fi.Mode().Perm())
+				}
+			}
+			checkOnlyEntries(t, dir, "file")
+		})
+	}
+}
+
+// Writes to the target of a symbolic link, and keeps the link
+func TestWriteFileAtomicSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links may not be created on Windows")
+	}
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	target := filepath.Join(dir, "target")
+	if err := ioutil.WriteFile(target, []byte("old"), 0644); err != nil {
+		t.Fatal("failed to write file: " + err.Error())
+	}
+	link := filepath.Join(dir, "link")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal("failed to create symlink: " + err.Error())
+	}
+
+	if err := WriteFileAtomic(link, []byte("new"), 0644); err != nil {
+		t.Fatal("WriteFileAtomic() failed: " + err.Error())
+	}
+
+	if content, err := ioutil.ReadFile(target); err != nil || string(content) != "new" {
+		t.Errorf("expected %q but got %q, %v", "new", string(content), err)
+	}
+	if fi, err := os.Lstat(link); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("%s is not a symbolic link anymore: %v", link, err)
+	}
+	checkOnlyEntries(t, dir, "link", "target")
+}
+
+// Removes the temporary file and keeps the destination if it failed to write
+func TestWriteFileAtomicFailure(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	// Renaming a file to a non-empty directory fails
+	file := filepath.Join(dir, "file")
+	if err := os.MkdirAll(filepath.Join(file, "child"), 0755); err != nil {
+		t.Fatal("failed to create directory: " + err.Error())
+	}
+
+	if err := WriteFileAtomic(file, []byte("new"), 0644); err == nil {
+		t.Error("WriteFileAtomic() must fail")
+	}
+
+	if fi, err := os.Stat(file); err != nil || !fi.IsDir() {
+		t.Errorf("%s was changed: %v", file, err)
+	}
+	checkOnlyEntries(t, dir, "file")
+}
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "volt-test-fileutil-")
+	if err != nil {
+		t.Fatal("failed to create temp dir: " + err.Error())
+	}
+	return dir
+}
+
+// checkOnlyEntries checks dir has only names (no temporary files are left).
+func checkOnlyEntries(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal("failed to read dir: " + err.Error())
+	}
+	var actual []string
+	for _, fi := range infos {
+		actual = append(actual, fi.Name())
+	}
+	if len(actual) != len(names) {
+		t.Errorf("expected entries %v but got %v", names, actual)
+		return
+	}
+	for i := range names {
+		if actual[i] != names[i] {
+			t.Errorf("expected entries %v but got %v", names, actual)
+			return
+		}
+	}
+}
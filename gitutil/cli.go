@@ -0,0 +1,201 @@
+package gitutil
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// cliBackend is Backend implemented by executing git command.
+type cliBackend struct{}
+
+// git executes git command in dir, and returns the standard output.
+// git does not look up repositories in parent directories of dir, so it
+// fails if dir is not a git repository.
+func (*cliBackend) git(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_CEILING_DIRECTORIES="+filepath.Dir(dir))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Errorf("\"git %s\" failed, out=%s: %s",
+			strings.Join(args, " "), strings.TrimSpace(stderr.String()), err.Error())
+	}
+	return out, nil
+}
+
+func (*cliBackend) Clone(url, dir string) error {
+	// "git clone" sets the upstream remote to "origin"
+	cmd := exec.Command("git", "clone", "--recursive", url, dir)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.Errorf("\"git clone --recursive %s %s\" failed, out=%s: %s", url, dir, string(out), err.Error())
+	}
+	return nil
+}
+
+func (b *cliBackend) Fetch(dir string) error {
+	branch, err := b.CurrentBranch(dir)
+	if err != nil {
+		return err
+	}
+	out, err := b.git(dir, "config", "--get", "branch."+branch+".remote")
+	if err != nil {
+		return errors.Errorf("gitconfig 'branch.%s.remote' is not found", branch)
+	}
+	return b.updated(dir, func() error {
+		_, err := b.git(dir, "fetch", strings.TrimSpace(string(out)))
+		return err
+	})
+}
+
+func (b *cliBackend) FastForward(dir string) error {
+	return b.updated(dir, func() error {
+		_, err := b.git(dir, "pull", "--ff-only")
+		return err
+	})
+}
+
+// updated calls update, and returns ErrAlreadyUpToDate if HEAD was not
+// changed.
+func (b *cliBackend) updated(dir string, update func() error) error {
+	before, err := GetHEAD(b, dir)
+	if err != nil {
+		return err
+	}
+	if err := update(); err != nil {
+		return err
+	}
+	after, err := GetHEAD(b, dir)
+	if err != nil {
+		return err
+	}
+	if before == after {
+		return ErrAlreadyUpToDate
+	}
+	return nil
+}
+
+func (b *cliBackend) ResolveRef(dir, ref string) (string, error) {
+	out, err := b.git(dir, "rev-parse", "--verify", ref+"^{commit}")
+	if err != nil {
+		return "", errors.Wrapf(err, "could not resolve '%s'", ref)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (b *cliBackend) CurrentBranch(dir string) (string, error) {
+	out, err := b.git(dir, "symbolic-ref", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	refBranch := strings.TrimSpace(string(out))
+	branch := refHeadsRx.FindStringSubmatch(refBranch)
+	if len(branch) == 0 {
+		return "", errors.New("HEAD is not matched to refs/heads/...: " + refBranch)
+	}
+	return branch[1], nil
+}
+
+func (b *cliBackend) IsBare(dir string) (bool, error) {
+	out, err := b.git(dir, "rev-parse", "--is-bare-repository")
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(string(out)) == "true", nil
+}
+
+func (b *cliBackend) IsClean(dir string) (bool, error) {
+	out, err := b.git(dir, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return len(bytes.TrimSpace(out)) == 0, nil
+}
+
+func (b *cliBackend) ReadTree(dir, commit string, fn func(file *TreeFile) error) error {
+	// Each entry is "{mode} SP {type} SP {hash} TAB {path} NUL"
+	out, err := b.git(dir, "ls-tree", "-r", "-z", "--full-tree", commit)
+	if err != nil {
+		return errors.Wrap(err, "failed to get tree "+commit)
+	}
+	for _, entry := range strings.Split(string(out), "\x00") {
+		tab := strings.IndexByte(entry, '\t')
+		if tab < 0 {
+			continue
+		}
+		fields := strings.Fields(entry[:tab])
+		if len(fields) != 3 || fields[1] != "blob" {
+			continue
+		}
+		mode, err := b.fileMode(fields[0])
+		if err != nil {
+			return err
+		}
+		hash := fields[2]
+		file := NewTreeFile(entry[tab+1:], hash, mode, func() (io.ReadCloser, error) {
+			content, err := b.git(dir, "cat-file", "blob", hash)
+			if err != nil {
+				return nil, err
+			}
+			return ioutil.NopCloser(bytes.NewReader(content)), nil
+		})
+		if err := fn(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileMode converts git file mode (e.g. "100644") to os.FileMode in the same
+// way as go-git.
+func (*cliBackend) fileMode(s string) (os.FileMode, error) {
+	switch s {
+	case "100644", "100664":
+		return 0644, nil
+	case "100755":
+		return 0755, nil
+	case "120000":
+		return os.ModePerm | os.ModeSymlink, nil
+	default:
+		return 0, errors.New("failed to convert file mode: " + s)
+	}
+}
+
+func (b *cliBackend) Log(dir, from, to string) ([]Commit, error) {
+	rev := to
+	if from != "" {
+		rev = from + ".." + to
+	}
+	out, err := b.git(dir, "log", "-z", "--format=%H%x01%an%x01%at%x01%B", rev)
+	if err != nil {
+		return nil, err
+	}
+	var commits []Commit
+	for _, entry := range strings.Split(string(out), "\x00") {
+		fields := strings.SplitN(entry, "\x01", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		sec, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse commit date")
+		}
+		commits = append(commits, Commit{
+			Hash:    fields[0],
+			Author:  fields[1],
+			When:    time.Unix(sec, 0),
+			Message: fields[3],
+		})
+	}
+	return commits, nil
+}
@@ -0,0 +1,272 @@
+package gitutil
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// defaultUsername is used when a token is given without user name.
+// Token authentication of most git hosting services accepts any user name.
+const defaultUsername = "volt"
+
+// Credential is a user name and a password (or a token) to access
+// repositories over HTTP(S).
+type Credential struct {
+	Username string
+	Password string
+}
+
+// String does not show the password, so that it is never written to logs.
+func (c *Credential) String() string {
+	return c.Username + ":*******"
+}
+
+// credentials looks up the credential of each host, and caches it.
+type credentials struct {
+	cfg   *config.Config
+	mu    sync.Mutex
+	cache map[string]*Credential
+	// fromGit is true for the hosts whose credential was got by
+	// "git credential fill"
+	fromGit map[string]bool
+}
+
+func newCredentials(cfg *config.Config) *credentials {
+	return &credentials{cfg: cfg, cache: make(map[string]*Credential), fromGit: make(map[string]bool)}
+}
+
+// lookup returns the credential of the host of rawurl.
+// It returns nil if rawurl is not HTTPS URL, rawurl has user info, or no
+// credential is found. Credentials are never sent over plain HTTP, because
+// they would be sent in cleartext. Credentials are looked up in the following
+// order:
+//
+//  1. $VOLT_TOKEN_{HOST} environment variable
+//  2. credentials."{host}" of config.toml
+//  3. ~/.netrc
+//  4. "git credential fill"
+func (c *credentials) lookup(rawurl string) (*Credential, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil || u.Scheme != "https" || u.User != nil {
+		return nil, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cred, exists := c.cache[u.Host]; exists {
+		return cred, nil
+	}
+
+	host := u.Hostname()
+	var cred *Credential
+	if token := os.Getenv(tokenEnvName(host)); token != "" {
+		logger.Debugf("use credential of $%s for %s", tokenEnvName(host), host)
+		cred = &Credential{Username: defaultUsername, Password: token}
+	} else if cfgCred, exists := c.cfg.Credentials[host]; exists {
+		logger.Debugf("use credential of config.toml for %s", host)
+		cred = &Credential{Username: cfgCred.Username, Password: cfgCred.Token}
+		if cred.Username == "" {
+			cred.Username = defaultUsername
+		}
+	} else {
+		cred, err = readNetrc(netrcPath(), host)
+		if err != nil {
+			return nil, err
+		}
+		if cred != nil {
+			logger.Debugf("use credential of %s for %s", netrcPath(), host)
+		} else if HasGitCmd() {
+			cred = gitCredentialFill(u)
+			c.fromGit[u.Host] = cred != nil
+		}
+	}
+	c.cache[u.Host] = cred
+	return cred, nil
+}
+
+// reject is called when the authentication with the credential of the host
+// of rawurl failed. If the credential was got by "git credential fill", it
+// executes "git credential reject" so that credential helpers do not return
+// the bad credential again. The credential is not used in this process
+// anymore.
+func (c *credentials) reject(rawurl string) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cred := c.cache[u.Host]
+	if cred == nil {
+		return
+	}
+	if c.fromGit[u.Host] {
+		gitCredentialReject(u, cred)
+	}
+	c.cache[u.Host] = nil
+	c.fromGit[u.Host] = false
+}
+
+// tokenEnvName returns the environment variable name of the token of host.
+// Characters other than alphabets and numbers are replaced with "_"
+// (e.g. "github.example.com" -> "VOLT_TOKEN_GITHUB_EXAMPLE_COM").
+func tokenEnvName(host string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+			return r
+		case 'a' <= r && r <= 'z':
+			return r - 'a' + 'A'
+		default:
+			return '_'
+		}
+	}, host)
+	return "VOLT_TOKEN_" + name
+}
+
+func netrcPath() string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(pathutil.HomeDir(), "_netrc")
+	}
+	return filepath.Join(pathutil.HomeDir(), ".netrc")
+}
+
+// readNetrc returns the login and password of machine in netrc file.
+// It returns nil if the file or the machine does not exist.
+func readNetrc(path, machine string) (*Credential, error) {
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to read "+path)
+	}
+	return parseNetrc(string(content), machine), nil
+}
+
+func parseNetrc(content, machine string) *Credential {
+	// Split into tokens, skipping macro definitions which continue until an
+	// empty line
+	var tokens []string
+	inMacdef := false
+	for _, line := range strings.Split(content, "\n") {
+		if inMacdef {
+			inMacdef = strings.TrimSpace(line) != ""
+			continue
+		}
+		for _, token := range strings.Fields(line) {
+			if token == "macdef" {
+				inMacdef = true
+				break
+			}
+			tokens = append(tokens, token)
+		}
+	}
+
+	var found, def, current *Credential
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "machine":
+			current = nil
+			if i+1 < len(tokens) {
+				i++
+				if tokens[i] == machine && found == nil {
+					found = &Credential{}
+					current = found
+				}
+			}
+		case "default":
+			current = nil
+			if def == nil {
+				def = &Credential{}
+				current = def
+			}
+		case "login", "password", "account":
+			if i+1 >= len(tokens) {
+				break
+			}
+			i++
+			if current == nil {
+				continue
+			}
+			if tokens[i-1] == "login" {
+				current.Username = tokens[i]
+			} else if tokens[i-1] == "password" {
+				current.Password = tokens[i]
+			}
+		}
+	}
+	if found == nil {
+		found = def
+	}
+	if found == nil || found.Password == "" {
+		return nil
+	}
+	if found.Username == "" {
+		found.Username = defaultUsername
+	}
+	return found
+}
+
+// gitCredentialFill executes "git credential fill" to get the credential
+// from credential helpers of git. It returns nil if no credential is found.
+func gitCredentialFill(u *url.URL) *Credential {
+	cmd := exec.Command("git", "credential", "fill")
+	// Do not ask user
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=true")
+	cmd.Stdin = strings.NewReader("protocol=" + u.Scheme + "\nhost=" + u.Host + "\n\n")
+	out, err := cmd.Output()
+	if err != nil {
+		// Do not show the output because it may contain the password
+		logger.Debugf("\"git credential fill\" failed for %s: %s", u.Host, err.Error())
+		return nil
+	}
+	cred := &Credential{}
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	for scanner.Scan() {
+		kv := strings.SplitN(scanner.Text(), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "username":
+			cred.Username = kv[1]
+		case "password":
+			cred.Password = kv[1]
+		}
+	}
+	if cred.Password == "" {
+		return nil
+	}
+	logger.Debugf("use credential of \"git credential fill\" for %s", u.Host)
+	if cred.Username == "" {
+		cred.Username = defaultUsername
+	}
+	return cred
+}
+
+// gitCredentialReject executes "git credential reject" to remove cred from
+// credential helpers of git.
+func gitCredentialReject(u *url.URL, cred *Credential) {
+	cmd := exec.Command("git", "credential", "reject")
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=true")
+	cmd.Stdin = strings.NewReader("protocol=" + u.Scheme + "\nhost=" + u.Host +
+		"\nusername=" + cred.Username + "\npassword=" + cred.Password + "\n\n")
+	if err := cmd.Run(); err != nil {
+		logger.Debugf("\"git credential reject\" failed for %s: %s", u.Host, err.Error())
+		return
+	}
+	logger.Debugf("rejected the credential of \"git credential fill\" for %s", u.Host)
+}
@@ -0,0 +1,230 @@
+package gitutil
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+// FakeBackend is an in-memory Backend for tests.
+// Repositories are registered by AddRepos(), and Clone() copies the
+// repository registered as the URL. Nothing is written to filesystem.
+type FakeBackend struct {
+	mu    sync.Mutex
+	repos map[string]*FakeRepos
+	// Calls are called methods and their first argument (e.g. "Clone https://github.com/tyru/caw.vim")
+	Calls []string
+}
+
+// FakeRepos is a repository of FakeBackend.
+type FakeRepos struct {
+	Bare   bool
+	Dirty  bool
+	Branch string
+	// Commits are commits of current branch from oldest to newest.
+	// HEAD is the last commit.
+	Commits []Commit
+	// Files are files of the tree of each commit hash
+	Files map[string][]FakeFile
+	// Upstream is the repository which Fetch() and FastForward() fetch
+	// commits from. Clone() sets the cloned repository.
+	Upstream *FakeRepos
+}
+
+// FakeFile is a file in a tree of FakeRepos.
+type FakeFile struct {
+	Name    string
+	Mode    os.FileMode
+	Content []byte
+}
+
+// NewFakeBackend returns an empty FakeBackend.
+func NewFakeBackend() *FakeBackend {
+	return &FakeBackend{repos: make(map[string]*FakeRepos)}
+}
+
+// AddRepos registers repos as dir (or URL).
+func (b *FakeBackend) AddRepos(dir string, repos *FakeRepos) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if repos.Branch == "" {
+		repos.Branch = "master"
+	}
+	if repos.Files == nil {
+		repos.Files = make(map[string][]FakeFile)
+	}
+	b.repos[dir] = repos
+}
+
+// Repos returns the repository registered as dir, or nil if not found.
+func (b *FakeBackend) Repos(dir string) *FakeRepos {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.repos[dir]
+}
+
+func (b *FakeBackend) open(method, dir string) (*FakeRepos, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.Calls = append(b.Calls, method+" "+dir)
+	repos, exists := b.repos[dir]
+	if !exists {
+		return nil, errors.New("repository does not exist: " + dir)
+	}
+	return repos, nil
+}
+
+func (b *FakeBackend) Clone(url, dir string) error {
+	src, err := b.open("Clone", url)
+	if err != nil {
+		return err
+	}
+	if b.Repos(dir) != nil {
+		return errors.New("repository already exists: " + dir)
+	}
+	b.AddRepos(dir, &FakeRepos{
+		Branch:   src.Branch,
+		Commits:  append([]Commit{}, src.Commits...),
+		Files:    src.Files,
+		Upstream: src,
+	})
+	return nil
+}
+
+func (b *FakeBackend) Fetch(dir string) error {
+	return b.update("Fetch", dir)
+}
+
+func (b *FakeBackend) FastForward(dir string) error {
+	return b.update("FastForward", dir)
+}
+
+func (b *FakeBackend) update(method, dir string) error {
+	repos, err := b.open(method, dir)
+	if err != nil {
+		return err
+	}
+	if repos.Upstream == nil {
+		return errors.New("no upstream remote: " + dir)
+	}
+	if len(repos.Upstream.Commits) == len(repos.Commits) {
+		return ErrAlreadyUpToDate
+	}
+	repos.Commits = append([]Commit{}, repos.Upstream.Commits...)
+	repos.Files = repos.Upstream.Files
+	return nil
+}
+
+func (b *FakeBackend) ResolveRef(dir, ref string) (string, error) {
+	repos, err := b.open("ResolveRef", dir)
+	if err != nil {
+		return "", err
+	}
+	if len(repos.Commits) == 0 {
+		return "", errors.Errorf("could not resolve '%s'", ref)
+	}
+	switch ref {
+	case "HEAD", "refs/heads/" + repos.Branch, "refs/remotes/origin/" + repos.Branch:
+		return repos.Commits[len(repos.Commits)-1].Hash, nil
+	}
+	for _, c := range repos.Commits {
+		if c.Hash == ref {
+			return c.Hash, nil
+		}
+	}
+	return "", errors.Errorf("could not resolve '%s'", ref)
+}
+
+func (b *FakeBackend) CurrentBranch(dir string) (string, error) {
+	repos, err := b.open("CurrentBranch", dir)
+	if err != nil {
+		return "", err
+	}
+	return repos.Branch, nil
+}
+
+func (b *FakeBackend) IsBare(dir string) (bool, error) {
+	repos, err := b.open("IsBare", dir)
+	if err != nil {
+		return false, err
+	}
+	return repos.Bare, nil
+}
+
+func (b *FakeBackend) IsClean(dir string) (bool, error) {
+	repos, err := b.open("IsClean", dir)
+	if err != nil {
+		return false, err
+	}
+	return !repos.Dirty, nil
+}
+
+func (b *FakeBackend) ReadTree(dir, commit string, fn func(file *TreeFile) error) error {
+	repos, err := b.open("ReadTree", dir)
+	if err != nil {
+		return err
+	}
+	files, exists := repos.Files[commit]
+	if !exists {
+		return errors.New("failed to get tree " + commit)
+	}
+	for i := range files {
+		content := files[i].Content
+		file := NewTreeFile(files[i].Name, fakeHash(content), files[i].Mode, func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(content)), nil
+		})
+		if err := fn(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *FakeBackend) Log(dir, from, to string) ([]Commit, error) {
+	repos, err := b.open("Log", dir)
+	if err != nil {
+		return nil, err
+	}
+	// Commits are linear, so commits before "from" are its ancestors. Like
+	// "git log from..to", "from" must exist.
+	if from != "" && !repos.hasCommit(from) {
+		return nil, errors.Errorf("could not resolve '%s'", from)
+	}
+	var commits []Commit
+	found := false
+	for i := len(repos.Commits) - 1; i >= 0; i-- {
+		c := repos.Commits[i]
+		if c.Hash == to {
+			found = true
+		}
+		if c.Hash == from {
+			break
+		}
+		if found {
+			commits = append(commits, c)
+		}
+	}
+	if !found {
+		return nil, errors.Errorf("could not resolve '%s'", to)
+	}
+	return commits, nil
+}
+
+func (repos *FakeRepos) hasCommit(hash string) bool {
+	for i := range repos.Commits {
+		if repos.Commits[i].Hash == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// fakeHash returns git blob hash of content.
+func fakeHash(content []byte) string {
+	return plumbing.ComputeHash(plumbing.BlobObject, content).String()
+}
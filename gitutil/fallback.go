@@ -0,0 +1,68 @@
+package gitutil
+
+import (
+	"os"
+
+	"github.com/vim-volt/volt/logger"
+)
+
+// fallbackBackend is Backend which uses primary, and tries secondary when
+// cloning, fetching, or fast-forwarding by primary failed.
+type fallbackBackend struct {
+	primary   Backend
+	secondary Backend
+}
+
+func (b *fallbackBackend) Clone(url, dir string) error {
+	err := b.primary.Clone(url, dir)
+	if err == nil {
+		return nil
+	}
+	logger.Warnf("failed to clone, try to execute \"git clone --recursive %s %s\" instead...: %s", url, dir, err.Error())
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	return b.secondary.Clone(url, dir)
+}
+
+func (b *fallbackBackend) Fetch(dir string) error {
+	err := b.primary.Fetch(dir)
+	if err == nil || err == ErrAlreadyUpToDate {
+		return err
+	}
+	logger.Warnf("failed to fetch, try to execute \"git fetch\" instead...: %s", err.Error())
+	return b.secondary.Fetch(dir)
+}
+
+func (b *fallbackBackend) FastForward(dir string) error {
+	err := b.primary.FastForward(dir)
+	if err == nil || err == ErrAlreadyUpToDate {
+		return err
+	}
+	logger.Warnf("failed to pull, try to execute \"git pull\" instead...: %s", err.Error())
+	return b.secondary.FastForward(dir)
+}
+
+func (b *fallbackBackend) ResolveRef(dir, ref string) (string, error) {
+	return b.primary.ResolveRef(dir, ref)
+}
+
+func (b *fallbackBackend) CurrentBranch(dir string) (string, error) {
+	return b.primary.CurrentBranch(dir)
+}
+
+func (b *fallbackBackend) IsBare(dir string) (bool, error) {
+	return b.primary.IsBare(dir)
+}
+
+func (b *fallbackBackend) IsClean(dir string) (bool, error) {
+	return b.primary.IsClean(dir)
+}
+
+func (b *fallbackBackend) ReadTree(dir, commit string, fn func(file *TreeFile) error) error {
+	return b.primary.ReadTree(dir, commit, fn)
+}
+
+func (b *fallbackBackend) Log(dir, from, to string) ([]Commit, error) {
+	return b.primary.Log(dir, from, to)
+}
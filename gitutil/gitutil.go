@@ -1,115 +1,153 @@
 package gitutil
 
 import (
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
 	"regexp"
+	"runtime"
+	"time"
 
 	"github.com/pkg/errors"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/client"
+	githttp "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
 
-	"github.com/vim-volt/volt/pathutil"
-	git "gopkg.in/src-d/go-git.v4"
-	"gopkg.in/src-d/go-git.v4/plumbing"
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/httputil"
 )
 
 var refHeadsRx = regexp.MustCompile(`^refs/heads/(.+)$`)
+var hashRx = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// ErrAlreadyUpToDate is returned by Backend.Fetch() and
+// Backend.FastForward() when a repository was not changed.
+var ErrAlreadyUpToDate = errors.New("already up-to-date")
+
+// Backend is an implementation of git operations which volt uses.
+// All methods take the directory of a repository.
+type Backend interface {
+	// Clone clones url to dir, and sets the upstream remote of current
+	// branch to "origin".
+	Clone(url, dir string) error
+	// Fetch fetches objects from the upstream remote of current branch.
+	// Returns ErrAlreadyUpToDate if the remote branch was not changed.
+	Fetch(dir string) error
+	// FastForward fetches objects from the upstream remote of current branch
+	// and fast-forwards current branch.
+	// Returns ErrAlreadyUpToDate if HEAD was not changed.
+	FastForward(dir string) error
+	// ResolveRef returns the commit hash which ref (e.g. "HEAD",
+	// "refs/remotes/origin/master", commit hash) points to.
+	ResolveRef(dir, ref string) (string, error)
+	// CurrentBranch returns the branch name of HEAD (e.g. "master").
+	CurrentBranch(dir string) (string, error)
+	// IsBare returns true if dir is a bare repository.
+	// Returns an error if dir is not a git repository.
+	IsBare(dir string) (bool, error)
+	// IsClean returns true if the worktree has no changes.
+	IsClean(dir string) (bool, error)
+	// ReadTree calls fn with each file in the tree of commit.
+	ReadTree(dir, commit string, fn func(file *TreeFile) error) error
+	// Log returns commits which are reachable from to, but not from from
+	// (from..to). Commits are ordered from newest to oldest.
+	Log(dir, from, to string) ([]Commit, error)
+}
 
-// GetHEAD gets HEAD reference hash string from reposPath.
-// See GetHEADRepository.
-func GetHEAD(reposPath pathutil.ReposPath) (string, error) {
-	repos, err := git.PlainOpen(reposPath.FullPath())
-	if err != nil {
-		return "", err
-	}
-	return GetHEADRepository(repos)
+// TreeFile is a file in a tree of git repository.
+type TreeFile struct {
+	// Name is a slash-separated path relative to the root of the tree
+	Name string
+	// Hash is a blob hash
+	Hash string
+	Mode os.FileMode
+	open func() (io.ReadCloser, error)
 }
 
-// GetHEADRepository gets HEAD reference hash string from git.Repository.
-// If the repository is bare:
-//   Return the reference of refs/remotes/origin/{branch}
-//   where {branch} is default branch
-// If the repository is non-bare:
-//   Return the reference of current branch's HEAD
-func GetHEADRepository(repos *git.Repository) (string, error) {
-	head, err := repos.Head()
-	if err != nil {
-		return "", err
-	}
+// NewTreeFile returns TreeFile. open is called by TreeFile.Reader().
+func NewTreeFile(name, hash string, mode os.FileMode, open func() (io.ReadCloser, error)) *TreeFile {
+	return &TreeFile{Name: name, Hash: hash, Mode: mode, open: open}
+}
 
-	cfg, err := repos.Config()
-	if err != nil {
-		return "", err
-	}
+// Reader returns the contents of the file.
+func (f *TreeFile) Reader() (io.ReadCloser, error) {
+	return f.open()
+}
 
-	if !cfg.Core.IsBare {
-		// Get reference of local {branch} HEAD
-		commit, err := repos.CommitObject(head.Hash())
-		if err != nil {
-			return "", err
-		}
-		return commit.Hash.String(), nil
-	}
+// Commit is a commit of git repository.
+type Commit struct {
+	Hash    string
+	Author  string
+	When    time.Time
+	Message string
+}
 
-	// Get branch name from head.Name().String()
-	// e.g. head.Name().String() = "refs/heads/master"
-	defaultBranch := refHeadsRx.FindStringSubmatch(head.Name().String())
-	if len(defaultBranch) == 0 {
-		return "", errors.New("could not find branch name from HEAD")
+// NewBackend returns Backend selected by get.git_backend of cfg.
+// go-git uses credentials of config.toml, environment variables, ~/.netrc,
+// and "git credential fill" for HTTP(S) repositories. git command uses its
+// own credential helpers.
+func NewBackend(cfg *config.Config) (Backend, error) {
+	if err := installHTTPClient(cfg); err != nil {
+		return nil, err
 	}
-
-	// Get reference of remote origin/{branch} HEAD
-	ref, err := repos.Reference(plumbing.ReferenceName("refs/remotes/origin/"+defaultBranch[1]), true)
-	if err != nil {
-		return "", err
+	gogit := &gogitBackend{creds: newCredentials(cfg)}
+	switch cfg.Get.GitBackend {
+	case config.AutoGitBackend:
+		if *cfg.Get.FallbackGitCmd && HasGitCmd() {
+			return &fallbackBackend{gogit, &cliBackend{}}, nil
+		}
+		return gogit, nil
+	case config.GoGitBackend:
+		return gogit, nil
+	case config.CLIGitBackend:
+		return &cliBackend{}, nil
+	default:
+		return nil, errors.New("unknown git backend: " + cfg.Get.GitBackend)
 	}
-	return ref.Hash().String(), nil
 }
 
-// SetUpstreamRemote sets current branch's upstream remote name to remote.
-func SetUpstreamRemote(r *git.Repository, remote string) error {
-	cfg, err := r.Config()
-	if err != nil {
-		return err
-	}
-
-	head, err := r.Head()
+// installHTTPClient makes go-git use HTTP client configured by [http] section
+// of cfg. go-git has only global HTTP client for each protocol.
+func installHTTPClient(cfg *config.Config) error {
+	transport, err := httputil.NewTransport(cfg)
 	if err != nil {
 		return err
 	}
+	// Do not set http.Client.Timeout, because it includes the time to read
+	// response body and it would make cloning large repositories fail
+	c := githttp.NewClient(&http.Client{Transport: transport})
+	client.InstallProtocol("http", c)
+	client.InstallProtocol("https", c)
+	return nil
+}
 
-	refBranch := head.Name().String()
-	branch := refHeadsRx.FindStringSubmatch(refBranch)
-	if len(branch) == 0 {
-		return errors.New("HEAD is not matched to refs/heads/...: " + refBranch)
+// HasGitCmd returns true if git command is installed.
+func HasGitCmd() bool {
+	exeName := "git"
+	if runtime.GOOS == "windows" {
+		exeName = "git.exe"
 	}
-
-	subsec := cfg.Raw.Section("branch").Subsection(branch[1])
-	subsec.AddOption("remote", remote)
-	subsec.AddOption("merge", refBranch)
-
-	return r.Storer.SetConfig(cfg)
+	_, err := exec.LookPath(exeName)
+	return err == nil
 }
 
-// GetUpstreamRemote gets current branch's upstream remote name (e.g. "origin").
-func GetUpstreamRemote(r *git.Repository) (string, error) {
-	cfg, err := r.Config()
+// GetHEAD gets HEAD reference hash string from dir.
+// If the repository is bare:
+// Return the reference of refs/remotes/origin/{branch} where {branch} is
+// default branch.
+// If the repository is non-bare:
+// Return the reference of current branch's HEAD.
+func GetHEAD(b Backend, dir string) (string, error) {
+	bare, err := b.IsBare(dir)
 	if err != nil {
 		return "", err
 	}
-
-	head, err := r.Head()
+	if !bare {
+		return b.ResolveRef(dir, "HEAD")
+	}
+	branch, err := b.CurrentBranch(dir)
 	if err != nil {
 		return "", err
 	}
-
-	refBranch := head.Name().String()
-	branch := refHeadsRx.FindStringSubmatch(refBranch)
-	if len(branch) == 0 {
-		return "", errors.New("HEAD is not matched to refs/heads/...: " + refBranch)
-	}
-
-	subsec := cfg.Raw.Section("branch").Subsection(branch[1])
-	remote := subsec.Option("remote")
-	if remote == "" {
-		return "", errors.Errorf("gitconfig 'branch.%s.remote' is not found", subsec.Name)
-	}
-	return remote, nil
+	return b.ResolveRef(dir, "refs/remotes/origin/"+branch)
 }
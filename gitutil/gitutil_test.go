@@ -0,0 +1,287 @@
+package gitutil
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vim-volt/volt/config"
+	"gopkg.in/src-d/go-billy.v3/osfs"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/client"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/server"
+)
+
+// testOrigin is an upstream repository which a backend clones from.
+type testOrigin interface {
+	url() string
+	// commit adds a commit which has file "plugin/{name}.vim", and returns the hash
+	commit(t *testing.T, name string) string
+}
+
+// gitOrigin is a git repository on filesystem.
+type gitOrigin struct {
+	dir string
+	r   *git.Repository
+}
+
+func newGitOrigin(t *testing.T, dir string) *gitOrigin {
+	r, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal("failed to init repository: " + err.Error())
+	}
+	// go-git server does not serve a repository without .git/config
+	cfg, err := r.Config()
+	if err == nil {
+		err = r.Storer.SetConfig(cfg)
+	}
+	if err != nil {
+		t.Fatal("failed to write config: " + err.Error())
+	}
+	return &gitOrigin{dir: dir, r: r}
+}
+
+func (o *gitOrigin) url() string {
+	// go-git server needs the path of .git directory
+	return filepath.Join(o.dir, ".git")
+}
+
+func (o *gitOrigin) commit(t *testing.T, name string) string {
+	rel := filepath.Join("plugin", name+".vim")
+	os.MkdirAll(filepath.Join(o.dir, "plugin"), 0755)
+	if err := ioutil.WriteFile(filepath.Join(o.dir, rel), []byte(name), 0644); err != nil {
+		t.Fatal("failed to write file: " + err.Error())
+	}
+	w, err := o.r.Worktree()
+	if err != nil {
+		t.Fatal("failed to get worktree: " + err.Error())
+	}
+	if _, err := w.Add(filepath.ToSlash(rel)); err != nil {
+		t.Fatal("failed to add file: " + err.Error())
+	}
+	hash, err := w.Commit(name, &git.CommitOptions{
+		Author: &object.Signature{Name: "John Doe", Email: "john@doe.org", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal("failed to commit: " + err.Error())
+	}
+	return hash.String()
+}
+
+// fakeOrigin is a repository of FakeBackend.
+type fakeOrigin struct {
+	repos *FakeRepos
+	files []FakeFile
+}
+
+func (o *fakeOrigin) url() string {
+	return "https://example.com/user/name"
+}
+
+func (o *fakeOrigin) commit(t *testing.T, name string) string {
+	hash := fakeHash([]byte(time.Now().String() + name))
+	o.files = append(o.files, FakeFile{Name: "plugin/" + name + ".vim", Mode: 0644, Content: []byte(name)})
+	o.repos.Commits = append(o.repos.Commits, Commit{Hash: hash, Author: "John Doe", When: time.Now(), Message: name})
+	o.repos.Files[hash] = append([]FakeFile{}, o.files...)
+	return hash
+}
+
+func TestBackend(t *testing.T) {
+	for _, name := range []string{"gogit", "cli", "fake"} {
+		t.Run(name, func(t *testing.T) {
+			tempDir, err := ioutil.TempDir("", "volt-test-gitutil-")
+			if err != nil {
+				t.Fatal("failed to create temp dir: " + err.Error())
+			}
+			defer os.RemoveAll(tempDir)
+
+			var b Backend
+			var origin testOrigin
+			switch name {
+			case "gogit":
+				// Serve local repositories by go-git instead of git-upload-pack
+				// command, which may not be compatible with go-git
+				client.InstallProtocol("file", server.NewServer(server.NewFilesystemLoader(osfs.New("/"))))
+				b = &gogitBackend{}
+				origin = newGitOrigin(t, filepath.Join(tempDir, "origin"))
+			case "cli":
+				if !HasGitCmd() {
+					t.Skip("git command is not installed")
+				}
+				b = &cliBackend{}
+				origin = newGitOrigin(t, filepath.Join(tempDir, "origin"))
+			case "fake":
+				fake := NewFakeBackend()
+				o := &fakeOrigin{repos: &FakeRepos{}}
+				fake.AddRepos(o.url(), o.repos)
+				b, origin = fake, o
+			}
+			testBackend(t, b, origin, filepath.Join(tempDir, "repos"))
+		})
+	}
+}
+
+func testBackend(t *testing.T, b Backend, origin testOrigin, dir string) {
+	first := origin.commit(t, "first")
+	if err := b.Clone(origin.url(), dir); err != nil {
+		t.Fatal("Clone() failed: " + err.Error())
+	}
+
+	if bare, err := b.IsBare(dir); err != nil || bare {
+		t.Errorf("IsBare(): expected false but got %v, %v", bare, err)
+	}
+	if clean, err := b.IsClean(dir); err != nil || !clean {
+		t.Errorf("IsClean(): expected true but got %v, %v", clean, err)
+	}
+	if branch, err := b.CurrentBranch(dir); err != nil || branch != "master" {
+		t.Errorf("CurrentBranch(): expected master but got %q, %v", branch, err)
+	}
+	if head, err := GetHEAD(b, dir); err != nil || head != first {
+		t.Errorf("GetHEAD(): expected %s but got %q, %v", first, head, err)
+	}
+
+	var names []string
+	err := b.ReadTree(dir, first, func(file *TreeFile) error {
+		r, err := file.Reader()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		content, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if string(content) != "first" || file.Mode != 0644 || file.Hash != fakeHash(content) {
+			t.Errorf("ReadTree(): unexpected file %s: mode=%v, hash=%s, content=%q", file.Name, file.Mode, file.Hash, content)
+		}
+		names = append(names, file.Name)
+		return nil
+	})
+	if err != nil || len(names) != 1 || names[0] != "plugin/first.vim" {
+		t.Errorf("ReadTree(): expected [plugin/first.vim] but got %v, %v", names, err)
+	}
+
+	second := origin.commit(t, "second")
+	if err := b.FastForward(dir); err != nil {
+		t.Fatal("FastForward() failed: " + err.Error())
+	}
+	if err := b.FastForward(dir); err != ErrAlreadyUpToDate {
+		t.Errorf("FastForward(): expected ErrAlreadyUpToDate but got %v", err)
+	}
+	if head, err := b.ResolveRef(dir, "HEAD"); err != nil || head != second {
+		t.Errorf("ResolveRef(HEAD): expected %s but got %q, %v", second, head, err)
+	}
+
+	commits, err := b.Log(dir, first, second)
+	if err != nil || len(commits) != 1 || commits[0].Hash != second {
+		t.Errorf("Log(): expected [%s] but got %+v, %v", second, commits, err)
+	}
+}
+
+// TestBackendLog checks Backend.Log() returns the same commits as
+// "git log from..to" even if "from" is not an ancestor of "to".
+func TestBackendLog(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-gitutil-")
+	if err != nil {
+		t.Fatal("failed to create temp dir: " + err.Error())
+	}
+	defer os.RemoveAll(tempDir)
+
+	// first - second (master)
+	//       \ third  (forced)
+	origin := newGitOrigin(t, tempDir)
+	first := origin.commit(t, "first")
+	second := origin.commit(t, "second")
+	w, err := origin.r.Worktree()
+	if err != nil {
+		t.Fatal("failed to get worktree: " + err.Error())
+	}
+	err = w.Checkout(&git.CheckoutOptions{
+		Hash:   plumbing.NewHash(first),
+		Branch: plumbing.ReferenceName("refs/heads/forced"),
+		Create: true,
+	})
+	if err != nil {
+		t.Fatal("failed to checkout: " + err.Error())
+	}
+	third := origin.commit(t, "third")
+
+	backends := map[string]Backend{"gogit": &gogitBackend{}}
+	if HasGitCmd() {
+		backends["cli"] = &cliBackend{}
+	}
+	for _, tt := range []struct {
+		from     string
+		to       string
+		expected []string
+	}{
+		{first, second, []string{second}},
+		{first, third, []string{third}},
+		{second, third, []string{third}},
+		{third, third, nil},
+		{"", third, []string{third, first}},
+	} {
+		for name, b := range backends {
+			commits, err := b.Log(tempDir, tt.from, tt.to)
+			var hashes []string
+			for _, c := range commits {
+				hashes = append(hashes, c.Hash)
+			}
+			if err != nil || !reflect.DeepEqual(hashes, tt.expected) {
+				t.Errorf("%s: Log(%q, %q): expected %v but got %v, %v", name, tt.from, tt.to, tt.expected, hashes, err)
+			}
+		}
+	}
+
+	for name, b := range backends {
+		if _, err := b.Log(tempDir, fakeHash([]byte("unknown")), third); err == nil {
+			t.Errorf("%s: Log() with unknown commit must fail", name)
+		}
+	}
+	fake := NewFakeBackend()
+	fake.AddRepos(tempDir, &FakeRepos{Commits: []Commit{{Hash: first}, {Hash: third}}})
+	if _, err := fake.Log(tempDir, second, third); err == nil {
+		t.Errorf("fake: Log() with unknown commit must fail")
+	}
+	if commits, err := fake.Log(tempDir, first, third); err != nil || len(commits) != 1 || commits[0].Hash != third {
+		t.Errorf("fake: Log(): expected [%s] but got %+v, %v", third, commits, err)
+	}
+}
+
+func TestGogitCloneTimeout(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "volt-test-gitutil-")
+	if err != nil {
+		t.Fatal("failed to create temp dir: " + err.Error())
+	}
+	defer os.RemoveAll(tempDir)
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-git-upload-pack-advertisement")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		// Stop sending data after the headers
+		<-done
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	cfg := &config.Config{}
+	cfg.HTTP.Timeout = "100ms"
+	if err := installHTTPClient(cfg); err != nil {
+		t.Fatal("failed to install HTTP client: " + err.Error())
+	}
+	b := &gogitBackend{creds: newCredentials(cfg)}
+	url := srv.URL + "/user/name"
+	err = b.Clone(url, filepath.Join(tempDir, "repos"))
+	if err == nil || !strings.Contains(err.Error(), "timed out to access "+url) {
+		t.Errorf("expected timeout error but got %v", err)
+	}
+}
@@ -0,0 +1,320 @@
+package gitutil
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/httputil"
+
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+)
+
+// gogitBackend is Backend implemented by go-git.
+type gogitBackend struct {
+	// creds is used for HTTP(S) authentication if non-nil
+	creds *credentials
+}
+
+// auth returns AuthMethod for url, or nil if no credential is found.
+func (b *gogitBackend) auth(url string) (transport.AuthMethod, error) {
+	if b.creds == nil {
+		return nil, nil
+	}
+	cred, err := b.creds.lookup(url)
+	if err != nil || cred == nil {
+		return nil, err
+	}
+	return http.NewBasicAuth(cred.Username, cred.Password), nil
+}
+
+// checkAuth rejects the credential of url if err is an authentication error,
+// and returns err.
+func (b *gogitBackend) checkAuth(url string, err error) error {
+	if b.creds != nil && (err == transport.ErrAuthenticationRequired || err == transport.ErrAuthorizationFailed) {
+		b.creds.reject(url)
+	}
+	return err
+}
+
+// remoteURL returns the URL of remote.
+func remoteURL(r *git.Repository, remote string) (string, error) {
+	rem, err := r.Remote(remote)
+	if err != nil {
+		return "", err
+	}
+	if len(rem.Config().URLs) == 0 {
+		return "", errors.Errorf("remote '%s' has no URL", remote)
+	}
+	return rem.Config().URLs[0], nil
+}
+
+// wrapTimeout returns an error which describes timeout if err is caused by
+// timeout of HTTP.
+func wrapTimeout(err error, url string) error {
+	cause := err
+	if e, ok := err.(*plumbing.UnexpectedError); ok {
+		cause = e.Err
+	}
+	if httputil.IsTimeout(cause) {
+		return errors.Errorf("timed out to access %s (see http.timeout of config.toml): %s", url, cause.Error())
+	}
+	return err
+}
+
+func (b *gogitBackend) Clone(url, dir string) error {
+	auth, err := b.auth(url)
+	if err != nil {
+		return err
+	}
+	r, err := git.PlainClone(dir, false, &git.CloneOptions{
+		URL:  url,
+		Auth: auth,
+		// TODO: Temporarily recursive clone is disabled, because go-git does
+		// not support relative submodule url in .gitmodules and it causes an
+		// error
+		RecurseSubmodules: 0,
+	})
+	if err != nil {
+		return wrapTimeout(b.checkAuth(url, err), url)
+	}
+	return setUpstreamRemote(r, "origin")
+}
+
+func (b *gogitBackend) Fetch(dir string) error {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return err
+	}
+	remote, err := getUpstreamRemote(r)
+	if err != nil {
+		return err
+	}
+	url, err := remoteURL(r, remote)
+	if err != nil {
+		return err
+	}
+	auth, err := b.auth(url)
+	if err != nil {
+		return err
+	}
+	err = r.Fetch(&git.FetchOptions{
+		RemoteName: remote,
+		Auth:       auth,
+	})
+	if err == git.NoErrAlreadyUpToDate {
+		return ErrAlreadyUpToDate
+	}
+	return wrapTimeout(b.checkAuth(url, err), url)
+}
+
+func (b *gogitBackend) FastForward(dir string) error {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return err
+	}
+	remote, err := getUpstreamRemote(r)
+	if err != nil {
+		return err
+	}
+	url, err := remoteURL(r, remote)
+	if err != nil {
+		return err
+	}
+	auth, err := b.auth(url)
+	if err != nil {
+		return err
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+	err = wt.Pull(&git.PullOptions{
+		RemoteName: remote,
+		Auth:       auth,
+		// TODO: Temporarily recursive clone is disabled, because go-git does
+		// not support relative submodule url in .gitmodules and it causes an
+		// error
+		RecurseSubmodules: 0,
+	})
+	if err == git.NoErrAlreadyUpToDate {
+		return ErrAlreadyUpToDate
+	}
+	return wrapTimeout(b.checkAuth(url, err), url)
+}
+
+func (*gogitBackend) ResolveRef(dir, ref string) (string, error) {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return "", err
+	}
+	if hashRx.MatchString(ref) {
+		commit, err := r.CommitObject(plumbing.NewHash(ref))
+		if err != nil {
+			return "", errors.Wrapf(err, "could not resolve '%s'", ref)
+		}
+		return commit.Hash.String(), nil
+	}
+	hash, err := r.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return "", errors.Wrapf(err, "could not resolve '%s'", ref)
+	}
+	return hash.String(), nil
+}
+
+func (*gogitBackend) CurrentBranch(dir string) (string, error) {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return "", err
+	}
+	return currentBranch(r)
+}
+
+func (*gogitBackend) IsBare(dir string) (bool, error) {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return false, err
+	}
+	cfg, err := r.Config()
+	if err != nil {
+		return false, err
+	}
+	return cfg.Core.IsBare, nil
+}
+
+func (*gogitBackend) IsClean(dir string) (bool, error) {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return false, err
+	}
+	wt, err := r.Worktree()
+	if err != nil {
+		return false, err
+	}
+	st, err := wt.Status()
+	if err != nil {
+		return false, err
+	}
+	return st.IsClean(), nil
+}
+
+func (*gogitBackend) ReadTree(dir, commit string, fn func(file *TreeFile) error) error {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return err
+	}
+	commitObj, err := r.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return errors.Wrap(err, "failed to get commit object "+commit)
+	}
+	tree, err := r.TreeObject(commitObj.TreeHash)
+	if err != nil {
+		return errors.Wrap(err, "failed to get tree "+commit)
+	}
+	return tree.Files().ForEach(func(file *object.File) error {
+		mode, err := file.Mode.ToOSFileMode()
+		if err != nil {
+			return errors.Wrap(err, "failed to convert file mode")
+		}
+		return fn(NewTreeFile(file.Name, file.Hash.String(), mode, func() (io.ReadCloser, error) {
+			return file.Reader()
+		}))
+	})
+}
+
+func (*gogitBackend) Log(dir, from, to string) ([]Commit, error) {
+	r, err := git.PlainOpen(dir)
+	if err != nil {
+		return nil, err
+	}
+	// Commits reachable from "from" are excluded like "git log from..to", even
+	// if "from" is not an ancestor of "to" (e.g. upstream was force-pushed)
+	excluded := make(map[plumbing.Hash]bool)
+	if from != "" {
+		iter, err := r.Log(&git.LogOptions{From: plumbing.NewHash(from)})
+		if err != nil {
+			return nil, err
+		}
+		err = iter.ForEach(func(c *object.Commit) error {
+			excluded[c.Hash] = true
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	iter, err := r.Log(&git.LogOptions{From: plumbing.NewHash(to)})
+	if err != nil {
+		return nil, err
+	}
+	var commits []Commit
+	err = iter.ForEach(func(c *object.Commit) error {
+		if excluded[c.Hash] {
+			return nil
+		}
+		commits = append(commits, Commit{
+			Hash:    c.Hash.String(),
+			Author:  c.Author.Name,
+			When:    c.Author.When,
+			Message: c.Message,
+		})
+		return nil
+	})
+	return commits, err
+}
+
+func currentBranch(r *git.Repository) (string, error) {
+	head, err := r.Head()
+	if err != nil {
+		return "", err
+	}
+	refBranch := head.Name().String()
+	branch := refHeadsRx.FindStringSubmatch(refBranch)
+	if len(branch) == 0 {
+		return "", errors.New("HEAD is not matched to refs/heads/...: " + refBranch)
+	}
+	return branch[1], nil
+}
+
+// setUpstreamRemote sets current branch's upstream remote name to remote.
+func setUpstreamRemote(r *git.Repository, remote string) error {
+	cfg, err := r.Config()
+	if err != nil {
+		return err
+	}
+
+	branch, err := currentBranch(r)
+	if err != nil {
+		return err
+	}
+
+	subsec := cfg.Raw.Section("branch").Subsection(branch)
+	subsec.AddOption("remote", remote)
+	subsec.AddOption("merge", "refs/heads/"+branch)
+
+	return r.Storer.SetConfig(cfg)
+}
+
+// getUpstreamRemote gets current branch's upstream remote name (e.g. "origin").
+func getUpstreamRemote(r *git.Repository) (string, error) {
+	cfg, err := r.Config()
+	if err != nil {
+		return "", err
+	}
+
+	branch, err := currentBranch(r)
+	if err != nil {
+		return "", err
+	}
+
+	subsec := cfg.Raw.Section("branch").Subsection(branch)
+	remote := subsec.Option("remote")
+	if remote == "" {
+		return "", errors.Errorf("gitconfig 'branch.%s.remote' is not found", subsec.Name)
+	}
+	return remote, nil
+}
@@ -0,0 +1,175 @@
+package httputil
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/config"
+)
+
+// NewTransport returns http.RoundTripper configured by [http] section of cfg.
+// Timeout is applied to connecting, TLS handshake, waiting for response
+// headers, and each read of response body. So reading a large body does not
+// time out as long as the server keeps sending data.
+func NewTransport(cfg *config.Config) (http.RoundTripper, error) {
+	timeout, err := time.ParseDuration(cfg.HTTP.Timeout)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid http.timeout")
+	}
+	proxy, err := proxyFunc(cfg.HTTP.Proxy, cfg.HTTP.NoProxy)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{}
+	if cfg.HTTP.CAFile != "" {
+		pool, err := readCAFile(cfg.HTTP.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	secure := newTransport(proxy, timeout, tlsConfig)
+	if len(cfg.HTTP.InsecureHosts) == 0 {
+		return secure, nil
+	}
+	insecureConfig := tlsConfig.Clone()
+	insecureConfig.InsecureSkipVerify = true
+	return &hostTransport{
+		secure:        secure,
+		insecure:      newTransport(proxy, timeout, insecureConfig),
+		insecureHosts: cfg.HTTP.InsecureHosts,
+	}, nil
+}
+
+// NewClient returns *http.Client which uses NewTransport(cfg).
+// The client has no total timeout, so that downloading a large file (e.g. a
+// release binary) over a slow connection does not fail partway.
+func NewClient(cfg *config.Config) (*http.Client, error) {
+	transport, err := NewTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// IsTimeout returns true if err is caused by timeout.
+func IsTimeout(err error) bool {
+	netErr, ok := errors.Cause(err).(net.Error)
+	return ok && netErr.Timeout()
+}
+
+func newTransport(proxy func(*http.Request) (*url.URL, error), timeout time.Duration, tlsConfig *tls.Config) *http.Transport {
+	return &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialContext(timeout),
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       tlsConfig,
+	}
+}
+
+// dialContext returns a function for http.Transport.DialContext.
+// The connections fail to read if the server sends no data within timeout.
+func dialContext(timeout time.Duration) func(context.Context, string, string) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &deadlineConn{Conn: conn, timeout: timeout}, nil
+	}
+}
+
+// deadlineConn sets the read deadline before each Read. The error of a read
+// which timed out is net.Error whose Timeout() returns true.
+type deadlineConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *deadlineConn) Read(b []byte) (int, error) {
+	if err := c.Conn.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
+
+// proxyFunc returns a function for http.Transport.Proxy.
+// If proxy is empty, proxy environment variables are used.
+func proxyFunc(proxy string, noProxy []string) (func(*http.Request) (*url.URL, error), error) {
+	var proxyURL *url.URL
+	if proxy != "" {
+		u, err := url.Parse(proxy)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid http.proxy")
+		}
+		proxyURL = u
+	}
+	return func(req *http.Request) (*url.URL, error) {
+		if matchHost(req.URL.Hostname(), noProxy) {
+			return nil, nil
+		}
+		if proxyURL != nil {
+			return proxyURL, nil
+		}
+		return http.ProxyFromEnvironment(req)
+	}, nil
+}
+
+func readCAFile(caFile string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read http.ca_file")
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificates were found in http.ca_file: " + caFile)
+	}
+	return pool, nil
+}
+
+// matchHost returns true if host is one of patterns or a subdomain of them.
+// Pattern "*" matches all hosts.
+func matchHost(host string, patterns []string) bool {
+	for _, p := range patterns {
+		p = strings.TrimPrefix(p, ".")
+		if p == "*" || host == p || strings.HasSuffix(host, "."+p) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostTransport does not verify TLS certificates of insecureHosts.
+type hostTransport struct {
+	secure        *http.Transport
+	insecure      *http.Transport
+	insecureHosts []string
+}
+
+func (t *hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if matchHost(req.URL.Hostname(), t.insecureHosts) {
+		return t.insecure.RoundTrip(req)
+	}
+	return t.secure.RoundTrip(req)
+}
@@ -1,34 +1,87 @@
 package httputil
 
 import (
-	"github.com/pkg/errors"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/config"
 )
 
+var defaultClient struct {
+	once    sync.Once
+	client  *http.Client
+	timeout string
+	err     error
+}
+
+// getDefaultClient returns *http.Client configured by config.toml.
+func getDefaultClient() (*http.Client, string, error) {
+	defaultClient.once.Do(func() {
+		cfg, err := config.Read()
+		if err != nil {
+			defaultClient.err = errors.Wrap(err, "could not read config.toml")
+			return
+		}
+		defaultClient.client, defaultClient.err = NewClient(cfg)
+		defaultClient.timeout = cfg.HTTP.Timeout
+	})
+	return defaultClient.client, defaultClient.timeout, defaultClient.err
+}
+
 // GetContentReader fetches url and returns io.ReadCloser.
 // Caller must close the reader.
 func GetContentReader(url string) (io.ReadCloser, error) {
-	// http.Get() allows up to 10 redirects
-	res, err := http.Get(url)
+	client, timeout, err := getDefaultClient()
 	if err != nil {
 		return nil, err
 	}
+	return getContentReader(client, timeout, url)
+}
+
+func getContentReader(client *http.Client, timeout, url string) (io.ReadCloser, error) {
+	// client.Get() allows up to 10 redirects
+	res, err := client.Get(url)
+	if err != nil {
+		if IsTimeout(err) {
+			return nil, timeoutError(url, timeout)
+		}
+		return nil, err
+	}
 	if res.StatusCode/100 != 2 {
+		res.Body.Close()
 		return nil, errors.New(url + " returned non-successful status: " + res.Status)
 	}
 	return res.Body, nil
 }
 
+func timeoutError(url, timeout string) error {
+	return errors.Errorf("timed out to fetch %s (http.timeout = %q)", url, timeout)
+}
+
 // GetContent fetches url and returns []byte.
 func GetContent(url string) ([]byte, error) {
-	r, err := GetContentReader(url)
+	client, timeout, err := getDefaultClient()
+	if err != nil {
+		return nil, err
+	}
+	return getContent(client, timeout, url)
+}
+
+func getContent(client *http.Client, timeout, url string) ([]byte, error) {
+	r, err := getContentReader(client, timeout, url)
 	if err != nil {
 		return nil, err
 	}
 	defer r.Close()
-	return ioutil.ReadAll(r)
+	content, err := ioutil.ReadAll(r)
+	if err != nil && IsTimeout(err) {
+		return nil, timeoutError(url, timeout)
+	}
+	return content, err
 }
 
 // GetContentString fetches url and returns string.
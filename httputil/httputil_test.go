@@ -0,0 +1,170 @@
+package httputil
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vim-volt/volt/config"
+)
+
+func newConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.HTTP.Timeout = "10s"
+	return cfg
+}
+
+func respond(body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, body)
+	})
+}
+
+func fetch(t *testing.T, cfg *config.Config, url string) (string, error) {
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatal("failed to create client: " + err.Error())
+	}
+	content, err := getContent(client, cfg.HTTP.Timeout, url)
+	return string(content), err
+}
+
+func TestTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	cfg := newConfig()
+	cfg.HTTP.Timeout = "100ms"
+	_, err := fetch(t, cfg, srv.URL)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected timeout error but got %v", err)
+	}
+}
+
+func TestSlowBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 5; i++ {
+			fmt.Fprint(w, "ok")
+			w.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	// Reading body takes longer than the timeout
+	cfg := newConfig()
+	cfg.HTTP.Timeout = "100ms"
+	if body, err := fetch(t, cfg, srv.URL); err != nil || body != strings.Repeat("ok", 5) {
+		t.Errorf("expected %q but got %q, %v", strings.Repeat("ok", 5), body, err)
+	}
+}
+
+func TestStalledBody(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+		w.(http.Flusher).Flush()
+		// Stop sending data after the headers
+		<-done
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	cfg := newConfig()
+	cfg.HTTP.Timeout = "100ms"
+	_, err := fetch(t, cfg, srv.URL)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("expected timeout error but got %v", err)
+	}
+}
+
+func TestInsecureHosts(t *testing.T) {
+	srv := httptest.NewTLSServer(respond("ok"))
+	defer srv.Close()
+
+	cfg := newConfig()
+	if _, err := fetch(t, cfg, srv.URL); err == nil {
+		t.Error("expected certificate error but got no error")
+	}
+	cfg.HTTP.InsecureHosts = []string{"127.0.0.1"}
+	if body, err := fetch(t, cfg, srv.URL); err != nil || body != "ok" {
+		t.Errorf("expected %q but got %q, %v", "ok", body, err)
+	}
+}
+
+func TestCAFile(t *testing.T) {
+	srv := httptest.NewTLSServer(respond("ok"))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "volt-test-httputil-")
+	if err != nil {
+		t.Fatal("failed to create temp dir: " + err.Error())
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	content := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := ioutil.WriteFile(caFile, content, 0644); err != nil {
+		t.Fatal("failed to write CA file: " + err.Error())
+	}
+
+	cfg := newConfig()
+	cfg.HTTP.CAFile = caFile
+	if body, err := fetch(t, cfg, srv.URL); err != nil || body != "ok" {
+		t.Errorf("expected %q but got %q, %v", "ok", body, err)
+	}
+}
+
+func TestProxy(t *testing.T) {
+	proxy := httptest.NewServer(respond("proxied"))
+	defer proxy.Close()
+	srv := httptest.NewServer(respond("direct"))
+	defer srv.Close()
+
+	var tests = []struct {
+		noProxy  []string
+		expected string
+	}{
+		{[]string{}, "proxied"},
+		{[]string{"example.com"}, "proxied"},
+		{[]string{"127.0.0.1"}, "direct"},
+		{[]string{"*"}, "direct"},
+	}
+	for _, tt := range tests {
+		cfg := newConfig()
+		cfg.HTTP.Proxy = proxy.URL
+		cfg.HTTP.NoProxy = tt.noProxy
+		if body, err := fetch(t, cfg, srv.URL); err != nil || body != tt.expected {
+			t.Errorf("no_proxy = %v: expected %q but got %q, %v", tt.noProxy, tt.expected, body, err)
+		}
+	}
+}
+
+func TestMatchHost(t *testing.T) {
+	var tests = []struct {
+		host     string
+		patterns []string
+		expected bool
+	}{
+		{"example.com", []string{"example.com"}, true},
+		{"git.example.com", []string{"example.com"}, true},
+		{"git.example.com", []string{".example.com"}, true},
+		{"badexample.com", []string{"example.com"}, false},
+		{"example.com", []string{"git.example.com"}, false},
+		{"example.com", []string{"*"}, true},
+		{"example.com", []string{}, false},
+	}
+	for _, tt := range tests {
+		if actual := matchHost(tt.host, tt.patterns); actual != tt.expected {
+			t.Errorf("matchHost(%q, %v): expected %v but got %v", tt.host, tt.patterns, tt.expected, actual)
+		}
+	}
+}
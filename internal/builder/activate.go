@@ -0,0 +1,162 @@
+package builder
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// newBuildDir returns a new path under (vim dir)/pack/.volt-builds.
+func newBuildDir() (string, error) {
+	dir := pathutil.VimVoltBuildsDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", errors.Wrap(err, "failed to create "+dir)
+	}
+	for i := 0; ; i++ {
+		path := filepath.Join(dir, strconv.FormatInt(time.Now().UnixNano(), 10))
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			return path, nil
+		} else if i >= 100 {
+			return "", errors.New("could not decide a new build directory in " + dir)
+		}
+	}
+}
+
+// activateBuild makes build the current build, and keeps the current build
+// as the previous build.
+//
+// ~/.vim/pack/volt and (vim dir)/pack/.volt-prev are symlinks (junctions on
+// Windows) to directories in (vim dir)/pack/.volt-builds. They are switched
+// by renaming a new symlink over the old one, so Vim sees either the old
+// build or the new build, and never sees a half-built or missing directory.
+// On Windows, a junction cannot be renamed over another one, so it is removed
+// just before the rename.
+//
+// ~/.vim/pack/volt built by older versions is a directory. It is moved into
+// (vim dir)/pack/.volt-builds before creating the symlink at the first time,
+// so it does not exist for a moment only at that time.
+func activateBuild(log *logger.Logger, build string) error {
+	vimVoltDir := pathutil.VimVoltDir()
+	prevLink := pathutil.VimVoltPrevDir()
+
+	current, err := readBuildLink(vimVoltDir)
+	if err != nil {
+		return err
+	}
+	if current == "" && pathutil.Exists(vimVoltDir) {
+		// Move the directory built by older versions
+		if current, err = newBuildDir(); err != nil {
+			return err
+		}
+		if err := os.Rename(vimVoltDir, current); err != nil {
+			return errors.Wrap(err, "failed to move "+vimVoltDir)
+		}
+	}
+	if prev, err := readBuildLink(prevLink); err != nil {
+		return err
+	} else if prev == "" && pathutil.Exists(prevLink) {
+		// Remove the previous build directory created by older versions
+		if err := os.RemoveAll(prevLink); err != nil {
+			return errors.Wrap(err, "failed to remove "+prevLink)
+		}
+	}
+
+	if err := replaceLink(vimVoltDir, build); err != nil {
+		if current != "" {
+			// Restore the directory moved above
+			if _, e := os.Lstat(vimVoltDir); os.IsNotExist(e) {
+				if e := replaceLink(vimVoltDir, current); e != nil {
+					return errors.Wrapf(err, "failed to restore %s (%s)", vimVoltDir, e.Error())
+				}
+			}
+		}
+		return err
+	}
+	if current != "" && current != build {
+		if err := replaceLink(prevLink, current); err != nil {
+			return err
+		}
+	}
+	removeOldBuilds(log, build, current)
+	return nil
+}
+
+// readBuildLink returns the directory which link refers to.
+// If link does not exist or is not a symlink, it returns empty string.
+func readBuildLink(link string) (string, error) {
+	fi, err := os.Lstat(link)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	if fi.Mode()&os.ModeSymlink == 0 {
+		return "", nil
+	}
+	target, err := os.Readlink(link)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read "+link)
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(link), target)
+	}
+	return filepath.Clean(target), nil
+}
+
+// replaceLink creates a new symlink to target, and renames it to link.
+func replaceLink(link, target string) error {
+	tmp := filepath.Join(filepath.Dir(link), ".volt-link")
+	os.Remove(tmp)
+	if err := linkDir(target, tmp); err != nil {
+		return errors.Wrap(err, "failed to create a link to "+target)
+	}
+	if runtime.GOOS == "windows" {
+		if err := os.Remove(link); err != nil && !os.IsNotExist(err) {
+			os.Remove(tmp)
+			return errors.Wrap(err, "failed to remove "+link)
+		}
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		os.Remove(tmp)
+		return errors.Wrap(err, "failed to rename "+tmp+" to "+link)
+	}
+	return nil
+}
+
+func linkDir(target, link string) error {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/c", "mklink", "/J", link, target).Run()
+	}
+	// Relative path is used so that (vim dir) can be moved
+	if rel, err := filepath.Rel(filepath.Dir(link), target); err == nil {
+		target = rel
+	}
+	return os.Symlink(target, link)
+}
+
+// removeOldBuilds removes the directories in (vim dir)/pack/.volt-builds
+// except the current build and the previous build.
+func removeOldBuilds(log *logger.Logger, current, prev string) {
+	dir := pathutil.VimVoltBuildsDir()
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for i := range entries {
+		path := filepath.Join(dir, entries[i].Name())
+		if path == current || path == prev {
+			continue
+		}
+		log.Debug("Removing old build " + path + " ...")
+		os.RemoveAll(path)
+	}
+}
@@ -0,0 +1,443 @@
+package builder
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/fileutil"
+	"github.com/vim-volt/volt/gitutil"
+	"github.com/vim-volt/volt/internal/buildinfo"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/plugconf"
+)
+
+// BaseBuilder is a base struct which all builders must implement
+type BaseBuilder struct {
+	config   *config.Config
+	lockJSON *lockjson.LockJSON
+	git      gitutil.Backend
+	log      *logger.Logger
+}
+
+// reposFullPath returns the directory of repos. System repositories are
+// looked up in "repos_path" of "[system]" section in config.toml.
+func (builder *BaseBuilder) reposFullPath(repos *lockjson.Repos) (string, error) {
+	if repos.Type != lockjson.ReposSystemType {
+		return repos.Path.FullPath(), nil
+	}
+	if dir := repos.Path.SystemFullPath(builder.config.System.ReposPath); dir != "" {
+		return dir, nil
+	}
+	return "", errors.Errorf("system repository '%s' is not found in system.repos_path", repos.Path)
+}
+
+// readLockJSON returns lockJSON given to BuildLockJSON(), or reads
+// $VOLTPATH/lock.json if it was not given.
+func (builder *BaseBuilder) readLockJSON() (*lockjson.LockJSON, error) {
+	if builder.lockJSON != nil {
+		return builder.lockJSON, nil
+	}
+	return lockjson.Read()
+}
+
+// perProfile returns true if build.per_profile is enabled.
+func (builder *BaseBuilder) perProfile() bool {
+	return builder.config != nil && *builder.config.Build.PerProfile
+}
+
+// installRCFiles installs vimrc and gvimrc according to changes.
+// If some of them cannot be installed, no files are changed.
+func (*BaseBuilder) installRCFiles(changes []RCFileChange) error {
+	for i := range changes {
+		if changes[i].Action == PlanRefuse {
+			return changes[i].Err
+		}
+	}
+	for i := range changes {
+		dst := changes[i].Path
+
+		// Remove destination (~/.vim/vimrc or ~/.vim/gvimrc)
+		os.Remove(dst)
+		if pathutil.Exists(dst) {
+			return errors.New("failed to remove " + dst)
+		}
+		if changes[i].Action == PlanRemove {
+			continue
+		}
+
+		os.MkdirAll(filepath.Dir(dst), 0755)
+		if err := fileutil.WriteFileAtomic(dst, changes[i].content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookUpProfileRCFiles returns existing rc files named srcRCFileName of
+// profileNames. The order of the result is the same as profileNames.
+func (*BaseBuilder) lookUpProfileRCFiles(profileNames []string, srcRCFileName string) []string {
+	srcList := make([]string, 0, len(profileNames))
+	for _, name := range profileNames {
+		src := filepath.Join(pathutil.RCDir(name), srcRCFileName)
+		if pathutil.Exists(src) {
+			srcList = append(srcList, src)
+		}
+	}
+	return srcList
+}
+
+// lookUpProfileRCFile returns the nearest existing rc file named
+// srcRCFileName of profileNames (the last one of profileNames is nearest).
+// If no rc file exists, returns an empty string.
+func (builder *BaseBuilder) lookUpProfileRCFile(profileNames []string, srcRCFileName string) string {
+	srcList := builder.lookUpProfileRCFiles(profileNames, srcRCFileName)
+	if len(srcList) == 0 {
+		return ""
+	}
+	return srcList[len(srcList)-1]
+}
+
+// getProfileNames returns the names of profileName and the profiles it
+// extends, in inheritance order.
+func (*BaseBuilder) getProfileNames(lockJSON *lockjson.LockJSON, profileName string) ([]string, error) {
+	profile, err := lockJSON.Profiles.FindByName(profileName)
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := lockJSON.ResolveProfiles(profile)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(profiles))
+	for _, p := range profiles {
+		names = append(names, p.Name)
+	}
+	return names, nil
+}
+
+// getReposList returns repositories to be installed into
+// (vim dir)/pack/volt/opt.
+// If build.per_profile is enabled, returns repositories of all profiles.
+// Otherwise returns repositories of the current profile.
+func (builder *BaseBuilder) getReposList(lockJSON *lockjson.LockJSON) (lockjson.ReposList, error) {
+	if !builder.perProfile() {
+		return lockJSON.GetCurrentReposList()
+	}
+	reposList := make(lockjson.ReposList, 0, len(lockJSON.Repos))
+	for i := range lockJSON.Profiles {
+		profReposList, err := lockJSON.GetReposListByProfile(&lockJSON.Profiles[i])
+		if err != nil {
+			return nil, err
+		}
+		for j := range profReposList {
+			if !reposList.Contains(profReposList[j].Path) {
+				reposList = append(reposList, profReposList[j])
+			}
+		}
+	}
+	return reposList, nil
+}
+
+// generateBundledPlugconfs generates bundled plugconf files, and returns
+// them and (vim dir)/pack/volt-{profile} directories to be removed.
+// If build.per_profile is enabled, generates bundled plugconf of each profile
+// for (vim dir)/pack/volt-{profile}/bundled_plugconf.vim, and a selector of
+// them for pathutil.BundledPlugConf() instead.
+// All repositories are shared in (vim dir)/pack/volt/opt because ":packadd"
+// searches all "pack/*/opt" directories.
+func (builder *BaseBuilder) generateBundledPlugconfs(lockJSON *lockjson.LockJSON, reposList lockjson.ReposList, profileNames []string) ([]bundledPlugconf, []string, error) {
+	warned := make(map[string]bool)
+	if !builder.perProfile() {
+		removeDirs, err := builder.profileDirsToRemove(nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		content, err := builder.generateBundledPlugconf(reposList, profileNames, warned)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []bundledPlugconf{{pathutil.BundledPlugConf(), content}}, removeDirs, nil
+	}
+
+	contents := make([]bundledPlugconf, 0, len(lockJSON.Profiles)+1)
+	profilePlugconfs := make(map[string]string, len(lockJSON.Profiles))
+	for i := range lockJSON.Profiles {
+		profile := &lockJSON.Profiles[i]
+		if !builder.isValidProfileDirName(profile.Name) {
+			return nil, nil, errors.Errorf("profile name %q cannot be used as a directory name", profile.Name)
+		}
+		profReposList, err := lockJSON.GetReposListByProfile(profile)
+		if err != nil {
+			return nil, nil, err
+		}
+		names, err := builder.getProfileNames(lockJSON, profile.Name)
+		if err != nil {
+			return nil, nil, err
+		}
+		content, err := builder.generateBundledPlugconf(profReposList, names, warned)
+		if err != nil {
+			return nil, nil, err
+		}
+		path := pathutil.ProfileBundledPlugConf(profile.Name)
+		contents = append(contents, bundledPlugconf{path, content})
+		profilePlugconfs[profile.Name] = path
+	}
+	removeDirs, err := builder.profileDirsToRemove(profilePlugconfs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := plugconf.GenerateProfileSelector(lockJSON.CurrentProfileName, profilePlugconfs)
+	if err != nil {
+		return nil, nil, err
+	}
+	contents = append(contents, bundledPlugconf{pathutil.BundledPlugConf(), content})
+	return contents, removeDirs, nil
+}
+
+// generateBundledPlugconf generates bundled plugconf of reposList.
+// The same warnings are not shown twice by warned map.
+func (builder *BaseBuilder) generateBundledPlugconf(reposList lockjson.ReposList, profileNames []string, warned map[string]bool) ([]byte, error) {
+	vimrc := builder.lookUpProfileRCFile(profileNames, pathutil.ProfileVimrc)
+	gvimrc := builder.lookUpProfileRCFile(profileNames, pathutil.ProfileGvimrc)
+	plugconfs, parseErr := plugconf.ParseMultiPlugconf(reposList)
+	if parseErr.HasErrs() {
+		// Vim script parse errors / other errors
+		return nil, parseErr.Errors()
+	}
+	if parseErr.HasWarns() {
+		// Vim script parse warnings
+		merr := parseErr.Warns()
+		for _, err := range merr.Errors {
+			if !warned[err.Error()] {
+				builder.log.Warn(err)
+				warned[err.Error()] = true
+			}
+		}
+	}
+	return plugconfs.GenerateBundlePlugconf(vimrc, gvimrc)
+}
+
+// installBundledPlugconf writes bundled plugconf files of plan, and removes
+// (vim dir)/pack/volt-{profile} directories which are no longer used.
+func (*BaseBuilder) installBundledPlugconf(plan *Plan) error {
+	for _, dir := range plan.removeProfileDirs {
+		if err := os.RemoveAll(dir); err != nil {
+			return errors.Wrap(err, "failed to remove "+dir)
+		}
+	}
+	for _, c := range plan.bundledPlugconfs {
+		os.MkdirAll(filepath.Dir(c.path), 0755)
+		if err := fileutil.WriteFileAtomic(c.path, c.content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// profileDirsToRemove returns (vim dir)/pack/volt-{profile} directories whose
+// profile is not in keep.
+func (*BaseBuilder) profileDirsToRemove(keep map[string]string) ([]string, error) {
+	dirs, err := pathutil.VimVoltProfileDirs()
+	if err != nil {
+		return nil, err
+	}
+	removeDirs := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		name := strings.TrimPrefix(filepath.Base(dir), "volt-")
+		if _, exists := keep[name]; !exists {
+			removeDirs = append(removeDirs, dir)
+		}
+	}
+	return removeDirs, nil
+}
+
+// isValidProfileDirName returns true if name can be used as a part of
+// directory name (vim dir)/pack/volt-{name}.
+func (*BaseBuilder) isValidProfileDirName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, `/\:*?"<>|`)
+}
+
+const magicComment = "\" NOTE: this file was generated by volt. please modify original file.\n"
+const magicCommentNext = "\" Original file: %s\n\n"
+
+// HasMagicComment returns true if the magic comment exists
+func (*BaseBuilder) HasMagicComment(dst string) bool {
+	r, err := os.Open(dst)
+	if err != nil {
+		return false
+	}
+	defer r.Close()
+
+	magic := []byte(magicComment)
+	read := make([]byte, len(magic))
+	n, err := r.Read(read)
+	if err != nil || n < len(magicComment) {
+		return false
+	}
+
+	for i := range magic {
+		if magic[i] != read[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// writeWithMagicComment concatenates srcList files into w with magic
+// comment.
+func (builder *BaseBuilder) writeWithMagicComment(w io.Writer, srcList []string) error {
+	if _, err := w.Write([]byte(magicComment)); err != nil {
+		return err
+	}
+	for i, src := range srcList {
+		if i > 0 {
+			if _, err := w.Write([]byte("\n")); err != nil {
+				return err
+			}
+		}
+		if err := builder.appendFile(w, src); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (*BaseBuilder) appendFile(w io.Writer, src string) (err error) {
+	r, err := os.Open(src)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if e := r.Close(); e != nil {
+			err = e
+		}
+	}()
+
+	_, err = w.Write([]byte(fmt.Sprintf(magicCommentNext, src)))
+	if err != nil {
+		return
+	}
+	_, err = io.Copy(w, r)
+	return
+}
+
+type actionReposResult struct {
+	err   error
+	repos *lockjson.Repos
+	files buildinfo.FileMap
+	// helptags is true if ":helptags" must be run for the repository
+	helptags bool
+}
+
+// helptags runs ":helptags" for doc directories of results whose helptags
+// is true in a single Vim process, and sets the error of each repository to
+// its result.
+// This does nothing if build.helptags config is false.
+func (builder *BaseBuilder) helptags(results []actionReposResult, vimExePath string) {
+	if !*builder.config.Build.Helptags {
+		return
+	}
+
+	// Collect directories of repositories which have doc directory
+	dirs := make([]string, 0, len(results))
+	resultMap := make(map[string]*actionReposResult, len(results))
+	for i := range results {
+		if !results[i].helptags || results[i].err != nil || results[i].repos == nil {
+			continue
+		}
+		// Do nothing if <reposPath>/doc directory doesn't exist
+		dir := results[i].repos.Path.EncodeToPlugDirName()
+		if !pathutil.Exists(filepath.Join(dir, "doc")) {
+			continue
+		}
+		dirs = append(dirs, dir)
+		resultMap[dir] = &results[i]
+	}
+	if len(dirs) == 0 {
+		return
+	}
+
+	failed, err := builder.runHelptags(dirs, vimExePath)
+	if err != nil {
+		for _, result := range resultMap {
+			result.err = errors.Wrap(err, "failed to make tags file")
+		}
+		return
+	}
+	for dir, msg := range failed {
+		if result, exists := resultMap[dir]; exists {
+			result.err = errors.New("failed to make tags file: " + msg)
+		}
+	}
+}
+
+// runHelptags executes ":helptags doc" in each dirs in a single Vim process,
+// and returns error messages of failed directories.
+func (builder *BaseBuilder) runHelptags(dirs []string, vimExePath string) (map[string]string, error) {
+	os.MkdirAll(pathutil.TempDir(), 0755)
+	tempDir, err := ioutil.TempDir(pathutil.TempDir(), "helptags-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Generate a script which runs ":helptags" for each directory, and
+	// writes failed directories and error messages to result file
+	resultFile := filepath.Join(tempDir, "result")
+	quoted := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		quoted = append(quoted, vimStringLiteral(dir))
+	}
+	script := filepath.Join(tempDir, "helptags.vim")
+	content := `let s:failed = []
+for s:dir in [` + strings.Join(quoted, ", ") + `]
+  try
+    execute 'cd' fnameescape(s:dir)
+    helptags doc
+  catch
+    call add(s:failed, s:dir . "\t" . v:exception)
+  endtry
+endfor
+call writefile(s:failed, ` + vimStringLiteral(resultFile) + `)
+qall!
+`
+	if err := ioutil.WriteFile(script, []byte(content), 0644); err != nil {
+		return nil, err
+	}
+
+	vimArgs := []string{"-u", "NONE", "-i", "NONE", "-N", "-es", "-S", script}
+	builder.log.Debugf("Executing '%s %s' ...", vimExePath, strings.Join(vimArgs, " "))
+	err = exec.Command(vimExePath, vimArgs...).Run()
+	result, readErr := ioutil.ReadFile(resultFile)
+	if readErr != nil {
+		// Vim exited before writing result file
+		if err == nil {
+			err = readErr
+		}
+		return nil, err
+	}
+
+	failed := make(map[string]string)
+	for _, line := range strings.Split(string(result), "\n") {
+		if i := strings.Index(line, "\t"); i >= 0 {
+			failed[line[:i]] = line[i+1:]
+		}
+	}
+	return failed, nil
+}
+
+// vimStringLiteral returns single-quoted Vim script string literal of s.
+func vimStringLiteral(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
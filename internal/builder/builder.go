@@ -0,0 +1,70 @@
+package builder
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/gitutil"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// Builder creates/updates ~/.vim/pack/volt directory
+type Builder interface {
+	// planRepos appends changes of repositories to plan
+	planRepos(plan *Plan) error
+	// Build changes files according to plan
+	Build(plan *Plan) error
+}
+
+const currentBuildInfoVersion = 2
+
+// Build creates/updates ~/.vim/pack/volt directory.
+// Progress is logged by the package functions of logger.
+func Build(full bool) error {
+	plan, err := MakePlan(full, logger.Default())
+	if err != nil {
+		return err
+	}
+	return plan.Apply()
+}
+
+// BuildLockJSON creates (vimDir)/pack/volt directory and (vimDir)/vimrc from
+// lockJSON instead of ~/.vim and $VOLTPATH/lock.json. This always does full
+// build, and ignores build.per_profile config.
+func BuildLockJSON(lockJSON *lockjson.LockJSON, vimDir string) error {
+	pathutil.SetVimDir(vimDir)
+	defer pathutil.SetVimDir("")
+
+	// Read config.toml
+	cfg, err := config.Read()
+	if err != nil {
+		return errors.Wrap(err, "could not read config.toml")
+	}
+	falseValue := false
+	cfg.Build.PerProfile = &falseValue
+	plan, err := makePlan(true, cfg, lockJSON, logger.Default())
+	if err != nil {
+		return err
+	}
+	return plan.Apply()
+}
+
+func getBuilder(cfg *config.Config, lockJSON *lockjson.LockJSON, log *logger.Logger) (Builder, error) {
+	backend, err := gitutil.NewBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+	base := BaseBuilder{config: cfg, lockJSON: lockJSON, git: backend, log: log}
+	switch cfg.Build.Strategy {
+	case config.SymlinkBuilder:
+		return &symlinkBuilder{base}, nil
+	case config.CopyBuilder:
+		return &copyBuilder{BaseBuilder: base}, nil
+	case config.HardlinkStoreBuilder:
+		return &copyBuilder{BaseBuilder: base, useStore: true}, nil
+	default:
+		return nil, errors.New("unknown builder type: " + cfg.Build.Strategy)
+	}
+}
@@ -0,0 +1,648 @@
+package builder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/vim-volt/volt/fileutil"
+	"github.com/vim-volt/volt/gitutil"
+	"github.com/vim-volt/volt/internal/buildinfo"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+type copyBuilder struct {
+	BaseBuilder
+	// useStore is true if files of git repositories are hard-linked from
+	// $VOLTPATH/store ("hardlink-store" strategy)
+	useStore bool
+}
+
+func (builder *copyBuilder) planRepos(plan *Plan) error {
+	for i := range plan.reposList {
+		repos := &plan.reposList[i]
+		change := plan.newReposChange(repos)
+		var changed bool
+		switch repos.Type {
+		case lockjson.ReposGitType:
+			changed = builder.planReposGit(&change)
+		case lockjson.ReposStaticType, lockjson.ReposSystemType:
+			changed = builder.planReposStatic(&change)
+		default:
+			change.Err = errors.New("invalid repository type: " + string(repos.Type))
+		}
+		if changed || change.Err != nil {
+			plan.Repos = append(plan.Repos, change)
+		}
+	}
+	// Remove vim repos not found in lock.json current repos list
+	return builder.planRemoveRepos(plan)
+}
+
+func (builder *copyBuilder) Build(plan *Plan) error {
+	// Exit if vim executable was not found in PATH
+	vimExePath, err := pathutil.VimExecutable()
+	if err != nil {
+		return err
+	}
+
+	builder.log.Info("Installing vimrc and gvimrc ...")
+
+	err = builder.installRCFiles(plan.RCFiles)
+	if err != nil {
+		return err
+	}
+
+	// Mkdir opt dir
+	optDir := pathutil.VimVoltOptDir()
+	os.MkdirAll(optDir, 0755)
+	if !pathutil.Exists(optDir) {
+		return errors.New("could not create " + optDir)
+	}
+
+	// Copy volt repos files to optDir
+	copyDone, copyCount := builder.copyReposList(plan.Repos)
+
+	// Remove vim repos not found in lock.json current repos list
+	removeDone, removeCount := builder.removeReposList(plan.Repos)
+
+	// Wait copy
+	buildInfo := plan.buildInfo
+	var copyModified bool
+	copyErr := builder.waitCopyRepos(copyDone, copyCount, vimExePath, func(result *actionReposResult) error {
+		builder.log.Info("Installing " + string(result.repos.Type) + " repository " + result.repos.Path.String() + " ... Done.")
+		// Construct buildInfo from the result
+		builder.constructBuildInfo(buildInfo, result)
+		copyModified = true
+		return nil
+	})
+
+	// Wait remove
+	var removeModified bool
+	removeErr := builder.waitRemoveRepos(removeDone, removeCount, func(result *actionReposResult) {
+		// Remove the repository from buildInfo
+		buildInfo.Repos.RemoveByReposPath(result.repos.Path)
+		removeModified = true
+	})
+
+	// Handle copy & remove errors
+	if copyErr != nil || removeErr != nil {
+		return multierror.Append(copyErr, removeErr).ErrorOrNil()
+	}
+
+	// Write bundled plugconf file
+	err = builder.installBundledPlugconf(plan)
+	if err != nil {
+		return err
+	}
+
+	// Write to build-info.json if buildInfo was modified
+	if copyModified || removeModified {
+		err = buildInfo.Write()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (builder *copyBuilder) copyReposList(changes []ReposChange) (chan actionReposResult, int) {
+	copyDone := make(chan actionReposResult, len(changes))
+	copyCount := 0
+	for i := range changes {
+		change := &changes[i]
+		if change.Action != PlanAdd && change.Action != PlanUpdate {
+			continue
+		}
+		copyCount++
+		if change.Err != nil {
+			copyDone <- actionReposResult{
+				err:   change.Err,
+				repos: change.repos,
+			}
+		} else if change.repos.Type == lockjson.ReposGitType {
+			go builder.updateGitRepos(change.repos, change.buildRepos, change.copyFromGitObjects, copyDone)
+		} else {
+			go builder.updateStaticRepos(change.repos, change.buildRepos, change.src, change.files, change.modes, copyDone)
+		}
+	}
+	return copyDone, copyCount
+}
+
+// planReposGit returns true if the git repository of change must be copied.
+func (builder *copyBuilder) planReposGit(change *ReposChange) bool {
+	repos := change.repos
+	src := repos.Path.FullPath()
+
+	// Open ~/volt/repos/{repos}
+	isBare, err := builder.git.IsBare(src)
+	if err != nil {
+		change.Err = errors.Wrap(err, "failed to copy "+string(repos.Type)+" repos: failed to open repository")
+		return true
+	}
+
+	// Show warning when HEAD and locked revision are different
+	head, err := gitutil.GetHEAD(builder.git, src)
+	if err != nil {
+		change.Err = errors.Errorf("failed to copy %s repos: failed to get HEAD revision of %q: %s", repos.Type, src, err.Error())
+		return true
+	}
+	if head != repos.Version {
+		log := builder.log.With("repos", repos.Path)
+		log.Warnf("%s: HEAD and locked revision are different", repos.Path)
+		log.Warn("  HEAD: " + head)
+		log.Warn("  locked revision: " + repos.Version)
+		log.Warn("  Please run 'volt get -l' to update locked revision.")
+	}
+
+	isClean := false
+	if !isBare {
+		if clean, err := builder.git.IsClean(src); err == nil {
+			isClean = clean
+		}
+	}
+
+	reason := builder.hasChangedGitRepos(repos, change.buildRepos, !isClean)
+	if reason == "" {
+		return false
+	}
+	if change.Action == PlanUpdate {
+		change.Reason = reason
+	}
+	// Copy files from .git/objects/... when:
+	// * bare repository
+	// * or worktree is clean
+	change.copyFromGitObjects = isBare || isClean
+	return true
+}
+
+// planReposStatic returns true if the static or system repository of change
+// must be copied.
+func (builder *copyBuilder) planReposStatic(change *ReposChange) bool {
+	src, err := builder.reposFullPath(change.repos)
+	if err != nil {
+		change.Err = errors.Wrap(err, "failed to copy static directory")
+		return true
+	}
+	if si, err := os.Stat(src); err != nil || !si.IsDir() {
+		change.Err = errors.New("failed to copy static directory: source is not a directory")
+		return true
+	}
+	// System repository may be a git repository managed by administrator
+	files, modes, err := builder.hashFiles(src, change.repos.Type == lockjson.ReposSystemType)
+	if err != nil {
+		change.Err = errors.Wrap(err, "failed to copy static directory")
+		return true
+	}
+	if !builder.hasChangedStaticRepos(change.buildRepos, files) {
+		return false
+	}
+	if change.Action == PlanUpdate && change.buildRepos != nil {
+		change.Reason = "files changed"
+	}
+	change.src = src
+	change.files = files
+	change.modes = modes
+	return true
+}
+
+// Remove vim repos not found in lock.json current repos list
+func (builder *copyBuilder) removeReposList(changes []ReposChange) (chan actionReposResult, int) {
+	removeList := make([]pathutil.ReposPath, 0, len(changes))
+	for i := range changes {
+		if changes[i].Action == PlanRemove {
+			removeList = append(removeList, changes[i].Path)
+		}
+	}
+	removeDone := make(chan actionReposResult, len(removeList))
+	for i := range removeList {
+		go func(reposPath pathutil.ReposPath) {
+			err := os.RemoveAll(reposPath.EncodeToPlugDirName())
+			builder.log.With("repos", reposPath).Info("Removing " + reposPath + " ... Done.")
+			removeDone <- actionReposResult{
+				err:   err,
+				repos: &lockjson.Repos{Path: reposPath},
+			}
+		}(removeList[i])
+	}
+	return removeDone, len(removeList)
+}
+
+func (builder *copyBuilder) waitCopyRepos(copyDone chan actionReposResult, copyCount int, vimExePath string, callback func(*actionReposResult) error) *multierror.Error {
+	results := make([]actionReposResult, 0, copyCount)
+	for i := 0; i < copyCount; i++ {
+		results = append(results, <-copyDone)
+	}
+
+	// Run ":helptags" for copied repositories at once
+	builder.helptags(results, vimExePath)
+
+	var merr *multierror.Error
+	for i := range results {
+		result := &results[i]
+		if result.err != nil {
+			merr = multierror.Append(
+				merr,
+				errors.Wrap(result.err,
+					"failed to copy repository '"+result.repos.Path.String()+
+						"'"))
+		} else {
+			err := callback(result)
+			if err != nil {
+				merr = multierror.Append(merr, err)
+			}
+		}
+	}
+	return merr
+}
+
+func (builder *copyBuilder) constructBuildInfo(buildInfo *buildinfo.BuildInfo, result *actionReposResult) {
+	if result.repos.Type == lockjson.ReposGitType {
+		r := buildInfo.Repos.FindByReposPath(result.repos.Path)
+		if r != nil {
+			r.Version = result.repos.Version
+			r.Files = result.files
+		} else {
+			buildInfo.Repos = append(
+				buildInfo.Repos,
+				buildinfo.Repos{
+					Type:    lockjson.ReposGitType,
+					Path:    result.repos.Path,
+					Version: result.repos.Version,
+					Files:   result.files,
+				},
+			)
+		}
+	} else if result.repos.Type == lockjson.ReposStaticType || result.repos.Type == lockjson.ReposSystemType {
+		r := buildInfo.Repos.FindByReposPath(result.repos.Path)
+		if r != nil {
+			r.Version = time.Now().Format(time.RFC3339)
+			r.Files = result.files
+		} else {
+			buildInfo.Repos = append(
+				buildInfo.Repos,
+				buildinfo.Repos{
+					Type:    result.repos.Type,
+					Path:    result.repos.Path,
+					Version: time.Now().Format(time.RFC3339),
+					Files:   result.files,
+				},
+			)
+		}
+	} else {
+		builder.log.Error("Unknown repos type (" + string(result.repos.Type) + ")")
+	}
+}
+
+func (*copyBuilder) waitRemoveRepos(removeDone chan actionReposResult, removeCount int, callback func(result *actionReposResult)) *multierror.Error {
+	var merr *multierror.Error
+	for i := 0; i < removeCount; i++ {
+		result := <-removeDone
+		if result.err != nil {
+			target := "files"
+			if result.repos != nil {
+				target = result.repos.Path.String()
+			}
+			merr = multierror.Append(
+				merr, errors.Wrap(result.err, "Failed to remove "+target))
+		} else {
+			callback(&result)
+		}
+	}
+	return merr
+}
+
+// hasChangedGitRepos returns the reason why the git repository must be
+// copied, or an empty string if it is not changed.
+func (*copyBuilder) hasChangedGitRepos(repos *lockjson.Repos, buildRepos *buildinfo.Repos, isDirty bool) string {
+	if buildRepos == nil { // Full build
+		return "full build"
+	}
+	if repos.Version != buildRepos.Version {
+		return "version changed"
+	}
+	if buildRepos.DirtyWorktree || isDirty {
+		return "worktree is dirty"
+	}
+	return ""
+}
+
+// hasChangedStaticRepos returns true if files of static repository differ
+// from files installed at previous build.
+func (*copyBuilder) hasChangedStaticRepos(buildRepos *buildinfo.Repos, files buildinfo.FileMap) bool {
+	if buildRepos == nil { // Full build
+		return true
+	}
+	if buildRepos.Files == nil || len(buildRepos.Files) != len(files) {
+		return true
+	}
+	for name, hash := range files {
+		if buildRepos.Files[name] != hash {
+			return true
+		}
+	}
+	return false
+}
+
+// Update ~/.vim/volt/opt/{repos} by files of ~/volt/repos/{repos}
+func (builder *copyBuilder) updateGitRepos(repos *lockjson.Repos, buildRepos *buildinfo.Repos, copyFromGitObjects bool, done chan actionReposResult) {
+	src := repos.Path.FullPath()
+	dst := repos.Path.EncodeToPlugDirName()
+
+	oldFiles, err := builder.previousFiles(dst, buildRepos)
+	if err != nil {
+		done <- actionReposResult{
+			err:   err,
+			repos: repos,
+		}
+		return
+	}
+
+	if copyFromGitObjects {
+		builder.log.With("repos", repos.Path).Debug("Copy from git objects: " + repos.Path)
+		builder.updateBareGitRepos(src, dst, repos, oldFiles, done)
+	} else {
+		builder.log.With("repos", repos.Path).Debug("Copy from filesystem: " + repos.Path)
+		builder.updateNonBareGitRepos(src, dst, repos, oldFiles, done)
+	}
+}
+
+// previousFiles returns files of buildRepos installed at previous build.
+// If they are unknown, removes ~/.vim/volt/opt/{repos} to copy all files
+// again, and returns empty FileMap.
+func (*copyBuilder) previousFiles(dst string, buildRepos *buildinfo.Repos) (buildinfo.FileMap, error) {
+	if buildRepos != nil && buildRepos.Files != nil && pathutil.Exists(dst) {
+		return buildRepos.Files, nil
+	}
+	if err := os.RemoveAll(dst); err != nil {
+		return nil, errors.Wrap(err, "failed to remove repository")
+	}
+	return buildinfo.FileMap{}, nil
+}
+
+func (builder *copyBuilder) updateBareGitRepos(src, dst string, repos *lockjson.Repos, oldFiles buildinfo.FileMap, done chan actionReposResult) {
+	// Collect files of locked commit
+	files := make(buildinfo.FileMap, 512)
+	modes := make(map[string]os.FileMode, 512)
+	objects := make(map[string]*gitutil.TreeFile, 512)
+	err := builder.git.ReadTree(src, repos.Version, func(file *gitutil.TreeFile) error {
+		files[file.Name] = file.Hash // blob hash
+		modes[file.Name] = file.Mode
+		objects[file.Name] = file
+		return nil
+	})
+	if err != nil {
+		done <- actionReposResult{
+			err:   err,
+			repos: repos,
+		}
+		return
+	}
+
+	// Copy changed files
+	docChanged, err := builder.syncFiles(dst, oldFiles, files, modes, func(name, to string) error {
+		if builder.useStore {
+			return linkFromStore(builder.log, objects[name], to, modes[name])
+		}
+		r, err := objects[name].Reader()
+		if err != nil {
+			return errors.Wrap(err, "failed to get file contents")
+		}
+		contents, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return errors.Wrap(err, "failed to get file contents")
+		}
+		return ioutil.WriteFile(to, contents, modes[name])
+	})
+	if err != nil {
+		done <- actionReposResult{
+			err:   err,
+			repos: repos,
+		}
+		return
+	}
+
+	// ":helptags" is run after all repositories were copied
+	if docChanged {
+		err = builder.removeTagsLink(dst)
+		if err != nil {
+			done <- actionReposResult{
+				err:   err,
+				repos: repos,
+			}
+			return
+		}
+	}
+
+	done <- actionReposResult{
+		err:      nil,
+		repos:    repos,
+		files:    files,
+		helptags: docChanged,
+	}
+}
+
+// BuildModeInvalidType is invalid types of files which copy builder cannot handle.
+var BuildModeInvalidType = os.ModeSymlink | os.ModeNamedPipe | os.ModeSocket | os.ModeDevice
+
+func (builder *copyBuilder) updateNonBareGitRepos(src, dst string, repos *lockjson.Repos, oldFiles buildinfo.FileMap, done chan actionReposResult) {
+	// Skip ".git" and ".gitignore"
+	files, modes, err := builder.hashFiles(src, true)
+	if err != nil {
+		done <- actionReposResult{
+			err:   err,
+			repos: repos,
+		}
+		return
+	}
+
+	// Copy changed files
+	buf := make([]byte, 32*1024)
+	docChanged, err := builder.syncFiles(dst, oldFiles, files, modes, func(name, to string) error {
+		return fileutil.TryLinkFile(filepath.Join(src, filepath.FromSlash(name)), to, buf, modes[name])
+	})
+	if err != nil {
+		done <- actionReposResult{
+			err:   err,
+			repos: repos,
+		}
+		return
+	}
+
+	// ":helptags" is run after all repositories were copied
+	if docChanged {
+		err = builder.removeTagsLink(dst)
+		if err != nil {
+			done <- actionReposResult{
+				err:   err,
+				repos: repos,
+			}
+			return
+		}
+	}
+
+	done <- actionReposResult{
+		err:      nil,
+		repos:    repos,
+		files:    files,
+		helptags: docChanged,
+	}
+}
+
+// hashFiles returns git blob hashes and modes of files under dir.
+// Keys are slash-separated paths relative to dir, same as git tree.
+// If skipGit is true, ".git" and ".gitignore" directly under dir are skipped.
+func (*copyBuilder) hashFiles(dir string, skipGit bool) (buildinfo.FileMap, map[string]os.FileMode, error) {
+	files := make(buildinfo.FileMap, 512)
+	modes := make(map[string]os.FileMode, 512)
+	// dir may be a symbolic link (e.g. imported by "volt add -link")
+	if realDir, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = realDir
+	}
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if skipGit && (name == ".git" || name == ".gitignore") {
+			if fi.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if fi.IsDir() || fi.Mode()&BuildModeInvalidType != 0 {
+			// Currenly skip the invalid files...
+			return nil
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[name] = plumbing.ComputeHash(plumbing.BlobObject, content).String()
+		modes[name] = fi.Mode()
+		return nil
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to read files")
+	}
+	return files, modes, nil
+}
+
+// syncFiles makes files under dst same as newFiles.
+// write() is called only for files whose hashes or executable bits differ
+// from oldFiles, and files which are not in newFiles are removed.
+// Returns true if any file under "doc/" was changed.
+func (*copyBuilder) syncFiles(dst string, oldFiles, newFiles buildinfo.FileMap, modes map[string]os.FileMode, write func(name, to string) error) (bool, error) {
+	var docChanged bool
+	for name, hash := range newFiles {
+		to := filepath.Join(dst, filepath.FromSlash(name))
+		if oldFiles[name] == hash {
+			fi, err := os.Lstat(to)
+			if err == nil && fi.Mode().IsRegular() && fi.Mode()&0111 == modes[name]&0111 {
+				continue
+			}
+		}
+		os.MkdirAll(filepath.Dir(to), 0755)
+		// Remove before writing because it may be a hard link to other file
+		if err := os.Remove(to); err != nil && !os.IsNotExist(err) {
+			return false, errors.Wrap(err, "failed to remove old file")
+		}
+		if err := write(name, to); err != nil {
+			return false, err
+		}
+		docChanged = docChanged || strings.HasPrefix(name, "doc/")
+	}
+	for name := range oldFiles {
+		if _, exists := newFiles[name]; exists {
+			continue
+		}
+		to := filepath.Join(dst, filepath.FromSlash(name))
+		if err := os.Remove(to); err != nil && !os.IsNotExist(err) {
+			return false, errors.Wrap(err, "failed to remove old file")
+		}
+		// Remove empty parent directories
+		for dir := filepath.Dir(to); dir != dst && strings.HasPrefix(dir, dst); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+		docChanged = docChanged || strings.HasPrefix(name, "doc/")
+	}
+	return docChanged, nil
+}
+
+// removeTagsLink removes "doc/tags" file before running ":helptags", because
+// it may be a hard link to the file of repository, store, or the previous
+// build.
+func (*copyBuilder) removeTagsLink(dst string) error {
+	tags := filepath.Join(dst, "doc", "tags")
+	if err := os.Remove(tags); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed to remove tags file")
+	}
+	// Remove "doc" directory if it has no files other than "tags"
+	os.Remove(filepath.Dir(tags))
+	return nil
+}
+
+// Update ~/.vim/volt/opt/{repos} by files of ~/volt/repos/{repos}
+func (builder *copyBuilder) updateStaticRepos(repos *lockjson.Repos, buildRepos *buildinfo.Repos, src string, files buildinfo.FileMap, modes map[string]os.FileMode, done chan actionReposResult) {
+	dst := repos.Path.EncodeToPlugDirName()
+
+	oldFiles, err := builder.previousFiles(dst, buildRepos)
+	if err != nil {
+		done <- actionReposResult{
+			err:   err,
+			repos: repos,
+		}
+		return
+	}
+
+	// Copy changed files of ~/volt/repos/{repos} to ~/.vim/volt/opt/{repos}
+	buf := make([]byte, 32*1024)
+	os.MkdirAll(dst, 0755)
+	docChanged, err := builder.syncFiles(dst, oldFiles, files, modes, func(name, to string) error {
+		return fileutil.TryLinkFile(filepath.Join(src, filepath.FromSlash(name)), to, buf, modes[name])
+	})
+	if err != nil {
+		done <- actionReposResult{
+			err:   errors.Wrap(err, "failed to copy static directory"),
+			repos: repos,
+		}
+		return
+	}
+
+	// ":helptags" is run after all repositories were copied
+	if docChanged {
+		err = builder.removeTagsLink(dst)
+		if err != nil {
+			done <- actionReposResult{
+				err:   err,
+				repos: repos,
+			}
+			return
+		}
+	}
+
+	done <- actionReposResult{
+		err:      nil,
+		repos:    repos,
+		files:    files,
+		helptags: docChanged,
+	}
+}
@@ -0,0 +1,135 @@
+package builder
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+
+	"github.com/vim-volt/volt/fileutil"
+	"github.com/vim-volt/volt/gitutil"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// storeEntry returns the path of the blob in $VOLTPATH/store.
+// Hard links share permission bits, so executable files are stored in
+// another entry ("{blob-hash}-x").
+func storeEntry(hash string, mode os.FileMode) string {
+	if mode&0111 != 0 {
+		hash += "-x"
+	}
+	return filepath.Join(pathutil.StoreDir(), hash)
+}
+
+// linkFromStore extracts the blob of file to $VOLTPATH/store if it does not
+// exist, and creates a hard link dst to it.
+// Store entries are read-only because they are shared by all builds. If an
+// entry was modified anyway (e.g. a built file was edited in place), it is
+// extracted again.
+// If a hard link cannot be created (e.g. different filesystems), copies the
+// store entry to dst.
+func linkFromStore(log *logger.Logger, file *gitutil.TreeFile, dst string, mode os.FileMode) error {
+	entry := storeEntry(file.Hash, mode)
+	if !isValidEntry(log, entry, file.Hash) {
+		if err := extractBlob(file, entry, mode); err != nil {
+			return errors.Wrap(err, "failed to extract "+file.Name+" to store")
+		}
+	}
+	if err := os.Link(entry, dst); err == nil {
+		return nil
+	}
+	buf := make([]byte, 32*1024)
+	return fileutil.CopyFile(entry, dst, buf, mode)
+}
+
+// extractBlob writes the contents of file to entry.
+// The contents are written to a temporary file and renamed, so other builds
+// never see a partially written entry.
+func extractBlob(file *gitutil.TreeFile, entry string, mode os.FileMode) error {
+	os.MkdirAll(filepath.Dir(entry), 0755)
+	tmp, err := ioutil.TempFile(filepath.Dir(entry), filepath.Base(entry)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	r, err := file.Reader()
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	_, err = io.Copy(tmp, r)
+	r.Close()
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode&^0222); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), entry)
+}
+
+// isValidEntry returns true if entry exists and its contents are the blob of
+// hash.
+func isValidEntry(log *logger.Logger, entry, hash string) bool {
+	f, err := os.Open(entry)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil || !fi.Mode().IsRegular() {
+		return false
+	}
+	h := plumbing.NewHasher(plumbing.BlobObject, fi.Size())
+	if _, err := io.Copy(h, f); err != nil {
+		return false
+	}
+	if h.Sum().String() != hash {
+		log.Warn("Store entry " + entry + " was modified. Extracting it again ...")
+		return false
+	}
+	return true
+}
+
+// gcStore removes the entries of $VOLTPATH/store which are not linked from
+// any builds (including the previous build kept for rollback), and temporary
+// files left by interrupted builds.
+func gcStore(log *logger.Logger) {
+	dir := pathutil.StoreDir()
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	var count int
+	for i := range entries {
+		if !entries[i].Mode().IsRegular() {
+			continue
+		}
+		path := filepath.Join(dir, entries[i].Name())
+		n, err := linkCount(path, entries[i])
+		if err != nil {
+			log.Debug("Could not get link count of " + path + ": " + err.Error())
+			continue
+		}
+		if n > 1 {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			log.Debug("Could not remove " + path + ": " + err.Error())
+			continue
+		}
+		count++
+	}
+	if count > 0 {
+		log.Debugf("Removed %d unused entries of %s", count, dir)
+	}
+}
@@ -0,0 +1,101 @@
+package builder
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+
+	"github.com/vim-volt/volt/gitutil"
+	"github.com/vim-volt/volt/internal/testutil"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func newTestTreeFile(name string, content []byte) *gitutil.TreeFile {
+	hash := plumbing.ComputeHash(plumbing.BlobObject, content).String()
+	return gitutil.NewTreeFile(name, hash, 0644, func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(content)), nil
+	})
+}
+
+// Checks:
+// (A) Store entries are read-only
+// (B) Built files are hard links to store entries
+// (C) Modified store entries are extracted again
+func TestLinkFromStore(t *testing.T) {
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	dir := filepath.Join(os.Getenv("HOME"), "build")
+	os.MkdirAll(dir, 0755)
+	content := []byte("echo 'hello'\n")
+	file := newTestTreeFile("plugin/hello.vim", content)
+	entry := storeEntry(file.Hash, file.Mode)
+
+	dst := filepath.Join(dir, "hello.vim")
+	if err := linkFromStore(logger.Default(), file, dst, file.Mode); err != nil {
+		t.Fatal("linkFromStore() failed: " + err.Error())
+	}
+	// (A)
+	fi, err := os.Stat(entry)
+	if err != nil {
+		t.Fatal("store entry was not created: " + err.Error())
+	}
+	if fi.Mode()&0222 != 0 {
+		t.Errorf("expected store entry is read-only but got %s", fi.Mode())
+	}
+	// (B)
+	if dfi, err := os.Stat(dst); err != nil || !os.SameFile(fi, dfi) {
+		t.Errorf("expected %s is a hard link to %s", dst, entry)
+	}
+
+	// Edit the built file in place
+	os.Chmod(dst, 0644)
+	if err := ioutil.WriteFile(dst, []byte("modified\n"), 0644); err != nil {
+		t.Fatal("failed to modify file: " + err.Error())
+	}
+	dst2 := filepath.Join(dir, "hello2.vim")
+	if err := linkFromStore(logger.Default(), file, dst2, file.Mode); err != nil {
+		t.Fatal("linkFromStore() failed: " + err.Error())
+	}
+	// (C)
+	if actual, _ := ioutil.ReadFile(dst2); !bytes.Equal(actual, content) {
+		t.Errorf("expected %q but got %q", string(content), string(actual))
+	}
+	if !isValidEntry(logger.Default(), entry, file.Hash) {
+		t.Errorf("expected %s is extracted again", entry)
+	}
+}
+
+// Checks:
+// (A) Store entries which are not linked from any builds are removed
+// (B) Store entries which are linked from builds are kept
+func TestGCStore(t *testing.T) {
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	dir := filepath.Join(os.Getenv("HOME"), "build")
+	os.MkdirAll(dir, 0755)
+	used := newTestTreeFile("used.vim", []byte("used\n"))
+	unused := newTestTreeFile("unused.vim", []byte("unused\n"))
+	for _, file := range []*gitutil.TreeFile{used, unused} {
+		if err := linkFromStore(logger.Default(), file, filepath.Join(dir, file.Name), file.Mode); err != nil {
+			t.Fatal("linkFromStore() failed: " + err.Error())
+		}
+	}
+	os.Remove(filepath.Join(dir, unused.Name))
+
+	gcStore(logger.Default())
+
+	// (A)
+	if pathutil.Exists(storeEntry(unused.Hash, unused.Mode)) {
+		t.Error("expected unused store entry is removed")
+	}
+	// (B)
+	if !pathutil.Exists(storeEntry(used.Hash, used.Mode)) {
+		t.Error("expected used store entry is kept")
+	}
+}
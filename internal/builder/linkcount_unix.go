@@ -0,0 +1,18 @@
+// +build !windows
+
+package builder
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// linkCount returns the number of hard links of the file.
+func linkCount(path string, fi os.FileInfo) (uint64, error) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, errors.New("unknown file info")
+	}
+	return uint64(st.Nlink), nil
+}
@@ -0,0 +1,22 @@
+// +build windows
+
+package builder
+
+import (
+	"os"
+	"syscall"
+)
+
+// linkCount returns the number of hard links of the file.
+func linkCount(path string, fi os.FileInfo) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	var info syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(syscall.Handle(f.Fd()), &info); err != nil {
+		return 0, err
+	}
+	return uint64(info.NumberOfLinks), nil
+}
@@ -0,0 +1,527 @@
+package builder
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"gopkg.in/src-d/go-git.v4/utils/diff"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/fileutil"
+	"github.com/vim-volt/volt/internal/buildinfo"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// PlanAction is an action to a file or directory in Plan.
+type PlanAction string
+
+const (
+	// PlanAdd creates a new file or directory
+	PlanAdd PlanAction = "add"
+	// PlanUpdate updates an existing file or directory
+	PlanUpdate PlanAction = "update"
+	// PlanRemove removes a file or directory
+	PlanRemove PlanAction = "remove"
+	// PlanRefuse refuses to overwrite a file which is not generated by volt
+	PlanRefuse PlanAction = "refuse"
+)
+
+// ReposChange is a change of (vim dir)/pack/volt/opt/{repos}.
+type ReposChange struct {
+	Action PlanAction
+	Path   pathutil.ReposPath
+	// Reason describes why the repository is added or updated
+	Reason string
+	// Err is an error found when planning. The repository is not installed
+	// and the build fails.
+	Err error
+
+	repos      *lockjson.Repos
+	buildRepos *buildinfo.Repos
+	// for git repository
+	copyFromGitObjects bool
+	// for static and system repository
+	src   string
+	files buildinfo.FileMap
+	modes map[string]os.FileMode
+}
+
+// RCFileChange is a change of vimrc or gvimrc in vim dir.
+type RCFileChange struct {
+	Action PlanAction
+	Path   string
+	// Sources are rc files of profiles which are concatenated into Path
+	Sources []string
+	// Err is the reason why the file cannot be installed (PlanRefuse)
+	Err error
+
+	content []byte
+}
+
+// PlugconfChange is a change of bundled plugconf file, or a removal of
+// (vim dir)/pack/volt-{profile} directory.
+type PlugconfChange struct {
+	Action PlanAction
+	Path   string
+	// Diff is a unified diff of the file
+	Diff string
+}
+
+// bundledPlugconf is a bundled plugconf file written by Plan.Apply().
+type bundledPlugconf struct {
+	path    string
+	content []byte
+}
+
+// Plan is a plan of 'volt build'. MakePlan() creates a plan without changing
+// any files, and Plan.Apply() changes files according to the plan.
+type Plan struct {
+	Full      bool
+	Strategy  string
+	Repos     []ReposChange
+	RCFiles   []RCFileChange
+	Plugconfs []PlugconfChange
+
+	builder           Builder
+	log               *logger.Logger
+	buildInfo         *buildinfo.BuildInfo
+	buildReposMap     map[pathutil.ReposPath]*buildinfo.Repos
+	lockJSON          *lockjson.LockJSON
+	reposList         lockjson.ReposList
+	profileNames      []string
+	bundledPlugconfs  []bundledPlugconf
+	removeProfileDirs []string
+}
+
+// IsEmpty returns true if the plan does not change any files.
+func (plan *Plan) IsEmpty() bool {
+	return len(plan.Repos) == 0 && len(plan.RCFiles) == 0 && len(plan.Plugconfs) == 0
+}
+
+// MakePlan returns the plan of 'volt build' without changing any files.
+// Warnings when making the plan and progress of Plan.Apply() are logged to
+// log.
+func MakePlan(full bool, log *logger.Logger) (*Plan, error) {
+	// Read config.toml
+	cfg, err := config.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read config.toml")
+	}
+	return makePlan(full, cfg, nil, log)
+}
+
+func makePlan(full bool, cfg *config.Config, lockJSON *lockjson.LockJSON, log *logger.Logger) (*Plan, error) {
+	// Get builder
+	blder, err := getBuilder(cfg, lockJSON, log)
+	if err != nil {
+		return nil, err
+	}
+	base := &BaseBuilder{config: cfg, lockJSON: lockJSON, log: log}
+
+	// Read ~/.vim/pack/volt/opt/build-info.json
+	buildInfo, err := buildinfo.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	// Do full build when:
+	// * build-info.json's version is different with current version
+	// * build-info.json's strategy is different with config
+	// * config strategy is symlink
+	if buildInfo.Version != currentBuildInfoVersion ||
+		buildInfo.Strategy != cfg.Build.Strategy ||
+		cfg.Build.Strategy == config.SymlinkBuilder {
+		full = true
+	}
+	buildInfo.Version = currentBuildInfoVersion
+	buildInfo.Strategy = cfg.Build.Strategy
+
+	// Put repos into map to be able to search with O(1).
+	// Use empty build-info.json map if the -full option was given
+	// because the repos info is unnecessary because it is not referenced.
+	var buildReposMap map[pathutil.ReposPath]*buildinfo.Repos
+	if full {
+		buildReposMap = make(map[pathutil.ReposPath]*buildinfo.Repos)
+	} else {
+		buildReposMap = make(map[pathutil.ReposPath]*buildinfo.Repos, len(buildInfo.Repos))
+		for i := range buildInfo.Repos {
+			repos := &buildInfo.Repos[i]
+			buildReposMap[repos.Path] = repos
+		}
+	}
+
+	// Read lock.json
+	lockJSON, err = base.readLockJSON()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read lock.json")
+	}
+
+	// Get repos list to install
+	reposList, err := base.getReposList(lockJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get current profile and the profiles it extends
+	profileNames, err := base.getProfileNames(lockJSON, lockJSON.CurrentProfileName)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{
+		Full:          full,
+		Strategy:      cfg.Build.Strategy,
+		builder:       blder,
+		log:           log,
+		buildInfo:     buildInfo,
+		buildReposMap: buildReposMap,
+		lockJSON:      lockJSON,
+		reposList:     reposList,
+		profileNames:  profileNames,
+	}
+	if err := blder.planRepos(plan); err != nil {
+		return nil, err
+	}
+	if err := base.planRCFiles(plan); err != nil {
+		return nil, err
+	}
+	if err := base.planPlugconfs(plan); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// Apply creates/updates ~/.vim/pack/volt directory according to the plan.
+// The directory is built in (vim dir)/pack/.volt-staging, and it is moved to
+// (vim dir)/pack/.volt-builds after the build succeeded. Then
+// ~/.vim/pack/volt, which is a symlink to the current build, is switched to
+// it (see activateBuild()). So ~/.vim/pack/volt is not changed if the build
+// failed. The previous build is kept for Rollback().
+func (plan *Plan) Apply() error {
+	optDir := pathutil.VimVoltOptDir()
+	if plan.Full {
+		plan.log.Info("Full building " + optDir + " directory ...")
+	} else {
+		plan.log.Info("Building " + optDir + " directory ...")
+	}
+
+	vimVoltDir := pathutil.VimVoltDir()
+	staging := pathutil.VimVoltStagingDir()
+	if err := plan.prepareStaging(vimVoltDir, staging); err != nil {
+		os.RemoveAll(staging)
+		return err
+	}
+
+	// Build in staging directory
+	plan.rebase(vimVoltDir, staging)
+	pathutil.SetVimVoltDir(staging)
+	err := plan.builder.Build(plan)
+	pathutil.SetVimVoltDir("")
+	plan.rebase(staging, vimVoltDir)
+	if err != nil {
+		os.RemoveAll(staging)
+		return err
+	}
+
+	build, err := newBuildDir()
+	if err != nil {
+		os.RemoveAll(staging)
+		return err
+	}
+	if err := os.Rename(staging, build); err != nil {
+		os.RemoveAll(staging)
+		return errors.Wrap(err, "failed to move "+staging)
+	}
+	if err := activateBuild(plan.log, build); err != nil {
+		return err
+	}
+	gcStore(plan.log)
+	return nil
+}
+
+// prepareStaging creates staging directory.
+// Full build starts from an empty directory. Smart build starts from hard
+// links of the files of current build, and builders never write to existing
+// files but replace them, so the previous build is not changed.
+func (plan *Plan) prepareStaging(vimVoltDir, staging string) error {
+	// Remove staging directory left by the build which was interrupted
+	os.RemoveAll(staging)
+	if pathutil.Exists(staging) {
+		return errors.New("failed to remove " + staging)
+	}
+	if plan.Full || !pathutil.Exists(vimVoltDir) {
+		return os.MkdirAll(staging, 0755)
+	}
+	err := fileutil.TryLinkDir(vimVoltDir, staging, nil, 0755, 0)
+	if err != nil {
+		return errors.Wrap(err, "failed to copy "+vimVoltDir+" to "+staging)
+	}
+	return nil
+}
+
+// rebase changes paths under from in plan to the paths under to.
+func (plan *Plan) rebase(from, to string) {
+	prefix := from + string(filepath.Separator)
+	for i := range plan.bundledPlugconfs {
+		path := plan.bundledPlugconfs[i].path
+		if strings.HasPrefix(path, prefix) {
+			plan.bundledPlugconfs[i].path = filepath.Join(to, path[len(prefix):])
+		}
+	}
+}
+
+// Rollback restores ~/.vim/pack/volt to the previous build.
+// The current build is kept as the previous build, so Rollback() again
+// restores the current build. Progress is logged to log.
+func Rollback(log *logger.Logger) error {
+	prev, err := readBuildLink(pathutil.VimVoltPrevDir())
+	if err != nil {
+		return err
+	}
+	if prev == "" || !pathutil.Exists(prev) {
+		return errors.New("no previous build exists: " + pathutil.VimVoltPrevDir())
+	}
+	log.Info("Rolling back " + pathutil.VimVoltDir() + " ...")
+	return activateBuild(log, prev)
+}
+
+// newReposChange returns a change to add or update repos.
+// repos is copied because plan.reposList is sorted when generating bundled
+// plugconf.
+func (plan *Plan) newReposChange(repos *lockjson.Repos) ReposChange {
+	r := *repos
+	repos = &r
+	change := ReposChange{
+		Action:     PlanUpdate,
+		Path:       repos.Path,
+		Reason:     "full build",
+		repos:      repos,
+		buildRepos: plan.buildReposMap[repos.Path],
+	}
+	if plan.buildInfo.Repos.FindByReposPath(repos.Path) == nil {
+		change.Action = PlanAdd
+		change.Reason = "new repository"
+	}
+	return change
+}
+
+// planRemoveRepos appends repositories which exist in
+// (vim dir)/pack/volt/opt but not in the repos list to plan.
+func (*BaseBuilder) planRemoveRepos(plan *Plan) error {
+	reposDirList, err := ioutil.ReadDir(pathutil.VimVoltOptDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for i := range reposDirList {
+		reposPath := pathutil.DecodeReposPath(reposDirList[i].Name())
+		if !plan.reposList.Contains(reposPath) {
+			plan.Repos = append(plan.Repos, ReposChange{Action: PlanRemove, Path: reposPath})
+		}
+	}
+	return nil
+}
+
+// planRCFiles appends changes of vimrc and gvimrc to plan.
+func (builder *BaseBuilder) planRCFiles(plan *Plan) error {
+	vimDir := pathutil.VimDir()
+	for _, rc := range []struct {
+		src string
+		dst string
+	}{
+		{pathutil.ProfileVimrc, filepath.Join(vimDir, pathutil.Vimrc)},
+		{pathutil.ProfileGvimrc, filepath.Join(vimDir, pathutil.Gvimrc)},
+	} {
+		change, err := builder.planRCFile(plan.profileNames, rc.src, rc.dst)
+		if err != nil {
+			return err
+		}
+		if change != nil {
+			plan.RCFiles = append(plan.RCFiles, *change)
+		}
+	}
+	return nil
+}
+
+// planRCFile returns a change of dst, or nil if dst is not changed.
+func (builder *BaseBuilder) planRCFile(profileNames []string, srcRCFileName, dst string) (*RCFileChange, error) {
+	srcList := builder.lookUpProfileRCFiles(profileNames, srcRCFileName)
+	profileName := profileNames[len(profileNames)-1]
+	exists := pathutil.Exists(dst)
+
+	// Refuse if destination file does not have magic comment
+	if exists && !builder.HasMagicComment(dst) {
+		if len(srcList) == 0 {
+			return nil, nil
+		}
+		return &RCFileChange{
+			Action:  PlanRefuse,
+			Path:    dst,
+			Sources: srcList,
+			Err:     errors.Errorf("'%s' is not an auto-generated file. please move to '%s' and re-run 'volt build'", dst, pathutil.RCDir(profileName)),
+		}, nil
+	}
+
+	// Remove destination if rc file does not exist
+	if len(srcList) == 0 {
+		if exists {
+			return &RCFileChange{Action: PlanRemove, Path: dst}, nil
+		}
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	if err := builder.writeWithMagicComment(&buf, srcList); err != nil {
+		return nil, err
+	}
+	change := &RCFileChange{Action: PlanAdd, Path: dst, Sources: srcList, content: buf.Bytes()}
+	if exists {
+		old, err := ioutil.ReadFile(dst)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(old, change.content) {
+			return nil, nil
+		}
+		change.Action = PlanUpdate
+	}
+	return change, nil
+}
+
+// planPlugconfs generates bundled plugconf files, and appends changes of them
+// to plan.
+func (builder *BaseBuilder) planPlugconfs(plan *Plan) error {
+	contents, removeDirs, err := builder.generateBundledPlugconfs(plan.lockJSON, plan.reposList, plan.profileNames)
+	if err != nil {
+		return err
+	}
+	plan.bundledPlugconfs = contents
+	plan.removeProfileDirs = removeDirs
+
+	for _, c := range contents {
+		action := PlanUpdate
+		old, err := ioutil.ReadFile(c.path)
+		if os.IsNotExist(err) {
+			action = PlanAdd
+		} else if err != nil {
+			return err
+		} else if bytes.Equal(old, c.content) {
+			continue
+		}
+		plan.Plugconfs = append(plan.Plugconfs, PlugconfChange{
+			Action: action,
+			Path:   c.path,
+			Diff:   unifiedDiff(c.path, string(old), string(c.content)),
+		})
+	}
+	for _, dir := range removeDirs {
+		plan.Plugconfs = append(plan.Plugconfs, PlugconfChange{Action: PlanRemove, Path: dir})
+	}
+	return nil
+}
+
+// unifiedDiff returns unified diff of from and to with 3 lines of context.
+func unifiedDiff(path, from, to string) string {
+	type diffLine struct {
+		op   byte
+		text string
+	}
+	var lines []diffLine
+	for _, d := range diff.Do(from, to) {
+		op := byte(' ')
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			op = '+'
+		case diffmatchpatch.DiffDelete:
+			op = '-'
+		}
+		for _, text := range strings.SplitAfter(d.Text, "\n") {
+			if text != "" {
+				lines = append(lines, diffLine{op, strings.TrimSuffix(text, "\n")})
+			}
+		}
+	}
+
+	const context = 3
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", path, path)
+	fromLine, toLine := 0, 0 // line numbers before lines[i]
+	for i := 0; i < len(lines); {
+		if lines[i].op == ' ' {
+			fromLine++
+			toLine++
+			i++
+			continue
+		}
+		// Extend the hunk while the next change is within 2*context lines
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < len(lines) {
+			if lines[end].op != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(lines) && lines[next].op == ' ' {
+				next++
+			}
+			if next == len(lines) || next-end > 2*context {
+				end += context
+				if end > next {
+					end = next
+				}
+				break
+			}
+			end = next
+		}
+
+		hunkFrom, hunkTo := fromLine-(i-start), toLine-(i-start)
+		var fromCount, toCount int
+		var body bytes.Buffer
+		for _, l := range lines[start:end] {
+			if l.op != '+' {
+				fromCount++
+			}
+			if l.op != '-' {
+				toCount++
+			}
+			body.WriteByte(l.op)
+			body.WriteString(l.text)
+			body.WriteByte('\n')
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", hunkRange(hunkFrom, fromCount), hunkRange(hunkTo, toCount))
+		buf.Write(body.Bytes())
+
+		for _, l := range lines[i:end] {
+			if l.op != '+' {
+				fromLine++
+			}
+			if l.op != '-' {
+				toLine++
+			}
+		}
+		i = end
+	}
+	return buf.String()
+}
+
+// hunkRange returns "{start},{count}" of a hunk header. start is 0-origin.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,156 @@
+package builder
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/gitutil"
+	"github.com/vim-volt/volt/internal/buildinfo"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+type symlinkBuilder struct {
+	BaseBuilder
+}
+
+func (builder *symlinkBuilder) planRepos(plan *Plan) error {
+	// symlink strategy always does full build
+	for i := range plan.reposList {
+		plan.Repos = append(plan.Repos, plan.newReposChange(&plan.reposList[i]))
+	}
+	return builder.planRemoveRepos(plan)
+}
+
+// TODO: rollback when return err (!= nil)
+func (builder *symlinkBuilder) Build(plan *Plan) error {
+	// Exit if vim executable was not found in PATH
+	vimExePath, err := pathutil.VimExecutable()
+	if err != nil {
+		return err
+	}
+
+	builder.log.Info("Installing vimrc and gvimrc ...")
+
+	err = builder.installRCFiles(plan.RCFiles)
+	if err != nil {
+		return err
+	}
+
+	// Mkdir opt dir
+	optDir := pathutil.VimVoltOptDir()
+	os.MkdirAll(optDir, 0755)
+	if !pathutil.Exists(optDir) {
+		return errors.New("could not create " + optDir)
+	}
+
+	reposList := plan.reposList
+	buildInfo := plan.buildInfo
+	buildInfo.Repos = make([]buildinfo.Repos, 0, len(reposList))
+	done := make(chan actionReposResult, len(reposList))
+	for i := range reposList {
+		go builder.installRepos(&reposList[i], done)
+		// Make build-info.json data
+		buildInfo.Repos = append(buildInfo.Repos, buildinfo.Repos{
+			Type:    reposList[i].Type,
+			Path:    reposList[i].Path,
+			Version: reposList[i].Version,
+		})
+	}
+	results := make([]actionReposResult, 0, len(reposList))
+	for i := 0; i < len(reposList); i++ {
+		results = append(results, <-done)
+	}
+
+	// Run ":helptags" for installed repositories at once
+	builder.helptags(results, vimExePath)
+
+	for i := range results {
+		if results[i].err != nil {
+			return results[i].err
+		}
+		if results[i].repos != nil {
+			builder.log.Debug("Installing " + string(results[i].repos.Type) + " repository " + results[i].repos.Path.String() + " ... Done.")
+		}
+	}
+
+	// Write bundled plugconf file
+	err = builder.installBundledPlugconf(plan)
+	if err != nil {
+		return err
+	}
+
+	// Write build-info.json
+	return buildInfo.Write()
+}
+
+func (builder *symlinkBuilder) installRepos(repos *lockjson.Repos, done chan actionReposResult) {
+	src, err := builder.reposFullPath(repos)
+	if err != nil {
+		done <- actionReposResult{err: err}
+		return
+	}
+	dst := repos.Path.EncodeToPlugDirName()
+
+	copied := false
+	if repos.Type == lockjson.ReposGitType {
+		// Determine it is bare repository or not
+		isBare, err := builder.git.IsBare(src)
+		if err != nil {
+			done <- actionReposResult{
+				err: errors.Errorf("repository %q: %s", src, err.Error()),
+			}
+			return
+		}
+
+		// Show warning when HEAD and locked revision are different
+		head, err := gitutil.GetHEAD(builder.git, src)
+		if err != nil {
+			done <- actionReposResult{
+				err: errors.Errorf("failed to get HEAD revision of %q: %s", src, err.Error()),
+			}
+			return
+		}
+		if head != repos.Version {
+			log := builder.log.With("repos", repos.Path)
+			log.Warnf("%s: HEAD and locked revision are different", repos.Path)
+			log.Warn("  HEAD: " + head)
+			log.Warn("  locked revision: " + repos.Version)
+			log.Warn("  Please run 'volt get -l' to update locked revision.")
+		}
+
+		if isBare {
+			// Copy files from git objects under vim dir
+			updateDone := make(chan actionReposResult, 1)
+			(&copyBuilder{BaseBuilder: builder.BaseBuilder}).updateBareGitRepos(src, dst, repos, nil, updateDone)
+			result := <-updateDone
+			if result.err != nil {
+				done <- actionReposResult{err: result.err}
+				return
+			}
+			copied = true
+		}
+	}
+
+	if !copied {
+		// Make symlinks under vim dir
+		if err := builder.symlink(src, dst); err != nil {
+			done <- actionReposResult{err: err}
+			return
+		}
+	}
+	// ":helptags" is run after all repositories were installed.
+	// System repositories are read-only, so their tags files must be
+	// created by the administrator.
+	done <- actionReposResult{repos: repos, helptags: repos.Type != lockjson.ReposSystemType}
+}
+
+func (*symlinkBuilder) symlink(src, dst string) error {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/c", "mklink", "/J", dst, src).Run()
+	}
+	return os.Symlink(src, dst)
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/pkg/errors"
 	"io/ioutil"
 
+	"github.com/vim-volt/volt/fileutil"
 	"github.com/vim-volt/volt/lockjson"
 	"github.com/vim-volt/volt/pathutil"
 )
@@ -68,7 +69,7 @@ func (buildInfo *BuildInfo) Write() error {
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(pathutil.BuildInfoJSON(), bytes, 0644)
+	return fileutil.WriteFileAtomic(pathutil.BuildInfoJSON(), bytes, 0644)
 }
 
 func (buildInfo *BuildInfo) validate() error {
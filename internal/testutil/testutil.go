@@ -57,6 +57,11 @@ func SetUpEnv(t *testing.T) {
 			t.Fatalf("failed to set %s", env)
 		}
 	}
+	// Do not read system config file of the host
+	err = os.Setenv("VOLT_SYSTEM_CONFIG", filepath.Join(tempDir, "system", "config.toml"))
+	if err != nil {
+		t.Fatal("failed to set VOLT_SYSTEM_CONFIG")
+	}
 }
 
 func CleanUpEnv(t *testing.T) {
@@ -232,5 +237,5 @@ func DefaultMatrix(t *testing.T, f func(*testing.T, bool, string)) {
 }
 
 func AvailableStrategies() []string {
-	return []string{config.SymlinkBuilder, config.CopyBuilder}
+	return []string{config.SymlinkBuilder, config.CopyBuilder, config.HardlinkStoreBuilder}
 }
@@ -7,9 +7,11 @@ import (
 	"path/filepath"
 	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 
+	"github.com/vim-volt/volt/fileutil"
 	"github.com/vim-volt/volt/logger"
 	"github.com/vim-volt/volt/pathutil"
 )
@@ -45,6 +47,16 @@ type Repos struct {
 	Type    ReposType          `json:"type"`
 	Path    pathutil.ReposPath `json:"path"`
 	Version string             `json:"version"`
+	// PlugconfTemplate is where the plugconf was generated from
+	PlugconfTemplate *PlugconfTemplate `json:"plugconf_template,omitempty"`
+}
+
+// PlugconfTemplate is a source of plugconf template.
+type PlugconfTemplate struct {
+	// Source is one of plugconf.template_sources of config.toml
+	Source string `json:"source"`
+	// Version is the commit hash of Source if Source is a git repository
+	Version string `json:"version,omitempty"`
 }
 
 type profReposPath []pathutil.ReposPath
@@ -52,10 +64,11 @@ type profReposPath []pathutil.ReposPath
 // Profile is a element of LockJSON.Profiles
 type Profile struct {
 	Name      string        `json:"name"`
+	Extends   []string      `json:"extends,omitempty"`
 	ReposPath profReposPath `json:"repos_path"`
 }
 
-const lockJSONVersion = 2
+const lockJSONVersion = 3
 
 func initialLockJSON() *LockJSON {
 	return &LockJSON{
@@ -96,7 +109,7 @@ func read(doLog bool) (*LockJSON, error) {
 	var lockJSON LockJSON
 	err = json.Unmarshal(bytes, &lockJSON)
 	if err != nil {
-		return nil, err
+		return nil, withPrevHint(err)
 	}
 
 	if lockJSON.Version < lockJSONVersion {
@@ -113,12 +126,22 @@ func read(doLog bool) (*LockJSON, error) {
 	// Validate lock.json
 	err = validate(&lockJSON)
 	if err != nil {
-		return nil, errors.Wrap(err, "validation failed: lock.json")
+		return nil, withPrevHint(errors.Wrap(err, "validation failed: lock.json"))
 	}
 
 	return &lockJSON, nil
 }
 
+// withPrevHint adds a hint to recover lock.json from lock.json.prev to err if
+// lock.json.prev exists.
+func withPrevHint(err error) error {
+	prev := pathutil.LockJSONPrev()
+	if !pathutil.Exists(prev) {
+		return err
+	}
+	return errors.Errorf("%s (the last good lock.json is saved as '%s')", err.Error(), prev)
+}
+
 // sortArrays sorts all arrays in lock.json for generating readable diff output
 // when lock.json is under version-controled.
 func sortArrays(lockJSON *LockJSON) {
@@ -194,6 +217,31 @@ func validate(lockJSON *LockJSON) error {
 		}
 	}
 
+	// Validate if profiles[]/extends[] exists in profiles[]/name
+	for i := range lockJSON.Profiles {
+		profile := &lockJSON.Profiles[i]
+		dup = make(map[string]bool, len(profile.Extends))
+		for j, name := range profile.Extends {
+			if lockJSON.Profiles.FindIndexByName(name) < 0 {
+				return errors.New(
+					"'" + name + "' (profiles[" + strconv.Itoa(i) +
+						"].extends[" + strconv.Itoa(j) + "]) doesn't exist in profiles")
+			}
+			// Validate if duplicate profiles[]/extends[] exist
+			if _, exists := dup[name]; exists {
+				return errors.New("duplicate '" + name + "' (extends) in profile '" + profile.Name + "'")
+			}
+			dup[name] = true
+		}
+	}
+
+	// Validate if profiles[]/extends[] does not make a cycle
+	for i := range lockJSON.Profiles {
+		if _, err := lockJSON.ResolveProfiles(&lockJSON.Profiles[i]); err != nil {
+			return err
+		}
+	}
+
 	// Validate if current_profile_name exists in profiles[]/name
 	found := false
 	for i := range lockJSON.Profiles {
@@ -245,7 +293,7 @@ func validateMissing(lockJSON *LockJSON) error {
 				return errors.New("missing: repos[" + strconv.Itoa(i) + "].version")
 			}
 			fallthrough
-		case ReposStaticType:
+		case ReposStaticType, ReposSystemType:
 			if repos.Path.String() == "" {
 				return errors.New("missing: repos[" + strconv.Itoa(i) + "].path")
 			}
@@ -269,6 +317,11 @@ func validateMissing(lockJSON *LockJSON) error {
 				return errors.New("missing: profile[" + strconv.Itoa(i) + "].repos_path[" + strconv.Itoa(j) + "]")
 			}
 		}
+		for j, name := range profile.Extends {
+			if name == "" {
+				return errors.New("missing: profile[" + strconv.Itoa(i) + "].extends[" + strconv.Itoa(j) + "]")
+			}
+		}
 	}
 	return nil
 }
@@ -297,7 +350,33 @@ func (lockJSON *LockJSON) Write() error {
 	if err != nil {
 		return err
 	}
-	return ioutil.WriteFile(pathutil.LockJSON(), bytes, 0644)
+	err = savePrev(lockfile, bytes)
+	if err != nil {
+		return errors.Wrap(err, "could not save lock.json.prev")
+	}
+	return fileutil.WriteFileAtomic(lockfile, bytes, 0644)
+}
+
+// savePrev saves current lock.json as lock.json.prev before lock.json is
+// overwritten by newContent. lock.json.prev is not changed if current
+// lock.json is broken or same as newContent, so it always holds the last good
+// lock.json.
+func savePrev(lockfile string, newContent []byte) error {
+	content, err := ioutil.ReadFile(lockfile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if string(content) == string(newContent) {
+		return nil
+	}
+	var lockJSON LockJSON
+	if json.Unmarshal(content, &lockJSON) != nil {
+		return nil
+	}
+	return fileutil.WriteFileAtomic(pathutil.LockJSONPrev(), content, 0644)
 }
 
 // GetCurrentReposList returns current profile's repositories.
@@ -405,14 +484,97 @@ func (reposPathList profReposPath) IndexOf(reposPath pathutil.ReposPath) int {
 }
 
 // GetReposListByProfile collects each repository of given profile and returns it.
+// The repositories of the profiles which the profile extends are also
+// collected (inherited repositories come first).
 func (lockJSON *LockJSON) GetReposListByProfile(profile *Profile) (ReposList, error) {
+	profiles, err := lockJSON.ResolveProfiles(profile)
+	if err != nil {
+		return nil, err
+	}
 	reposList := make(ReposList, 0, len(profile.ReposPath))
-	for _, reposPath := range profile.ReposPath {
-		repos := lockJSON.Repos.FindByPath(reposPath)
-		if repos == nil {
-			return nil, errors.New("repos '" + reposPath.String() + "' does not exist")
+	for _, p := range profiles {
+		for _, reposPath := range p.ReposPath {
+			if reposList.Contains(reposPath) {
+				continue
+			}
+			repos := lockJSON.Repos.FindByPath(reposPath)
+			if repos == nil {
+				return nil, errors.New("repos '" + reposPath.String() + "' does not exist")
+			}
+			reposList = append(reposList, *repos)
 		}
-		reposList = append(reposList, *repos)
 	}
 	return reposList, nil
 }
+
+// ResolveProfiles returns profile and all profiles which profile extends
+// directly or indirectly, in inheritance order: the farthest ancestor comes
+// first, and profile itself comes last.
+// Non-nil error is returned if a profile does not exist or
+// the inheritance has a cycle.
+func (lockJSON *LockJSON) ResolveProfiles(profile *Profile) ([]*Profile, error) {
+	result := make([]*Profile, 0, len(profile.Extends)+1)
+	visited := make(map[string]bool, len(lockJSON.Profiles))
+	err := lockJSON.resolveProfiles(profile, nil, visited, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (lockJSON *LockJSON) resolveProfiles(profile *Profile, path []string, visited map[string]bool, result *[]*Profile) error {
+	for i := range path {
+		if path[i] == profile.Name {
+			cycle := append(path[i:], profile.Name)
+			return errors.New("cyclic profile inheritance: " + strings.Join(cycle, " -> "))
+		}
+	}
+	if visited[profile.Name] {
+		return nil
+	}
+	path = append(path, profile.Name)
+	for _, name := range profile.Extends {
+		parent, err := lockJSON.Profiles.FindByName(name)
+		if err != nil {
+			return err
+		}
+		err = lockJSON.resolveProfiles(parent, path, visited, result)
+		if err != nil {
+			return err
+		}
+	}
+	visited[profile.Name] = true
+	*result = append(*result, profile)
+	return nil
+}
+
+// GetInheritedReposPath returns repositories which profile inherits from the
+// profiles it extends. The repositories which profile itself has are not
+// included.
+func (lockJSON *LockJSON) GetInheritedReposPath(profile *Profile) ([]InheritedReposPath, error) {
+	profiles, err := lockJSON.ResolveProfiles(profile)
+	if err != nil {
+		return nil, err
+	}
+	var result []InheritedReposPath
+	seen := append(profReposPath{}, profile.ReposPath...)
+	for _, p := range profiles[:len(profiles)-1] {
+		for _, reposPath := range p.ReposPath {
+			if seen.Contains(reposPath) {
+				continue
+			}
+			seen = append(seen, reposPath)
+			result = append(result, InheritedReposPath{
+				Path:    reposPath,
+				Profile: p.Name,
+			})
+		}
+	}
+	return result, nil
+}
+
+// InheritedReposPath is a repository which a profile inherits from Profile.
+type InheritedReposPath struct {
+	Path    pathutil.ReposPath
+	Profile string
+}
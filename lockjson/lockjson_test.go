@@ -0,0 +1,320 @@
+package lockjson
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// setUpVoltPath sets $VOLTPATH to a temp directory, and returns a function
+// to restore it.
+func setUpVoltPath(t *testing.T) func() {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "volt-test-lockjson-")
+	if err != nil {
+		t.Fatal("failed to create temp dir: " + err.Error())
+	}
+	old := os.Getenv("VOLTPATH")
+	os.Setenv("VOLTPATH", dir)
+	return func() {
+		os.Setenv("VOLTPATH", old)
+		os.RemoveAll(dir)
+	}
+}
+
+func marshal(t *testing.T, lockJSON *LockJSON) string {
+	t.Helper()
+	sortArrays(lockJSON)
+	b, err := json.MarshalIndent(lockJSON, "", "  ")
+	if err != nil {
+		t.Fatal("failed to marshal lock.json: " + err.Error())
+	}
+	return string(b)
+}
+
+// checkFile checks the content of file. If expected is empty, file must not
+// exist.
+func checkFile(t *testing.T, file, expected string) {
+	t.Helper()
+	content, err := ioutil.ReadFile(file)
+	if expected == "" {
+		if !os.IsNotExist(err) {
+			t.Errorf("%s must not exist: %v", file, err)
+		}
+		return
+	}
+	if err != nil {
+		t.Errorf("failed to read %s: %s", file, err.Error())
+	} else if string(content) != expected {
+		t.Errorf("expected %s is\n%s\nbut got\n%s", file, expected, string(content))
+	}
+}
+
+// Write saves the last good lock.json as lock.json.prev, and both files are
+// always valid lock.json
+func TestWritePrev(t *testing.T) {
+	defer setUpVoltPath(t)()
+	lockfile := pathutil.LockJSON()
+	prev := pathutil.LockJSONPrev()
+
+	// No lock.json.prev is saved at first
+	first := initialLockJSON()
+	if err := first.Write(); err != nil {
+		t.Fatal("Write() failed: " + err.Error())
+	}
+	checkFile(t, lockfile, marshal(t, first))
+	checkFile(t, prev, "")
+
+	// Previous lock.json is saved
+	second := initialLockJSON()
+	second.Repos = append(second.Repos, Repos{Type: ReposStaticType, Path: "localhost/local/hello"})
+	second.Profiles[0].ReposPath = append(second.Profiles[0].ReposPath, "localhost/local/hello")
+	if err := second.Write(); err != nil {
+		t.Fatal("Write() failed: " + err.Error())
+	}
+	checkFile(t, lockfile, marshal(t, second))
+	checkFile(t, prev, marshal(t, first))
+
+	// lock.json.prev is not changed if lock.json is not changed
+	if err := second.Write(); err != nil {
+		t.Fatal("Write() failed: " + err.Error())
+	}
+	checkFile(t, lockfile, marshal(t, second))
+	checkFile(t, prev, marshal(t, first))
+
+	// Invalid lock.json is not written
+	invalid := initialLockJSON()
+	invalid.CurrentProfileName = "unknown"
+	if err := invalid.Write(); err == nil {
+		t.Error("Write() must fail with invalid lock.json")
+	}
+	checkFile(t, lockfile, marshal(t, second))
+	checkFile(t, prev, marshal(t, first))
+
+	// Broken lock.json is not saved as lock.json.prev
+	if err := ioutil.WriteFile(lockfile, []byte("{"), 0644); err != nil {
+		t.Fatal("failed to write lock.json: " + err.Error())
+	}
+	third := initialLockJSON()
+	if err := third.Write(); err != nil {
+		t.Fatal("Write() failed: " + err.Error())
+	}
+	checkFile(t, lockfile, marshal(t, third))
+	checkFile(t, prev, marshal(t, first))
+
+	// No temporary files are left
+	infos, err := ioutil.ReadDir(pathutil.VoltPath())
+	if err != nil {
+		t.Fatal("failed to read $VOLTPATH: " + err.Error())
+	}
+	if len(infos) != 2 {
+		var names []string
+		for _, fi := range infos {
+			names = append(names, fi.Name())
+		}
+		t.Errorf("expected only lock.json and lock.json.prev but got %v", names)
+	}
+}
+
+// newProfilesLockJSON returns lock.json which has profiles, and has all
+// repositories of them as static repositories.
+func newProfilesLockJSON(profiles ...Profile) *LockJSON {
+	lockJSON := initialLockJSON()
+	lockJSON.Profiles = profiles
+	lockJSON.CurrentProfileName = profiles[0].Name
+	for i := range profiles {
+		if profiles[i].ReposPath == nil {
+			profiles[i].ReposPath = profReposPath{}
+		}
+		for _, reposPath := range profiles[i].ReposPath {
+			if !lockJSON.Repos.Contains(reposPath) {
+				lockJSON.Repos = append(lockJSON.Repos, Repos{Type: ReposStaticType, Path: reposPath})
+			}
+		}
+	}
+	return lockJSON
+}
+
+// diamondProfiles returns profiles where "d" extends "b" and "c", and both of
+// them extend "a".
+func diamondProfiles() []Profile {
+	return []Profile{
+		{Name: "d", Extends: []string{"b", "c"}, ReposPath: profReposPath{"localhost/local/r3", "localhost/local/r4"}},
+		{Name: "c", Extends: []string{"a"}, ReposPath: profReposPath{"localhost/local/r2", "localhost/local/r3"}},
+		{Name: "b", Extends: []string{"a"}, ReposPath: profReposPath{"localhost/local/r1", "localhost/local/r2"}},
+		{Name: "a", ReposPath: profReposPath{"localhost/local/r1"}},
+	}
+}
+
+func TestResolveProfiles(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		profiles []Profile
+		expected []string // profile names in inheritance order
+		err      string
+	}{
+		{
+			"no extends",
+			[]Profile{{Name: "a"}},
+			[]string{"a"}, "",
+		},
+		{
+			"chain",
+			[]Profile{{Name: "a", Extends: []string{"b"}}, {Name: "b", Extends: []string{"c"}}, {Name: "c"}},
+			[]string{"c", "b", "a"}, "",
+		},
+		{
+			"diamond",
+			diamondProfiles(),
+			[]string{"a", "b", "c", "d"}, "",
+		},
+		{
+			"extends itself",
+			[]Profile{{Name: "a", Extends: []string{"a"}}},
+			nil, "cyclic profile inheritance: a -> a",
+		},
+		{
+			"cycle",
+			[]Profile{{Name: "a", Extends: []string{"b"}}, {Name: "b", Extends: []string{"c"}}, {Name: "c", Extends: []string{"b"}}},
+			nil, "cyclic profile inheritance: b -> c -> b",
+		},
+		{
+			"missing parent",
+			[]Profile{{Name: "a", Extends: []string{"b"}}, {Name: "b", Extends: []string{"missing"}}},
+			nil, "profile 'missing' does not exist",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			lockJSON := newProfilesLockJSON(tt.profiles...)
+			profiles, err := lockJSON.ResolveProfiles(&lockJSON.Profiles[0])
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("expected error %q but got %v", tt.err, err)
+				}
+				return
+			}
+			var names []string
+			for _, p := range profiles {
+				names = append(names, p.Name)
+			}
+			if err != nil || !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("expected %v but got %v, %v", tt.expected, names, err)
+			}
+		})
+	}
+}
+
+// Repositories which are inherited from several profiles are not duplicated
+func TestDiamondInheritance(t *testing.T) {
+	lockJSON := newProfilesLockJSON(diamondProfiles()...)
+	profile := &lockJSON.Profiles[0]
+
+	inherited, err := lockJSON.GetInheritedReposPath(profile)
+	expected := []InheritedReposPath{
+		{Path: "localhost/local/r1", Profile: "a"},
+		{Path: "localhost/local/r2", Profile: "b"},
+	}
+	if err != nil || !reflect.DeepEqual(inherited, expected) {
+		t.Errorf("GetInheritedReposPath(): expected %v but got %v, %v", expected, inherited, err)
+	}
+
+	reposList, err := lockJSON.GetReposListByProfile(profile)
+	var paths []pathutil.ReposPath
+	for i := range reposList {
+		paths = append(paths, reposList[i].Path)
+	}
+	expectedPaths := []pathutil.ReposPath{"localhost/local/r1", "localhost/local/r2", "localhost/local/r3", "localhost/local/r4"}
+	if err != nil || !reflect.DeepEqual(paths, expectedPaths) {
+		t.Errorf("GetReposListByProfile(): expected %v but got %v, %v", expectedPaths, paths, err)
+	}
+}
+
+func TestValidateExtends(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		profiles []Profile
+		err      string
+	}{
+		{
+			"diamond",
+			diamondProfiles(),
+			"",
+		},
+		{
+			"extends itself",
+			[]Profile{{Name: "a", Extends: []string{"a"}}},
+			"cyclic profile inheritance: a -> a",
+		},
+		{
+			"cycle",
+			[]Profile{{Name: "a", Extends: []string{"b"}}, {Name: "b", Extends: []string{"a"}}},
+			"cyclic profile inheritance: a -> b -> a",
+		},
+		{
+			"missing parent",
+			[]Profile{{Name: "a"}, {Name: "b", Extends: []string{"a", "missing"}}},
+			"'missing' (profiles[1].extends[1]) doesn't exist in profiles",
+		},
+		{
+			"duplicate parent",
+			[]Profile{{Name: "a"}, {Name: "b", Extends: []string{"a", "a"}}},
+			"duplicate 'a' (extends) in profile 'b'",
+		},
+		{
+			"empty parent",
+			[]Profile{{Name: "a", Extends: []string{""}}},
+			"missing: profile[0].extends[0]",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate(newProfilesLockJSON(tt.profiles...))
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("expected no error but got %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("expected error %q but got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+// Read() migrates lock.json of old versions to the current version
+func TestReadMigration(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		content string
+	}{
+		{
+			"version 1",
+			`{"version": 1, "active_profile": "default", "repos": [{"type": "static", "path": "localhost/local/hello"}],` +
+				` "profiles": [{"name": "default", "repos_path": ["localhost/local/hello"]}]}`,
+		},
+		{
+			"version 2",
+			`{"version": 2, "current_profile_name": "default", "repos": [{"type": "static", "path": "localhost/local/hello"}],` +
+				` "profiles": [{"name": "default", "repos_path": ["localhost/local/hello"]}]}`,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			defer setUpVoltPath(t)()
+			if err := ioutil.WriteFile(pathutil.LockJSON(), []byte(tt.content), 0644); err != nil {
+				t.Fatal("failed to write lock.json: " + err.Error())
+			}
+
+			lockJSON, err := ReadNoMigrationMsg()
+			if err != nil {
+				t.Fatal("ReadNoMigrationMsg() failed: " + err.Error())
+			}
+			expected := newProfilesLockJSON(Profile{Name: "default", ReposPath: profReposPath{"localhost/local/hello"}})
+			if !reflect.DeepEqual(lockJSON, expected) {
+				t.Errorf("expected %+v but got %+v", expected, lockJSON)
+			}
+		})
+	}
+}
@@ -22,6 +22,7 @@ func migrate(rawJSON []byte, lockJSON *LockJSON) error {
 
 var migrateFunc = []func([]byte, *LockJSON) error{
 	migrate1To2,
+	migrate2To3,
 }
 
 // Rename 'active_profile' to 'current_profile_name'
@@ -39,3 +40,9 @@ func migrate1To2(rawJSON []byte, lockJSON *LockJSON) error {
 
 	return nil
 }
+
+// Add 'extends' to profiles[] (optional, so nothing to convert)
+func migrate2To3(rawJSON []byte, lockJSON *LockJSON) error {
+	lockJSON.Version += 1
+	return nil
+}
@@ -0,0 +1,143 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// TextFormat writes an entry as a line of the time, the level, the
+	// message, and "key=value" fields.
+	TextFormat = "text"
+	// JSONFormat writes an entry as a JSON object per line.
+	JSONFormat = "json"
+)
+
+var (
+	logFile   io.WriteCloser
+	logFormat = TextFormat
+	trxID     string
+)
+
+// OpenFile opens file to append all entries including debug level ones in
+// format (TextFormat or JSONFormat).
+// If other file was already opened, it is closed.
+func OpenFile(file, format string) error {
+	if format != TextFormat && format != JSONFormat {
+		return errors.Errorf("unknown log format: %q", format)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	m.Lock()
+	defer m.Unlock()
+	if logFile != nil {
+		logFile.Close()
+	}
+	logFile = f
+	logFormat = format
+	return nil
+}
+
+// Close closes the log file opened by OpenFile().
+func Close() error {
+	m.Lock()
+	defer m.Unlock()
+	if logFile == nil {
+		return nil
+	}
+	err := logFile.Close()
+	logFile = nil
+	return err
+}
+
+// SetTransactionID sets the ID of current transaction, which is recorded to
+// the log file. Empty id means no transaction is running.
+func SetTransactionID(id string) {
+	m.Lock()
+	defer m.Unlock()
+	trxID = id
+}
+
+type entry struct {
+	time   time.Time
+	level  LogLevel
+	msg    string
+	caller string
+	trxID  string
+	fields []Field
+}
+
+// writeEntry writes e to the log file. The caller must lock m.
+func writeEntry(e *entry) {
+	var line []byte
+	if logFormat == JSONFormat {
+		line = e.formatJSON()
+	} else {
+		line = e.formatText()
+	}
+	logFile.Write(append(line, '\n'))
+}
+
+const timeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// formatText formats e like:
+//
+//	2018-04-01T12:34:56.789Z DEBUG Installing github.com/tyru/caw.vim ... caller=subcmd/get.go:434 trx=3 repos=github.com/tyru/caw.vim
+func (e *entry) formatText() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(e.time.Format(timeFormat))
+	buf.WriteString(" " + strings.ToUpper(e.level.String()))
+	buf.WriteString(" " + strings.Replace(e.msg, "\n", `\n`, -1))
+	buf.WriteString(" caller=" + quoteIfNeeded(e.caller))
+	if e.trxID != "" {
+		buf.WriteString(" trx=" + quoteIfNeeded(e.trxID))
+	}
+	for _, f := range e.fields {
+		buf.WriteString(" " + f.Key + "=" + quoteIfNeeded(fmt.Sprint(f.Value)))
+	}
+	return buf.Bytes()
+}
+
+func quoteIfNeeded(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// formatJSON formats e like:
+//
+//	{"caller":"subcmd/get.go:434","level":"debug","msg":"Installing ...","repos":"github.com/tyru/caw.vim","time":"2018-04-01T12:34:56.789Z","trx":"3"}
+func (e *entry) formatJSON() []byte {
+	obj := make(map[string]interface{}, len(e.fields)+5)
+	for _, f := range e.fields {
+		obj[f.Key] = fmt.Sprint(f.Value)
+	}
+	obj["time"] = e.time.Format(timeFormat)
+	obj["level"] = e.level.String()
+	obj["msg"] = e.msg
+	obj["caller"] = e.caller
+	if e.trxID != "" {
+		obj["trx"] = e.trxID
+	}
+	line, err := json.Marshal(obj)
+	if err != nil {
+		// This must not be occurred because all values are strings
+		return []byte(strconv.Quote(e.msg))
+	}
+	return line
+}
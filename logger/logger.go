@@ -2,6 +2,7 @@ package logger
 
 import (
 	"fmt"
+	"io"
 	"runtime"
 	"strings"
 	"sync"
@@ -25,6 +26,19 @@ const (
 	DebugLevel LogLevel = 4
 )
 
+func (level LogLevel) String() string {
+	switch level {
+	case ErrorLevel:
+		return "error"
+	case WarnLevel:
+		return "warn"
+	case InfoLevel:
+		return "info"
+	default:
+		return "debug"
+	}
+}
+
 var (
 	errorLabel string
 	warnLabel  string
@@ -52,109 +66,201 @@ func init() {
 
 var logLevel = InfoLevel
 
+// Field is a key and a value attached to log entries.
+// For example, goroutines which process each repository attach the
+// repository path, so that interleaved entries can be filtered afterwards.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Logger logs messages with fields.
+type Logger struct {
+	fields []Field
+	// w is the writer of the messages. If nil, they are written to stdout
+	// (stderr if level is ErrorLevel).
+	w io.Writer
+}
+
+var std = &Logger{}
+
+// New returns a logger which writes the messages to w instead of stdout and
+// stderr. Labels are not colored. Use ioutil.Discard to discard the messages.
+// Entries are still written to the log file if it is opened.
+func New(w io.Writer) *Logger {
+	return &Logger{w: w}
+}
+
+// Default returns the logger which the package functions use.
+func Default() *Logger {
+	return std
+}
+
+// With returns a logger which attaches the field of key and value to
+// all entries.
+func With(key string, value interface{}) *Logger {
+	return std.With(key, value)
+}
+
+// With returns a logger which attaches the field of key and value to
+// all entries in addition to the fields of l.
+func (l *Logger) With(key string, value interface{}) *Logger {
+	fields := make([]Field, len(l.fields), len(l.fields)+1)
+	copy(fields, l.fields)
+	return &Logger{fields: append(fields, Field{Key: key, Value: value}), w: l.w}
+}
+
 // Errorf logs formatted message of arguments.
 func Errorf(format string, msgs ...interface{}) {
-	if logLevel < ErrorLevel {
-		return
-	}
-	m.Lock()
-	defer m.Unlock()
-	msgs = append([]interface{}{getDebugPrefix()}, msgs...)
-	out.Fprintf(colorable.NewColorableStderr(), errorLabel+"%s "+format+"\n", msgs...)
+	std.output(ErrorLevel, fmt.Sprintf(format, msgs...))
 }
 
 // Error logs message of arguments.
 func Error(msgs ...interface{}) {
-	if logLevel < ErrorLevel {
-		return
-	}
-	m.Lock()
-	defer m.Unlock()
-	cmsg := getDebugPrefix()
-	msgs = append([]interface{}{errorLabel + cmsg}, msgs...)
-	out.Fprintln(colorable.NewColorableStderr(), msgs...)
+	std.output(ErrorLevel, sprintln(msgs...))
 }
 
 // Warnf logs formatted message of arguments.
 func Warnf(format string, msgs ...interface{}) {
-	if logLevel < WarnLevel {
-		return
-	}
-	m.Lock()
-	defer m.Unlock()
-	msgs = append([]interface{}{getDebugPrefix()}, msgs...)
-	out.Printf(warnLabel+"%s "+format+"\n", msgs...)
+	std.output(WarnLevel, fmt.Sprintf(format, msgs...))
 }
 
 // Warn logs message of arguments.
 func Warn(msgs ...interface{}) {
-	if logLevel < WarnLevel {
-		return
-	}
-	m.Lock()
-	defer m.Unlock()
-	cmsg := getDebugPrefix()
-	msgs = append([]interface{}{warnLabel + cmsg}, msgs...)
-	out.Println(msgs...)
+	std.output(WarnLevel, sprintln(msgs...))
 }
 
 // Infof logs formatted message of arguments.
 func Infof(format string, msgs ...interface{}) {
-	if logLevel < InfoLevel {
-		return
-	}
-	m.Lock()
-	defer m.Unlock()
-	msgs = append([]interface{}{getDebugPrefix()}, msgs...)
-	out.Printf(infoLabel+"%s "+format+"\n", msgs...)
+	std.output(InfoLevel, fmt.Sprintf(format, msgs...))
 }
 
 // Info logs message of arguments.
 func Info(msgs ...interface{}) {
-	if logLevel < InfoLevel {
-		return
-	}
-	m.Lock()
-	defer m.Unlock()
-	cmsg := getDebugPrefix()
-	msgs = append([]interface{}{infoLabel + cmsg}, msgs...)
-	out.Println(msgs...)
+	std.output(InfoLevel, sprintln(msgs...))
 }
 
 // Debugf logs formatted message of arguments.
 func Debugf(format string, msgs ...interface{}) {
-	if logLevel < DebugLevel {
-		return
-	}
-	m.Lock()
-	defer m.Unlock()
-	msgs = append([]interface{}{getDebugPrefix()}, msgs...)
-	out.Printf(debugLabel+"%s "+format+"\n", msgs...)
+	std.output(DebugLevel, fmt.Sprintf(format, msgs...))
 }
 
 // Debug logs message of arguments.
 func Debug(msgs ...interface{}) {
-	if logLevel < DebugLevel {
-		return
-	}
+	std.output(DebugLevel, sprintln(msgs...))
+}
+
+// Errorf logs formatted message of arguments.
+func (l *Logger) Errorf(format string, msgs ...interface{}) {
+	l.output(ErrorLevel, fmt.Sprintf(format, msgs...))
+}
+
+// Error logs message of arguments.
+func (l *Logger) Error(msgs ...interface{}) {
+	l.output(ErrorLevel, sprintln(msgs...))
+}
+
+// Warnf logs formatted message of arguments.
+func (l *Logger) Warnf(format string, msgs ...interface{}) {
+	l.output(WarnLevel, fmt.Sprintf(format, msgs...))
+}
+
+// Warn logs message of arguments.
+func (l *Logger) Warn(msgs ...interface{}) {
+	l.output(WarnLevel, sprintln(msgs...))
+}
+
+// Infof logs formatted message of arguments.
+func (l *Logger) Infof(format string, msgs ...interface{}) {
+	l.output(InfoLevel, fmt.Sprintf(format, msgs...))
+}
+
+// Info logs message of arguments.
+func (l *Logger) Info(msgs ...interface{}) {
+	l.output(InfoLevel, sprintln(msgs...))
+}
+
+// Debugf logs formatted message of arguments.
+func (l *Logger) Debugf(format string, msgs ...interface{}) {
+	l.output(DebugLevel, fmt.Sprintf(format, msgs...))
+}
+
+// Debug logs message of arguments.
+func (l *Logger) Debug(msgs ...interface{}) {
+	l.output(DebugLevel, sprintln(msgs...))
+}
+
+// sprintln joins msgs with spaces like fmt.Println().
+func sprintln(msgs ...interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(msgs...), "\n")
+}
+
+// output writes msg to l.w, or stdout (stderr if level is ErrorLevel) if
+// level is enabled, and to the log file regardless of current log level.
+// This must be called from the exported functions and methods, to get the
+// caller of them.
+func (l *Logger) output(level LogLevel, msg string) {
 	m.Lock()
 	defer m.Unlock()
-	cmsg := getDebugPrefix()
-	msgs = append([]interface{}{debugLabel + cmsg}, msgs...)
-	out.Println(msgs...)
+	if logLevel < level && logFile == nil {
+		return
+	}
+	now := time.Now().UTC()
+	caller := getCaller(3)
+	if logLevel >= level && l.w != nil {
+		fmt.Fprintln(l.w, "["+strings.ToUpper(level.String())+"]"+l.getDebugPrefix(now, caller)+" "+msg)
+	} else if logLevel >= level {
+		var label string
+		switch level {
+		case ErrorLevel:
+			label = errorLabel
+		case WarnLevel:
+			label = warnLabel
+		case InfoLevel:
+			label = infoLabel
+		default:
+			label = debugLabel
+		}
+		line := label + l.getDebugPrefix(now, caller) + " " + msg
+		if level == ErrorLevel {
+			out.Fprintln(colorable.NewColorableStderr(), line)
+		} else {
+			out.Println(line)
+		}
+	}
+	if logFile != nil {
+		writeEntry(&entry{
+			time:   now,
+			level:  level,
+			msg:    msg,
+			caller: caller,
+			trxID:  trxID,
+			fields: l.fields,
+		})
+	}
 }
 
-func getDebugPrefix() string {
-	const voltDirName = "github.com/vim-volt/volt/"
+// getDebugPrefix returns the time, the caller, and the fields of an entry
+// shown when debug level is enabled.
+func (l *Logger) getDebugPrefix(now time.Time, caller string) string {
 	if logLevel < DebugLevel {
 		return ""
 	}
-	_, fn, line, _ := runtime.Caller(2)
+	prefix := fmt.Sprintf("[%s][%s]", now.Format("15:04:05.000"), caller)
+	for _, f := range l.fields {
+		prefix += fmt.Sprintf("[%s=%v]", f.Key, f.Value)
+	}
+	return prefix
+}
+
+func getCaller(skip int) string {
+	const voltDirName = "github.com/vim-volt/volt/"
+	_, fn, line, _ := runtime.Caller(skip)
 	idx := strings.Index(fn, voltDirName)
 	if idx >= 0 {
 		fn = fn[idx+len(voltDirName):]
 	}
-	return fmt.Sprintf("[%s][%s:%d]", time.Now().UTC().Format("15:04:05.000"), fn, line)
+	return fmt.Sprintf("%s:%d", fn, line)
 }
 
 // SetLevel sets current log level to level.
@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLogFile(t *testing.T) {
+	var tests = []struct {
+		format string
+		check  func(t *testing.T, line string)
+	}{
+		{
+			format: TextFormat,
+			check: func(t *testing.T, line string) {
+				fields := strings.Fields(line)
+				if len(fields) < 2 || fields[1] != "DEBUG" {
+					t.Errorf("expected DEBUG level but got %q", line)
+				}
+				for _, s := range []string{
+					" debug \"message\" ",
+					"caller=",
+					"logger_test.go:",
+					" trx=3 ",
+					` repos=github.com/tyru/caw.vim`,
+					` note="a b"`,
+				} {
+					if !strings.Contains(line, s) {
+						t.Errorf("expected %q in %q", s, line)
+					}
+				}
+			},
+		},
+		{
+			format: JSONFormat,
+			check: func(t *testing.T, line string) {
+				var obj map[string]string
+				if err := json.Unmarshal([]byte(line), &obj); err != nil {
+					t.Fatalf("could not parse %q: %s", line, err)
+				}
+				for key, expected := range map[string]string{
+					"level": "debug",
+					"msg":   `debug "message"`,
+					"trx":   "3",
+					"repos": "github.com/tyru/caw.vim",
+					"note":  "a b",
+				} {
+					if obj[key] != expected {
+						t.Errorf("expected %s = %q but got %q", key, expected, obj[key])
+					}
+				}
+				if !strings.Contains(obj["caller"], "logger_test.go:") {
+					t.Errorf("expected caller is logger_test.go but got %q", obj["caller"])
+				}
+			},
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "volt-test-logger-")
+	if err != nil {
+		t.Fatal("failed to create temp dir: " + err.Error())
+	}
+	defer os.RemoveAll(dir)
+	// Debug messages are recorded even if they are not shown
+	defer SetLevel(logLevel)
+	SetLevel(ErrorLevel)
+	defer SetTransactionID("")
+	SetTransactionID("3")
+
+	for _, tt := range tests {
+		file := filepath.Join(dir, tt.format, "volt.log")
+		if err := OpenFile(file, tt.format); err != nil {
+			t.Fatal("failed to open log file: " + err.Error())
+		}
+		With("repos", "github.com/tyru/caw.vim").With("note", "a b").Debug(`debug "message"`)
+		if err := Close(); err != nil {
+			t.Fatal("failed to close log file: " + err.Error())
+		}
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal("failed to read log file: " + err.Error())
+		}
+		lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+		if len(lines) != 1 {
+			t.Errorf("%s: expected 1 line but got %q", tt.format, string(content))
+			continue
+		}
+		tt.check(t, lines[0])
+	}
+}
+
+func TestOpenFileUnknownFormat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "volt-test-logger-")
+	if err != nil {
+		t.Fatal("failed to create temp dir: " + err.Error())
+	}
+	defer os.RemoveAll(dir)
+	if err := OpenFile(filepath.Join(dir, "volt.log"), "xml"); err == nil {
+		Close()
+		t.Error("expected an error but got nil")
+	}
+}
+
+func TestNew(t *testing.T) {
+	defer SetLevel(logLevel)
+	SetLevel(InfoLevel)
+
+	var buf bytes.Buffer
+	log := New(&buf).With("repos", "github.com/tyru/caw.vim")
+	log.Info("info message")
+	log.Debug("debug message")
+	log.Error("error message")
+
+	expected := "[INFO] info message\n[ERROR] error message\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q but got %q", expected, buf.String())
+	}
+}
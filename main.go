@@ -12,7 +12,11 @@ import (
 func main() {
 	err := subcmd.Run(os.Args, subcmd.DefaultRunner)
 	if err != nil {
-		logger.Error(err.Msg)
+		if err.Msg != "" {
+			logger.Error(err.Msg)
+		}
+		logger.Close()
 		os.Exit(err.Code)
 	}
+	logger.Close()
 }
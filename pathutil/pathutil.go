@@ -79,6 +79,19 @@ func (path ReposPath) FullPath() string {
 	return filepath.Join(paths...)
 }
 
+// SystemFullPath returns the directory of ReposPath found first in dirs
+// (system repository search path). Returns "" if it is not found.
+func (path ReposPath) SystemFullPath(dirs []string) string {
+	reposList := strings.Split(filepath.ToSlash(path.String()), "/")
+	for _, dir := range dirs {
+		fullpath := filepath.Join(append([]string{dir}, reposList...)...)
+		if fi, err := os.Stat(fullpath); err == nil && fi.IsDir() {
+			return fullpath
+		}
+	}
+	return ""
+}
+
 // CloneURL returns string "https://{reposPath}".
 func (path ReposPath) CloneURL() string {
 	return "https://" + filepath.ToSlash(path.String())
@@ -188,16 +201,77 @@ func LockJSON() string {
 	return filepath.Join(VoltPath(), "lock.json")
 }
 
+// LockJSONPrev returns fullpath of "$HOME/volt/lock.json.prev".
+func LockJSONPrev() string {
+	return filepath.Join(VoltPath(), "lock.json.prev")
+}
+
 // ConfigTOML returns fullpath of "$HOME/volt/config.toml".
 func ConfigTOML() string {
 	return filepath.Join(VoltPath(), "config.toml")
 }
 
+// SystemConfigTOML returns fullpath of system config file, which is
+// "$VOLT_SYSTEM_CONFIG" if it is set, otherwise:
+//   Windows  : %ProgramData%/volt/config.toml
+//   Otherwise: /etc/volt/config.toml
+func SystemConfigTOML() string {
+	if path := os.Getenv("VOLT_SYSTEM_CONFIG"); path != "" {
+		return path
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("ProgramData"), "volt", "config.toml")
+	}
+	return "/etc/volt/config.toml"
+}
+
+// ProjectConfigTOMLName is the basename of a project-local config file,
+// which is looked up from current directory to its parents.
+const ProjectConfigTOMLName = ".volt.toml"
+
+// LookUpProjectConfigTOML returns fullpath of ".volt.toml" in current
+// directory or the nearest parent directory.
+// If it is not found, it returns empty string.
+func LookUpProjectConfigTOML() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		// Directories which cannot be read (e.g. other user's home) are
+		// skipped
+		path := filepath.Join(dir, ProjectConfigTOMLName)
+		if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
 // TrxDir returns fullpath of "$HOME/volt/trx".
 func TrxDir() string {
 	return filepath.Join(VoltPath(), "trx")
 }
 
+// BinDir returns fullpath of "$HOME/volt/bin".
+func BinDir() string {
+	return filepath.Join(VoltPath(), "bin")
+}
+
+// StoreDir returns fullpath of "$HOME/volt/store".
+func StoreDir() string {
+	return filepath.Join(VoltPath(), "store")
+}
+
+// CacheDir returns fullpath of "$HOME/volt/cache".
+func CacheDir() string {
+	return filepath.Join(VoltPath(), "cache")
+}
+
 // TempDir returns fullpath of "$HOME/tmp".
 func TempDir() string {
 	return filepath.Join(VoltPath(), "tmp")
@@ -218,10 +292,17 @@ func VimExecutable() (string, error) {
 	return exec.LookPath(exeName)
 }
 
+// vimDir is the directory set by SetVimDir().
+var vimDir string
+
 // VimDir returns the following fullpath:
 //   Windows: $HOME/vimfiles
 //   Other: $HOME/.vim
+// If SetVimDir() was called with non-empty string, returns it instead.
 func VimDir() string {
+	if vimDir != "" {
+		return vimDir
+	}
 	vimdir := ".vim"
 	if runtime.GOOS == "windows" {
 		vimdir = "vimfiles"
@@ -229,19 +310,72 @@ func VimDir() string {
 	return filepath.Join(HomeDir(), vimdir)
 }
 
+// SetVimDir changes the directory returned by VimDir() and the directories
+// under it (VimVoltDir(), VimVoltProfileDir(), ...) to dir.
+// This is used by "volt run" to build into a temporary directory instead of
+// (vim dir). If dir is empty, VimDir() returns (vim dir) again.
+func SetVimDir(dir string) {
+	vimDir = dir
+}
+
+// vimVoltDir is the directory set by SetVimVoltDir().
+var vimVoltDir string
+
 // VimVoltDir returns "(vim dir)/pack/volt".
+// If SetVimVoltDir() was called with non-empty string, returns it instead.
 func VimVoltDir() string {
+	if vimVoltDir != "" {
+		return vimVoltDir
+	}
 	return filepath.Join(VimDir(), "pack", "volt")
 }
 
+// SetVimVoltDir changes the directory returned by VimVoltDir() and its
+// subdirectories (VimVoltOptDir(), BuildInfoJSON(), ...) to dir.
+// This is used by "volt build" to build into a staging directory.
+// If dir is empty, VimVoltDir() returns "(vim dir)/pack/volt" again.
+func SetVimVoltDir(dir string) {
+	vimVoltDir = dir
+}
+
 // VimVoltOptDir returns "(vim dir)/pack/volt/opt".
 func VimVoltOptDir() string {
-	return filepath.Join(VimDir(), "pack", "volt", "opt")
+	return filepath.Join(VimVoltDir(), "opt")
 }
 
 // VimVoltStartDir returns "(vim dir)/pack/volt/start".
 func VimVoltStartDir() string {
-	return filepath.Join(VimDir(), "pack", "volt", "start")
+	return filepath.Join(VimVoltDir(), "start")
+}
+
+// VimVoltStagingDir returns "(vim dir)/pack/.volt-staging".
+// Vim does not load packages in it because the name starts with ".".
+func VimVoltStagingDir() string {
+	return filepath.Join(VimDir(), "pack", ".volt-staging")
+}
+
+// VimVoltPrevDir returns "(vim dir)/pack/.volt-prev", which is a symlink to
+// the previous build.
+func VimVoltPrevDir() string {
+	return filepath.Join(VimDir(), "pack", ".volt-prev")
+}
+
+// VimVoltBuildsDir returns "(vim dir)/pack/.volt-builds", which has the
+// current build and the previous build. "(vim dir)/pack/volt" is a symlink
+// to the current build.
+func VimVoltBuildsDir() string {
+	return filepath.Join(VimDir(), "pack", ".volt-builds")
+}
+
+// VimVoltProfileDir returns "(vim dir)/pack/volt-{profileName}".
+func VimVoltProfileDir(profileName string) string {
+	return filepath.Join(VimDir(), "pack", "volt-"+profileName)
+}
+
+// VimVoltProfileDirs returns existing "(vim dir)/pack/volt-{profileName}"
+// directories.
+func VimVoltProfileDirs() ([]string, error) {
+	return filepath.Glob(filepath.Join(VimDir(), "pack", "volt-*"))
 }
 
 // BuildInfoJSON returns "(vim dir)/pack/volt/build-info.json".
@@ -254,6 +388,15 @@ func BundledPlugConf() string {
 	return filepath.Join(VimVoltStartDir(), "system", "plugin", "bundled_plugconf.vim")
 }
 
+// ProfileBundledPlugConf returns "(vim dir)/pack/volt-{profileName}/bundled_plugconf.vim".
+func ProfileBundledPlugConf(profileName string) string {
+	return filepath.Join(VimVoltProfileDir(profileName), "bundled_plugconf.vim")
+}
+
+// VoltProfileFile is the basename of a file which selects a profile of the
+// directory and its subdirectories.
+const VoltProfileFile = ".volt-profile"
+
 // LookUpVimrc looks up vimrc path from the following candidates:
 //   Windows  : $HOME/_vimrc
 //              (vim dir)/vimrc
@@ -0,0 +1,176 @@
+package volt
+
+import (
+	"context"
+
+	"github.com/vim-volt/volt/internal/builder"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/transaction"
+)
+
+// BuildOptions is the options of Build().
+type BuildOptions struct {
+	// Full rebuilds all repositories ignoring the previous build
+	// ("volt build -full")
+	Full bool
+	// DryRun returns the plan without changing any files
+	// ("volt build -dry-run")
+	DryRun bool
+	// Rollback restores the previous build instead of building
+	// ("volt build -rollback")
+	Rollback bool
+	// Logger receives progress messages. If nil, nothing is logged
+	Logger *logger.Logger
+}
+
+// BuildResult is the plan of Build(), and whether it was applied.
+type BuildResult struct {
+	// Strategy is build.strategy of config.toml
+	Strategy string
+	// Full is true if all repositories are rebuilt (e.g. BuildOptions.Full
+	// is true, or build.strategy was changed)
+	Full bool
+	// Repos are changes of ~/.vim/pack/volt/opt/{repos}
+	Repos []BuildChange
+	// RCFiles are changes of vimrc and gvimrc
+	RCFiles []BuildChange
+	// Plugconfs are changes of bundled plugconf files
+	Plugconfs []BuildChange
+	// Applied is true if ~/.vim/pack/volt was switched to the new build.
+	// If the build failed, ~/.vim/pack/volt is left unchanged (the new
+	// build is discarded), and Applied is false.
+	Applied bool
+	// RolledBack is true if ~/.vim/pack/volt was switched to the previous
+	// build by BuildOptions.Rollback. The plan is empty in that case.
+	RolledBack bool
+}
+
+// BuildChange is a change of a file or a directory by Build().
+type BuildChange struct {
+	// Action is "add", "update", "remove", or "refuse" (a file which is not
+	// generated by volt is not overwritten)
+	Action string
+	Path   string
+	// Reason describes why the repository is added or updated
+	Reason string
+	// Diff is the unified diff of a bundled plugconf file
+	Diff string
+	// Err is the reason why the file cannot be installed
+	Err error
+}
+
+// BuildStep is a step of Build().
+type BuildStep string
+
+const (
+	// BuildStepBeginTransaction begins the transaction
+	BuildStepBeginTransaction BuildStep = "begin transaction"
+	// BuildStepPlan makes the build plan of BuildOptions.DryRun
+	BuildStepPlan BuildStep = "make build plan"
+	// BuildStepBuild makes the build plan and applies it
+	BuildStepBuild BuildStep = "build"
+	// BuildStepRollback restores the previous build
+	BuildStepRollback BuildStep = "rollback"
+	// BuildStepEndTransaction ends the transaction
+	BuildStepEndTransaction BuildStep = "end transaction"
+)
+
+// BuildError is the error of Build() with the step where it failed.
+type BuildError struct {
+	Step BuildStep
+	Err  error
+}
+
+func (e *BuildError) Error() string {
+	return "failed to " + string(e.Step) + ": " + e.Err.Error()
+}
+
+// Cause returns the underlying error (see github.com/pkg/errors.Cause()).
+func (e *BuildError) Cause() error {
+	return e.Err
+}
+
+// Build creates or updates ~/.vim/pack/volt from lock.json, and returns what
+// was changed. The result is not nil even if it fails, and tells whether
+// ~/.vim/pack/volt was changed (see BuildResult.Applied and
+// BuildResult.RolledBack). The error is *BuildError unless ctx is canceled.
+func Build(ctx context.Context, opts BuildOptions) (_ *BuildResult, result error) {
+	res := &BuildResult{}
+	if err := checkContext(ctx, "build"); err != nil {
+		return res, err
+	}
+	log := newLogger(opts.Logger)
+
+	if opts.DryRun {
+		plan, err := builder.MakePlan(opts.Full, log)
+		if err != nil {
+			return res, &BuildError{Step: BuildStepPlan, Err: err}
+		}
+		res.setPlan(plan)
+		return res, nil
+	}
+
+	// Begin transaction
+	trx, err := transaction.Start()
+	if err != nil {
+		return res, &BuildError{Step: BuildStepBeginTransaction, Err: err}
+	}
+	defer func() {
+		if err := trx.Done(); err != nil {
+			result = &BuildError{Step: BuildStepEndTransaction, Err: err}
+		}
+	}()
+
+	if opts.Rollback {
+		if err := builder.Rollback(log); err != nil {
+			return res, &BuildError{Step: BuildStepRollback, Err: err}
+		}
+		res.RolledBack = true
+		return res, nil
+	}
+
+	plan, err := builder.MakePlan(opts.Full, log)
+	if err != nil {
+		return res, &BuildError{Step: BuildStepBuild, Err: err}
+	}
+	res.setPlan(plan)
+	if err := plan.Apply(); err != nil {
+		return res, &BuildError{Step: BuildStepBuild, Err: err}
+	}
+	res.Applied = true
+	return res, nil
+}
+
+// setPlan sets the changes of plan to result.
+func (result *BuildResult) setPlan(plan *builder.Plan) {
+	result.Strategy = plan.Strategy
+	result.Full = plan.Full
+	result.Repos = make([]BuildChange, 0, len(plan.Repos))
+	result.RCFiles = make([]BuildChange, 0, len(plan.RCFiles))
+	result.Plugconfs = make([]BuildChange, 0, len(plan.Plugconfs))
+	for i := range plan.Repos {
+		change := &plan.Repos[i]
+		result.Repos = append(result.Repos, BuildChange{
+			Action: string(change.Action),
+			Path:   change.Path.String(),
+			Reason: change.Reason,
+			Err:    change.Err,
+		})
+	}
+	for i := range plan.RCFiles {
+		change := &plan.RCFiles[i]
+		result.RCFiles = append(result.RCFiles, BuildChange{
+			Action: string(change.Action),
+			Path:   change.Path,
+			Err:    change.Err,
+		})
+	}
+	for i := range plan.Plugconfs {
+		change := &plan.Plugconfs[i]
+		result.Plugconfs = append(result.Plugconfs, BuildChange{
+			Action: string(change.Action),
+			Path:   change.Path,
+			Diff:   change.Diff,
+		})
+	}
+}
@@ -0,0 +1,551 @@
+package volt
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/fileutil"
+	"github.com/vim-volt/volt/gitutil"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/plugconf"
+	"github.com/vim-volt/volt/transaction"
+
+	multierror "github.com/hashicorp/go-multierror"
+)
+
+// InstallOptions is the options of Install().
+type InstallOptions struct {
+	// Repos are repositories to install (e.g. "tyru/caw.vim",
+	// "github.com/tyru/caw.vim").
+	// Existing repositories and system repositories are added to lock.json
+	// without cloning.
+	Repos []string
+	// CurrentProfile uses all repositories of current profile instead of
+	// Repos, to install missing ones ("volt get -l")
+	CurrentProfile bool
+	// Logger receives progress messages. If nil, nothing is logged
+	Logger *logger.Logger
+}
+
+// UpgradeOptions is the options of Upgrade().
+type UpgradeOptions struct {
+	// Repos are repositories to upgrade. Repositories which are not
+	// installed are installed
+	Repos []string
+	// CurrentProfile uses all repositories of current profile instead of
+	// Repos ("volt get -l -u")
+	CurrentProfile bool
+	// Logger receives progress messages. If nil, nothing is logged
+	Logger *logger.Logger
+}
+
+// ReposStatus is what Install() or Upgrade() did to a repository.
+type ReposStatus string
+
+const (
+	// StatusInstallFailed means installing the repository failed
+	StatusInstallFailed ReposStatus = "install failed"
+	// StatusUpgradeFailed means upgrading the repository failed
+	StatusUpgradeFailed ReposStatus = "upgrade failed"
+	// StatusNoChange means the repository is already up to date
+	StatusNoChange ReposStatus = "no change"
+	// StatusAlreadyExists means the repository exists and is already in
+	// current profile
+	StatusAlreadyExists ReposStatus = "already exists"
+	// StatusAddedToProfile means the existing repository was added to
+	// current profile
+	StatusAddedToProfile ReposStatus = "added repository to current profile"
+	// StatusInstalled means the repository was cloned
+	StatusInstalled ReposStatus = "installed"
+	// StatusAddedSystemRepos means the system repository was added to
+	// lock.json (see GetResult.SystemPath)
+	StatusAddedSystemRepos ReposStatus = "added system repository"
+	// StatusRevisionUpdated means the locked revision in lock.json was
+	// updated to HEAD
+	StatusRevisionUpdated ReposStatus = "updated lock.json revision"
+	// StatusUpgraded means new commits were fetched and merged
+	StatusUpgraded ReposStatus = "upgraded"
+	// StatusFetched means new objects were fetched but HEAD was not changed
+	// (e.g. bare repository)
+	StatusFetched ReposStatus = "fetched objects"
+)
+
+// GetResult is the result of a repository of Install() or Upgrade().
+type GetResult struct {
+	Path   pathutil.ReposPath
+	Type   lockjson.ReposType
+	Status ReposStatus
+	// From and To are the revisions before and after StatusUpgraded or
+	// StatusRevisionUpdated. To is the current revision of other statuses.
+	From string
+	To   string
+	// SystemPath is the directory of StatusAddedSystemRepos
+	SystemPath string
+	// PlugconfTemplate is where the plugconf was created from. It is nil if
+	// plugconf was not created or created from skeleton
+	PlugconfTemplate *lockjson.PlugconfTemplate
+	// Err is non-nil if Status is StatusInstallFailed or StatusUpgradeFailed
+	Err error
+}
+
+// Install installs repositories, adds them to current profile, and builds
+// ~/.vim/pack/volt. Plugconf files are created from the templates of
+// plugconf.template_sources if get.create_skeleton_plugconf is true.
+//
+// The results are sorted by repository path. If some of repositories
+// failed, it returns the results with an error.
+// If ctx is canceled, repositories which have not been started are failed,
+// and ~/.vim/pack/volt is not built.
+func Install(ctx context.Context, opts InstallOptions) ([]GetResult, error) {
+	return get(ctx, opts.Repos, opts.CurrentProfile, false, newLogger(opts.Logger))
+}
+
+// Upgrade upgrades repositories (and installs missing ones), updates their
+// locked revisions, and builds ~/.vim/pack/volt. Static repositories are
+// not upgraded, and only locked revisions of system repositories are
+// updated. See Install() for the results.
+func Upgrade(ctx context.Context, opts UpgradeOptions) ([]GetResult, error) {
+	return get(ctx, opts.Repos, opts.CurrentProfile, true, newLogger(opts.Logger))
+}
+
+func get(ctx context.Context, repos []string, currentProfile, upgrade bool, log *logger.Logger) (_ []GetResult, result error) {
+	// Read lock.json
+	lockJSON, err := lockjson.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read lock.json")
+	}
+	reposPathList, err := getReposPathList(lockJSON, repos, currentProfile)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get repos list")
+	}
+	if len(reposPathList) == 0 {
+		return nil, errors.New("no repositories are specified")
+	}
+
+	// Find matching profile
+	profile, err := lockJSON.Profiles.FindByName(lockJSON.CurrentProfileName)
+	if err != nil {
+		// this must not be occurred because lockjson.Read()
+		// validates if the matching profile exists
+		return nil, err
+	}
+
+	// Read config.toml
+	cfg, err := config.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read config.toml")
+	}
+	git, err := gitutil.NewBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+	g := &getter{
+		ctx:       ctx,
+		upgrade:   upgrade,
+		cfg:       cfg,
+		git:       git,
+		log:       log,
+		templates: plugconf.NewTemplateFetcher(cfg.Plugconf.TemplateSources, git),
+	}
+
+	// Begin transaction
+	trx, err := transaction.Start()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := trx.Done(); err != nil {
+			result = err
+		}
+	}()
+
+	done := make(chan GetResult, len(reposPathList))
+	getCount := 0
+	// Invoke installing / upgrading tasks
+	for _, reposPath := range reposPathList {
+		repos := lockJSON.Repos.FindByPath(reposPath)
+		if repos == nil || repos.Type == lockjson.ReposGitType || repos.Type == lockjson.ReposSystemType {
+			go g.getParallel(reposPath, repos, done)
+			getCount++
+		}
+	}
+
+	// Wait results
+	failed := false
+	results := make([]GetResult, 0, getCount)
+	var updatedLockJSON bool
+	for i := 0; i < getCount; i++ {
+		r := <-done
+		// Update repos[]/version
+		if r.Err != nil {
+			failed = true
+		} else {
+			added := updateReposVersion(lockJSON, &r, profile)
+			if added && r.Status == StatusAlreadyExists {
+				r.Status = StatusAddedToProfile
+			}
+			updatedLockJSON = true
+		}
+		results = append(results, r)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Path < results[j].Path
+	})
+
+	if updatedLockJSON {
+		// Write to lock.json
+		err = lockJSON.Write()
+		if err != nil {
+			return results, errors.Wrap(err, "could not write to lock.json")
+		}
+	}
+
+	if err := checkContext(ctx, "build"); err != nil {
+		return results, err
+	}
+
+	// Build ~/.vim/pack/volt dir
+	err = build(false, log)
+	if err != nil {
+		return results, errors.Wrap(err, "could not build "+pathutil.VimVoltDir())
+	}
+
+	if failed {
+		if upgrade {
+			return results, errors.New("failed to upgrade some plugins")
+		}
+		return results, errors.New("failed to install some plugins")
+	}
+	return results, nil
+}
+
+func getReposPathList(lockJSON *lockjson.LockJSON, repos []string, currentProfile bool) ([]pathutil.ReposPath, error) {
+	if !currentProfile {
+		return normalizeReposList(lockJSON, repos)
+	}
+	reposList, err := lockJSON.GetCurrentReposList()
+	if err != nil {
+		return nil, err
+	}
+	reposPathList := make([]pathutil.ReposPath, 0, len(reposList))
+	for i := range reposList {
+		reposPathList = append(reposPathList, reposList[i].Path)
+	}
+	return reposPathList, nil
+}
+
+// getter installs or upgrades each repository in goroutines.
+type getter struct {
+	ctx       context.Context
+	upgrade   bool
+	cfg       *config.Config
+	git       gitutil.Backend
+	log       *logger.Logger
+	templates *plugconf.TemplateFetcher
+}
+
+// This function is executed in goroutine of each plugin.
+// 1. install plugin if it does not exist
+// 2. install plugconf if it does not exist and createPlugconf=true
+func (g *getter) getParallel(reposPath pathutil.ReposPath, repos *lockjson.Repos, done chan<- GetResult) {
+	if err := checkContext(g.ctx, "install"); err != nil {
+		done <- GetResult{Path: reposPath, Status: StatusInstallFailed, Err: err}
+		return
+	}
+	pluginResult := g.installPlugin(reposPath, repos)
+	if pluginResult.Err != nil || !*g.cfg.Get.CreateSkeletonPlugconf {
+		done <- pluginResult
+		return
+	}
+	var recorded *lockjson.PlugconfTemplate
+	if repos != nil {
+		recorded = repos.PlugconfTemplate
+	}
+	done <- g.installPlugconf(reposPath, recorded, pluginResult)
+}
+
+func (g *getter) installPlugin(reposPath pathutil.ReposPath, repos *lockjson.Repos) GetResult {
+	if repos != nil && repos.Type == lockjson.ReposSystemType ||
+		repos == nil && !pathutil.Exists(reposPath.FullPath()) && reposPath.SystemFullPath(g.cfg.System.ReposPath) != "" {
+		return g.installSystemPlugin(reposPath, repos)
+	}
+
+	log := g.log.With("repos", reposPath)
+
+	// true:upgrade, false:install
+	fullReposPath := reposPath.FullPath()
+	doInstall := !pathutil.Exists(fullReposPath)
+	doUpgrade := g.upgrade && !doInstall
+
+	var fromHash string
+	var err error
+	if doUpgrade {
+		// Get HEAD hash string
+		fromHash, err = gitutil.GetHEAD(g.git, fullReposPath)
+		if err != nil {
+			return GetResult{
+				Path:   reposPath,
+				Status: StatusInstallFailed,
+				Err:    errors.Wrap(err, "failed to get HEAD commit hash"),
+			}
+		}
+	}
+
+	var status ReposStatus
+	var upgraded bool
+	var checkRevision bool
+
+	if doUpgrade {
+		// when g.upgrade is true, repos must not be nil.
+		if repos == nil {
+			return GetResult{
+				Path:   reposPath,
+				Status: StatusUpgradeFailed,
+				Err:    errors.New("failed to upgrade plugin: -u was specified but repos == nil"),
+			}
+		}
+		// Upgrade plugin
+		log.Debug("Upgrading " + reposPath + " ...")
+		err := g.upgradePlugin(reposPath)
+		if err != gitutil.ErrAlreadyUpToDate && err != nil {
+			return GetResult{
+				Path:   reposPath,
+				Status: StatusUpgradeFailed,
+				Err:    errors.Wrap(err, "failed to upgrade plugin"),
+			}
+		}
+		if err == gitutil.ErrAlreadyUpToDate {
+			status = StatusNoChange
+		} else {
+			upgraded = true
+		}
+	} else if doInstall {
+		// Install plugin
+		log.Debug("Installing " + reposPath + " ...")
+		err := g.clonePlugin(reposPath)
+		if err != nil {
+			result := errors.Wrap(err, "failed to install plugin")
+			log.Debug("Rollbacking " + fullReposPath + " ...")
+			err = removeDir(fullReposPath)
+			if err != nil {
+				result = multierror.Append(result, err)
+			}
+			return GetResult{Path: reposPath, Status: StatusInstallFailed, Err: result}
+		}
+		status = StatusInstalled
+	} else {
+		status = StatusAlreadyExists
+		checkRevision = true
+	}
+
+	var toHash string
+	reposType, err := g.detectReposType(fullReposPath)
+	if err == nil && reposType == lockjson.ReposGitType {
+		// Get HEAD hash string
+		toHash, err = gitutil.GetHEAD(g.git, fullReposPath)
+		if err != nil {
+			result := errors.Wrap(err, "failed to get HEAD commit hash")
+			if doInstall {
+				log.Debug("Rollbacking " + fullReposPath + " ...")
+				err = removeDir(fullReposPath)
+				if err != nil {
+					result = multierror.Append(result, err)
+				}
+			}
+			return GetResult{Path: reposPath, Status: StatusInstallFailed, Err: result}
+		}
+	}
+
+	if upgraded {
+		if fromHash != toHash {
+			status = StatusUpgraded
+			g.logCommits(reposPath, fromHash, toHash)
+		} else {
+			status = StatusFetched
+		}
+	}
+
+	if checkRevision && repos != nil && repos.Version != toHash {
+		status = StatusRevisionUpdated
+		fromHash = repos.Version
+	}
+
+	return GetResult{
+		Path:   reposPath,
+		Type:   reposType,
+		Status: status,
+		From:   fromHash,
+		To:     toHash,
+	}
+}
+
+// installSystemPlugin adds the repository found in system.repos_path to
+// lock.json. System repositories are read-only, so they are never cloned nor
+// upgraded, but the locked revision is updated to their HEAD.
+func (g *getter) installSystemPlugin(reposPath pathutil.ReposPath, repos *lockjson.Repos) GetResult {
+	fullpath := reposPath.SystemFullPath(g.cfg.System.ReposPath)
+	if fullpath == "" {
+		return GetResult{
+			Path:   reposPath,
+			Status: StatusInstallFailed,
+			Err:    errors.New("system repository is not found in system.repos_path"),
+		}
+	}
+
+	var hash string
+	if pathutil.Exists(filepath.Join(fullpath, ".git")) {
+		var err error
+		hash, err = gitutil.GetHEAD(g.git, fullpath)
+		if err != nil {
+			return GetResult{
+				Path:   reposPath,
+				Status: StatusInstallFailed,
+				Err:    errors.Wrap(err, "failed to get HEAD commit hash"),
+			}
+		}
+	}
+
+	result := GetResult{
+		Path:       reposPath,
+		Type:       lockjson.ReposSystemType,
+		To:         hash,
+		SystemPath: fullpath,
+	}
+	switch {
+	case repos == nil:
+		result.Status = StatusAddedSystemRepos
+	case repos.Version != hash:
+		result.Status = StatusRevisionUpdated
+		result.From = repos.Version
+	case g.upgrade:
+		result.Status = StatusNoChange
+	default:
+		result.Status = StatusAlreadyExists
+	}
+	return result
+}
+
+func (g *getter) installPlugconf(reposPath pathutil.ReposPath, recorded *lockjson.PlugconfTemplate, pluginResult GetResult) GetResult {
+	// Install plugconf
+	g.log.With("repos", reposPath).Debug("Installing plugconf " + reposPath + " ...")
+	tmplSource, err := g.templates.Install(reposPath, recorded)
+	if err != nil {
+		// TODO: Remove the repository only when the repos *did not* exist
+		// previously and was installed newly.
+		return GetResult{
+			Path:   reposPath,
+			Status: StatusInstallFailed,
+			Err:    errors.Wrap(err, "failed to install plugconf"),
+		}
+	}
+	pluginResult.PlugconfTemplate = tmplSource
+	return pluginResult
+}
+
+func (g *getter) detectReposType(fullpath string) (lockjson.ReposType, error) {
+	if pathutil.Exists(filepath.Join(fullpath, ".git")) {
+		if _, err := g.git.IsBare(fullpath); err != nil {
+			return "", err
+		}
+		return lockjson.ReposGitType, nil
+	}
+	return lockjson.ReposStaticType, nil
+}
+
+func removeDir(fullReposPath string) error {
+	if pathutil.Exists(fullReposPath) {
+		err := os.RemoveAll(fullReposPath)
+		if err != nil {
+			return errors.Errorf("rollback failed: cannot remove '%s'", fullReposPath)
+		}
+		// Remove parent directories
+		fileutil.RemoveDirs(filepath.Dir(fullReposPath))
+	}
+	return nil
+}
+
+func (g *getter) upgradePlugin(reposPath pathutil.ReposPath) error {
+	fullpath := reposPath.FullPath()
+	isBare, err := g.git.IsBare(fullpath)
+	if err != nil {
+		return err
+	}
+	if isBare {
+		return g.git.Fetch(fullpath)
+	}
+	return g.git.FastForward(fullpath)
+}
+
+// logCommits shows commits from..to for debugging.
+func (g *getter) logCommits(reposPath pathutil.ReposPath, from, to string) {
+	log := g.log.With("repos", reposPath)
+	commits, err := g.git.Log(reposPath.FullPath(), from, to)
+	if err != nil {
+		log.Debug("failed to get commits: " + err.Error())
+		return
+	}
+	for _, c := range commits {
+		log.Debugf("  %s %s", c.Hash[:7], strings.SplitN(c.Message, "\n", 2)[0])
+	}
+}
+
+var errRepoExists = errors.New("repository exists")
+
+func (g *getter) clonePlugin(reposPath pathutil.ReposPath) error {
+	fullpath := reposPath.FullPath()
+	if pathutil.Exists(fullpath) {
+		return errRepoExists
+	}
+
+	err := os.MkdirAll(filepath.Dir(fullpath), 0755)
+	if err != nil {
+		return err
+	}
+
+	// Clone repository to $VOLTPATH/repos/{site}/{user}/{name}
+	return g.git.Clone(reposPath.CloneURL(), fullpath)
+}
+
+// * Add repos to 'repos' if not found
+// * Add repos to 'profiles[]/repos_path' if not found
+func updateReposVersion(lockJSON *lockjson.LockJSON, r *GetResult, profile *lockjson.Profile) bool {
+	repos := lockJSON.Repos.FindByPath(r.Path)
+
+	added := false
+
+	if repos == nil {
+		// repos is not found in lock.json
+		// -> previous operation is install
+		repos = &lockjson.Repos{
+			Type:             r.Type,
+			Path:             r.Path,
+			Version:          r.To,
+			PlugconfTemplate: r.PlugconfTemplate,
+		}
+		// Add repos to 'repos'
+		lockJSON.Repos = append(lockJSON.Repos, *repos)
+		added = true
+	} else {
+		// repos is found in lock.json
+		// -> previous operation is upgrade
+		repos.Version = r.To
+		if r.PlugconfTemplate != nil {
+			repos.PlugconfTemplate = r.PlugconfTemplate
+		}
+	}
+
+	if !profile.ReposPath.Contains(r.Path) {
+		// Add repos to 'profiles[]/repos_path'
+		profile.ReposPath = append(profile.ReposPath, r.Path)
+		added = true
+	}
+	return added
+}
@@ -0,0 +1,86 @@
+package volt
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// ListOptions is the options of List().
+type ListOptions struct {
+	// Profile is the profile name to list. If empty, current profile is used
+	Profile string
+}
+
+// ListResult is the result of List().
+type ListResult struct {
+	// Profile is the listed profile name
+	Profile string
+	// Current is true if Profile is current profile
+	Current bool
+	// Extends are the profile names which Profile extends
+	Extends []string
+	// Repos are the repositories of Profile, including the inherited ones
+	Repos []ListedRepos
+}
+
+// ListedRepos is a repository of ListResult.
+type ListedRepos struct {
+	Path pathutil.ReposPath
+	Type lockjson.ReposType
+	// Version is the locked revision (empty for static repositories)
+	Version string
+	// InheritedFrom is the profile name which has the repository, if it is
+	// inherited from extended profiles
+	InheritedFrom string
+}
+
+// List returns the repositories of a profile ("volt list").
+func List(ctx context.Context, opts ListOptions) (*ListResult, error) {
+	if err := checkContext(ctx, "list"); err != nil {
+		return nil, err
+	}
+
+	// Read lock.json
+	lockJSON, err := lockjson.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read lock.json")
+	}
+	name := opts.Profile
+	if name == "" {
+		name = lockJSON.CurrentProfileName
+	}
+	profile, err := lockJSON.Profiles.FindByName(name)
+	if err != nil {
+		return nil, err
+	}
+	inherited, err := lockJSON.GetInheritedReposPath(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ListResult{
+		Profile: profile.Name,
+		Current: profile.Name == lockJSON.CurrentProfileName,
+		Extends: append([]string{}, profile.Extends...),
+		Repos:   make([]ListedRepos, 0, len(profile.ReposPath)+len(inherited)),
+	}
+	add := func(reposPath pathutil.ReposPath, from string) {
+		listed := ListedRepos{Path: reposPath, InheritedFrom: from}
+		if repos := lockJSON.Repos.FindByPath(reposPath); repos != nil {
+			listed.Type = repos.Type
+			listed.Version = repos.Version
+		}
+		result.Repos = append(result.Repos, listed)
+	}
+	for _, reposPath := range profile.ReposPath {
+		add(reposPath, "")
+	}
+	for i := range inherited {
+		add(inherited[i].Path, inherited[i].Profile)
+	}
+	return result, nil
+}
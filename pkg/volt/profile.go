@@ -0,0 +1,98 @@
+package volt
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/transaction"
+)
+
+// SetProfileOptions is the options of SetProfile().
+type SetProfileOptions struct {
+	// Name is the profile name to be current profile
+	Name string
+	// Create creates the profile if it does not exist ("volt profile set -n")
+	Create bool
+	// Logger receives progress messages. If nil, nothing is logged
+	Logger *logger.Logger
+}
+
+// SetProfileResult is the result of SetProfile().
+type SetProfileResult struct {
+	// Previous is the previous current profile name
+	Previous string
+	// Created is true if the profile was created
+	Created bool
+}
+
+// SetProfile changes current profile, and builds ~/.vim/pack/volt.
+// It fails if the profile is already current profile.
+func SetProfile(ctx context.Context, opts SetProfileOptions) (_ *SetProfileResult, result error) {
+	if err := checkContext(ctx, "set profile"); err != nil {
+		return nil, err
+	}
+	if opts.Name == "" {
+		return nil, errors.New("profile name is empty")
+	}
+
+	// Read lock.json
+	lockJSON, err := lockjson.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read lock.json")
+	}
+
+	// Exit if current profile is same as opts.Name
+	if lockJSON.CurrentProfileName == opts.Name {
+		return nil, errors.Errorf("'%s' is current profile", opts.Name)
+	}
+
+	// Create given profile unless the profile exists
+	res := &SetProfileResult{Previous: lockJSON.CurrentProfileName}
+	if _, err := lockJSON.Profiles.FindByName(opts.Name); err != nil {
+		if !opts.Create {
+			return nil, err
+		}
+		lockJSON.Profiles = append(lockJSON.Profiles, lockjson.Profile{
+			Name:      opts.Name,
+			ReposPath: make([]pathutil.ReposPath, 0),
+		})
+		res.Created = true
+	}
+
+	// Begin transaction
+	trx, err := transaction.Start()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := trx.Done(); err != nil {
+			result = err
+		}
+	}()
+
+	// Set profile name
+	lockJSON.CurrentProfileName = opts.Name
+
+	// Write to lock.json
+	err = lockJSON.Write()
+	if err != nil {
+		return nil, err
+	}
+
+	log := newLogger(opts.Logger)
+	if res.Created {
+		log.Info("Created new profile '" + opts.Name + "'")
+	}
+	log.Info("Changed current profile: " + opts.Name)
+
+	// Build ~/.vim/pack/volt dir
+	err = build(false, log)
+	if err != nil {
+		return res, errors.Wrap(err, "could not build "+pathutil.VimVoltDir())
+	}
+	return res, nil
+}
@@ -0,0 +1,179 @@
+package volt
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/fileutil"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/plugconf"
+	"github.com/vim-volt/volt/transaction"
+)
+
+// RemoveOptions is the options of Remove().
+type RemoveOptions struct {
+	// Repos are repositories to remove from lock.json
+	Repos []string
+	// RemoveRepos also removes repository directories ("volt rm -r").
+	// System repositories cannot be removed
+	RemoveRepos bool
+	// RemovePlugconf also removes plugconf files ("volt rm -p")
+	RemovePlugconf bool
+	// Logger receives progress messages. If nil, nothing is logged
+	Logger *logger.Logger
+}
+
+// RemoveResult is the result of a repository of Remove().
+type RemoveResult struct {
+	Path pathutil.ReposPath
+	// RemovedRepos is true if the repository directory was removed
+	RemovedRepos bool
+	// RemovedPlugconf is true if the plugconf file was removed
+	RemovedPlugconf bool
+	// RemovedFromLockJSON is true if the repository was removed from
+	// lock.json
+	RemovedFromLockJSON bool
+}
+
+// Remove removes repositories from lock.json (and their directories and
+// plugconf files by opts), and builds ~/.vim/pack/volt.
+// It fails without changing anything if some of repositories are depended
+// by other repositories, or nothing is removed.
+func Remove(ctx context.Context, opts RemoveOptions) (_ []RemoveResult, result error) {
+	if err := checkContext(ctx, "remove"); err != nil {
+		return nil, err
+	}
+	if len(opts.Repos) == 0 {
+		return nil, errors.New("no repositories are specified")
+	}
+	log := newLogger(opts.Logger)
+
+	// Read lock.json
+	lockJSON, err := lockjson.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read lock.json")
+	}
+
+	// Begin transaction
+	trx, err := transaction.Start()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := trx.Done(); err != nil {
+			result = err
+		}
+	}()
+
+	// Get the existing entries if already have it
+	// (e.g. github.com/tyru/CaW.vim -> github.com/tyru/caw.vim)
+	reposPathList, err := normalizeReposList(lockJSON, opts.Repos)
+	if err != nil {
+		return nil, err
+	}
+	for _, reposPath := range reposPathList {
+		// System repositories are managed by administrator
+		r := lockJSON.Repos.FindByPath(reposPath)
+		if opts.RemoveRepos && r != nil && r.Type == lockjson.ReposSystemType {
+			return nil, errors.Errorf("cannot remove system repository '%s'", r.Path)
+		}
+	}
+
+	// Check if specified plugins are depended by some plugins
+	for _, reposPath := range reposPathList {
+		rdeps, err := plugconf.RdepsOf(reposPath, lockJSON.Repos)
+		if err != nil {
+			return nil, err
+		}
+		if len(rdeps) > 0 {
+			return nil, errors.Errorf("cannot remove '%s' because it's depended by '%s'",
+				reposPath, strings.Join(rdeps.Strings(), "', '"))
+		}
+	}
+
+	results := make([]RemoveResult, 0, len(reposPathList))
+	removeCount := 0
+	for _, reposPath := range reposPathList {
+		r := RemoveResult{Path: reposPath}
+
+		// Remove repository directory
+		if opts.RemoveRepos {
+			fullReposPath := reposPath.FullPath()
+			if pathutil.Exists(fullReposPath) {
+				if err := removeRepos(fullReposPath, log); err != nil {
+					return results, err
+				}
+				r.RemovedRepos = true
+				removeCount++
+			} else {
+				logger.Debugf("No repository was installed for '%s' ... skip.", reposPath)
+			}
+		}
+
+		// Remove plugconf file
+		if opts.RemovePlugconf {
+			plugconfPath := reposPath.Plugconf()
+			if pathutil.Exists(plugconfPath) {
+				if err := removePlugconf(plugconfPath, log); err != nil {
+					return results, err
+				}
+				r.RemovedPlugconf = true
+				removeCount++
+			} else {
+				logger.Debugf("No plugconf was installed for '%s' ... skip.", reposPath)
+			}
+		}
+
+		// Remove repository from lock.json
+		err := lockJSON.Repos.RemoveAllReposPath(reposPath)
+		err2 := lockJSON.Profiles.RemoveAllReposPath(reposPath)
+		if err == nil || err2 == nil {
+			r.RemovedFromLockJSON = true
+			removeCount++
+		}
+		results = append(results, r)
+	}
+	if removeCount == 0 {
+		return results, errors.New("no plugins are removed")
+	}
+
+	// Write to lock.json
+	err = lockJSON.Write()
+	if err != nil {
+		return results, errors.Wrap(err, "could not write to lock.json")
+	}
+
+	// Build ~/.vim/pack/volt dir
+	err = build(false, log)
+	if err != nil {
+		return results, errors.Wrap(err, "could not build "+pathutil.VimVoltDir())
+	}
+	return results, nil
+}
+
+// Remove repository directory
+func removeRepos(fullReposPath string, log *logger.Logger) error {
+	log.Info("Removing " + fullReposPath + " ...")
+	if err := os.RemoveAll(fullReposPath); err != nil {
+		return err
+	}
+	fileutil.RemoveDirs(filepath.Dir(fullReposPath))
+	return nil
+}
+
+// Remove plugconf file
+func removePlugconf(plugconfPath string, log *logger.Logger) error {
+	log.Info("Removing plugconf files ...")
+	if err := os.Remove(plugconfPath); err != nil {
+		return err
+	}
+	// Remove parent directories of plugconf
+	fileutil.RemoveDirs(filepath.Dir(plugconfPath))
+	return nil
+}
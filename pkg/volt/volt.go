@@ -0,0 +1,70 @@
+// Package volt is the Go API of volt to manage Vim plugins from other
+// programs.
+//
+// Each function corresponds to a volt command (e.g. Install() is
+// "volt get"), and returns the result instead of printing it.
+// Progress is logged to the Logger of the options (e.g. logger.New(w) writes
+// it to w, and logger.Default() writes it to stdout like volt command).
+// Nothing is logged if the Logger is nil.
+//
+// The functions read and write files of $VOLTPATH (or ~/volt) like volt
+// command, and fail if other volt process (or other function call) is
+// changing them.
+package volt
+
+import (
+	"context"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/internal/builder"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// normalizeReposList normalizes repos (e.g. "tyru/caw.vim" ->
+// "github.com/tyru/caw.vim"). If repos exists in lockJSON, its path is
+// used (e.g. "github.com/tyru/CaW.vim" -> "github.com/tyru/caw.vim").
+func normalizeReposList(lockJSON *lockjson.LockJSON, repos []string) ([]pathutil.ReposPath, error) {
+	reposPathList := make([]pathutil.ReposPath, 0, len(repos))
+	for _, r := range repos {
+		reposPath, err := pathutil.NormalizeRepos(r)
+		if err != nil {
+			return nil, err
+		}
+		if found := lockJSON.Repos.FindByPath(reposPath); found != nil {
+			reposPath = found.Path
+		}
+		reposPathList = append(reposPathList, reposPath)
+	}
+	return reposPathList, nil
+}
+
+// checkContext returns the error of ctx with the operation name if ctx is
+// canceled.
+func checkContext(ctx context.Context, op string) error {
+	if err := ctx.Err(); err != nil {
+		return errors.Wrap(err, op+" was canceled")
+	}
+	return nil
+}
+
+// newLogger returns log, or a logger which discards messages if log is nil.
+func newLogger(log *logger.Logger) *logger.Logger {
+	if log == nil {
+		return logger.New(ioutil.Discard)
+	}
+	return log
+}
+
+// build creates/updates ~/.vim/pack/volt directory, and logs the progress to
+// log.
+func build(full bool, log *logger.Logger) error {
+	plan, err := builder.MakePlan(full, log)
+	if err != nil {
+		return err
+	}
+	return plan.Apply()
+}
@@ -0,0 +1,202 @@
+package volt
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vim-volt/volt/fileutil"
+	"github.com/vim-volt/volt/internal/testutil"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// Checks:
+// (A) Install() adds a static repository to lock.json and current profile
+// (B) List() returns the installed repository
+// (C) SetProfile() creates a new profile and changes current profile
+// (D) Build() with DryRun does not change ~/.vim/pack/volt
+// (E) Remove() removes the repository from lock.json
+// (F) Install() and Remove() fail with no repositories
+func TestAPI(t *testing.T) {
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	ctx := context.Background()
+
+	// =============== setup =============== //
+
+	reposPath := pathutil.ReposPath("localhost/local/hello")
+	src := filepath.Join(testutil.TestdataDir(), "local", "hello")
+	dst := reposPath.FullPath()
+	os.MkdirAll(filepath.Dir(dst), 0777)
+	if err := fileutil.CopyDir(src, dst, make([]byte, 32*1024), 0777, 0); err != nil {
+		t.Fatalf("failed to copy %s to %s: %s", src, dst, err)
+	}
+
+	// =============== run =============== //
+
+	results, err := Install(ctx, InstallOptions{Repos: []string{reposPath.String()}})
+	if err != nil {
+		t.Fatal("Install() failed: " + err.Error())
+	}
+	// (A)
+	if len(results) != 1 || results[0].Path != reposPath || results[0].Type != lockjson.ReposStaticType {
+		t.Fatalf("unexpected results of Install(): %+v", results)
+	}
+	if results[0].Status != StatusAddedToProfile {
+		t.Errorf("expected status %q but got %q", StatusAddedToProfile, results[0].Status)
+	}
+
+	list, err := List(ctx, ListOptions{})
+	if err != nil {
+		t.Fatal("List() failed: " + err.Error())
+	}
+	// (B)
+	if list.Profile != "default" || !list.Current {
+		t.Errorf("expected current profile 'default' but got %+v", list)
+	}
+	if len(list.Repos) != 1 || list.Repos[0].Path != reposPath {
+		t.Errorf("expected %s in List() but got %+v", reposPath, list.Repos)
+	}
+
+	profile, err := SetProfile(ctx, SetProfileOptions{Name: "new", Create: true})
+	if err != nil {
+		t.Fatal("SetProfile() failed: " + err.Error())
+	}
+	// (C)
+	if profile.Previous != "default" || !profile.Created {
+		t.Errorf("unexpected result of SetProfile(): %+v", profile)
+	}
+	if _, err := SetProfile(ctx, SetProfileOptions{Name: "default"}); err != nil {
+		t.Fatal("SetProfile() failed: " + err.Error())
+	}
+
+	// (D)
+	optDir := filepath.Join(pathutil.VimVoltOptDir(), reposPath.EncodeToPlugDirName())
+	os.RemoveAll(optDir)
+	plan, err := Build(ctx, BuildOptions{DryRun: true})
+	if err != nil {
+		t.Fatal("Build() failed: " + err.Error())
+	}
+	if len(plan.Repos) != 1 || plan.Repos[0].Path != reposPath.String() {
+		t.Errorf("expected %s in the plan but got %+v", reposPath, plan.Repos)
+	}
+	if pathutil.Exists(optDir) {
+		t.Errorf("Build() with DryRun created %s", optDir)
+	}
+
+	removed, err := Remove(ctx, RemoveOptions{Repos: []string{reposPath.String()}})
+	if err != nil {
+		t.Fatal("Remove() failed: " + err.Error())
+	}
+	// (E)
+	if len(removed) != 1 || !removed[0].RemovedFromLockJSON || removed[0].RemovedRepos {
+		t.Errorf("unexpected results of Remove(): %+v", removed)
+	}
+	lockJSON, err := lockjson.Read()
+	if err != nil {
+		t.Fatal("lockjson.Read() failed: " + err.Error())
+	}
+	if lockJSON.Repos.FindByPath(reposPath) != nil {
+		t.Errorf("expected %s to be removed from lock.json", reposPath)
+	}
+
+	// (F)
+	if _, err := Install(ctx, InstallOptions{}); err == nil {
+		t.Error("expected Install() with no repositories to fail")
+	}
+	if _, err := Remove(ctx, RemoveOptions{}); err == nil {
+		t.Error("expected Remove() with no repositories to fail")
+	}
+}
+
+// Checks:
+// (A) Build() logs progress to BuildOptions.Logger
+// (B) Build() returns Applied = true if it succeeded
+// (C) Build() with Rollback returns RolledBack = true
+// (D) Build() returns non-nil result with Applied = false if it failed
+// (E) Build() returns *BuildError of the step which failed
+func TestBuildResult(t *testing.T) {
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	ctx := context.Background()
+
+	// =============== run =============== //
+
+	var buf bytes.Buffer
+	res, err := Build(ctx, BuildOptions{Logger: logger.New(&buf)})
+	if err != nil {
+		t.Fatal("Build() failed: " + err.Error())
+	}
+	// (A)
+	if !bytes.Contains(buf.Bytes(), []byte(pathutil.VimVoltOptDir())) {
+		t.Errorf("expected progress in the output but got %q", buf.String())
+	}
+	// (B)
+	if !res.Applied || res.RolledBack {
+		t.Errorf("unexpected result of Build(): %+v", res)
+	}
+
+	if _, err := Build(ctx, BuildOptions{}); err != nil {
+		t.Fatal("Build() failed: " + err.Error())
+	}
+	res, err = Build(ctx, BuildOptions{Rollback: true})
+	if err != nil {
+		t.Fatal("Build() with Rollback failed: " + err.Error())
+	}
+	// (C)
+	if !res.RolledBack || res.Applied {
+		t.Errorf("unexpected result of Build() with Rollback: %+v", res)
+	}
+
+	configFile := pathutil.ConfigTOML()
+	if err := ioutil.WriteFile(configFile, []byte("[build]\nstrategy = \"unknown\"\n"), 0644); err != nil {
+		t.Fatal("failed to write config.toml: " + err.Error())
+	}
+	res, err = Build(ctx, BuildOptions{})
+	// (D)
+	if err == nil {
+		t.Error("expected Build() to fail")
+	}
+	if res == nil || res.Applied || res.RolledBack {
+		t.Errorf("unexpected result of failed Build(): %+v", res)
+	}
+	// (E)
+	if e, ok := err.(*BuildError); !ok || e.Step != BuildStepBuild {
+		t.Errorf("expected *BuildError of %q but got %#v", BuildStepBuild, err)
+	}
+}
+
+// Checks:
+// (A) All functions fail with canceled context
+func TestAPICanceled(t *testing.T) {
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// (A)
+	if _, err := Install(ctx, InstallOptions{Repos: []string{"localhost/local/hello"}}); err == nil {
+		t.Error("expected Install() to fail")
+	}
+	if _, err := Upgrade(ctx, UpgradeOptions{}); err == nil {
+		t.Error("expected Upgrade() to fail")
+	}
+	if _, err := Remove(ctx, RemoveOptions{Repos: []string{"localhost/local/hello"}}); err == nil {
+		t.Error("expected Remove() to fail")
+	}
+	if _, err := Build(ctx, BuildOptions{}); err == nil {
+		t.Error("expected Build() to fail")
+	}
+	if _, err := SetProfile(ctx, SetProfileOptions{Name: "new", Create: true}); err == nil {
+		t.Error("expected SetProfile() to fail")
+	}
+	if _, err := List(ctx, ListOptions{}); err == nil {
+		t.Error("expected List() to fail")
+	}
+}
@@ -0,0 +1,366 @@
+package plugconf
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/fileutil"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+
+	"github.com/haya14busa/go-vimlparser"
+	"github.com/haya14busa/go-vimlparser/ast"
+	"github.com/haya14busa/go-vimlparser/token"
+)
+
+// LintProblem is a problem of plugconf found by Lint().
+type LintProblem struct {
+	Path   string
+	Line   int
+	Column int
+	Msg    string
+	// IsWarn is true if the problem does not break "volt build"
+	IsWarn bool
+	fix    *lintFix
+}
+
+// lintFix replaces Old at Offset of plugconf with New.
+type lintFix struct {
+	Offset int
+	Old    string
+	New    string
+}
+
+// Fixable returns true if FixLintProblems() can fix the problem.
+func (p *LintProblem) Fixable() bool {
+	return p.fix != nil
+}
+
+func (p *LintProblem) String() string {
+	severity := "error"
+	if p.IsWarn {
+		severity = "warning"
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", p.Path, p.Line, p.Column, severity, p.Msg)
+}
+
+// hookNames are function names which volt calls.
+var hookNames = []string{
+	"s:on_load_pre",
+	"s:on_load_post",
+	"s:loaded_on",
+	"s:depends",
+}
+
+// Lint parses the plugconf of reposPath, and returns found problems.
+// lockJSON is used to check the repositories of s:depends().
+func Lint(reposPath pathutil.ReposPath, lockJSON *lockjson.LockJSON) ([]LintProblem, error) {
+	path := reposPath.Plugconf()
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read plugconf")
+	}
+	file, err := vimlparser.ParseFile(bytes.NewReader(src), path, nil)
+	if err != nil {
+		if e, ok := err.(*vimlparser.ErrVimlParser); ok {
+			return []LintProblem{{Path: path, Line: e.Line, Column: e.Column, Msg: e.Msg}}, nil
+		}
+		return []LintProblem{{Path: path, Line: 1, Column: 1, Msg: err.Error()}}, nil
+	}
+
+	l := &linter{path: path, src: src, reposPath: reposPath, lockJSON: lockJSON}
+	l.lint(file)
+	sort.SliceStable(l.problems, func(i, j int) bool {
+		if l.problems[i].Line != l.problems[j].Line {
+			return l.problems[i].Line < l.problems[j].Line
+		}
+		return l.problems[i].Column < l.problems[j].Column
+	})
+	return l.problems, nil
+}
+
+// FixLintProblems rewrites the plugconf of reposPath to fix the fixable
+// problems of problems, which must be returned by Lint().
+// Returns the number of fixed problems.
+func FixLintProblems(reposPath pathutil.ReposPath, problems []LintProblem) (int, error) {
+	var fixes []*lintFix
+	for i := range problems {
+		if problems[i].fix != nil {
+			fixes = append(fixes, problems[i].fix)
+		}
+	}
+	if len(fixes) == 0 {
+		return 0, nil
+	}
+
+	path := reposPath.Plugconf()
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, errors.Wrap(err, "could not read plugconf")
+	}
+	// Replace from the end not to change offsets of other fixes
+	sort.Slice(fixes, func(i, j int) bool {
+		return fixes[i].Offset > fixes[j].Offset
+	})
+	for _, fix := range fixes {
+		end := fix.Offset + len(fix.Old)
+		if end > len(src) || string(src[fix.Offset:end]) != fix.Old {
+			return 0, errors.Errorf("%s was modified after linting", path)
+		}
+		src = append(src[:fix.Offset:fix.Offset], append([]byte(fix.New), src[end:]...)...)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	if err := fileutil.WriteFileAtomic(path, src, info.Mode().Perm()); err != nil {
+		return 0, errors.Wrap(err, "could not write plugconf")
+	}
+	return len(fixes), nil
+}
+
+type linter struct {
+	path      string
+	src       []byte
+	reposPath pathutil.ReposPath
+	lockJSON  *lockjson.LockJSON
+	problems  []LintProblem
+}
+
+func (l *linter) report(pos ast.Pos, isWarn bool, fix *lintFix, format string, args ...interface{}) {
+	l.problems = append(l.problems, LintProblem{
+		Path:   l.path,
+		Line:   pos.Line,
+		Column: pos.Column,
+		Msg:    fmt.Sprintf(format, args...),
+		IsWarn: isWarn,
+		fix:    fix,
+	})
+}
+
+func (l *linter) lint(file *ast.File) {
+	// Collect top-level functions
+	var functions []*ast.Function
+	defined := make(map[string]bool)
+	for _, stmt := range file.Body {
+		fn, ok := stmt.(*ast.Function)
+		if !ok {
+			continue
+		}
+		ident, ok := fn.Name.(*ast.Ident)
+		if !ok {
+			continue
+		}
+		if defined[ident.Name] {
+			l.report(ident.NamePos, false, nil, "duplicate %s()", ident.Name)
+			continue
+		}
+		functions = append(functions, fn)
+		defined[ident.Name] = true
+	}
+	if defined["s:config"] && defined["s:on_load_pre"] {
+		l.report(file.Pos(), false, nil, "both s:on_load_pre() and s:config() are defined")
+	}
+
+	for _, fn := range functions {
+		ident := fn.Name.(*ast.Ident)
+		if isProhibitedFuncName(ident.Name) {
+			l.report(ident.NamePos, false, nil, "'%s' is prohibited function name. please use other function name", ident.Name)
+			continue
+		}
+		switch ident.Name {
+		case "s:loaded_on":
+			l.lintLoadedOn(fn)
+		case "s:depends":
+			l.lintDepends(fn)
+		case "s:on_load_pre", "s:on_load_post":
+		case "s:config":
+			var fix *lintFix
+			if !defined["s:on_load_pre"] {
+				fix = &lintFix{Offset: ident.NamePos.Offset, Old: ident.Name, New: "s:on_load_pre"}
+				defined["s:on_load_pre"] = true
+			}
+			l.report(ident.NamePos, true, fix, "s:config() is deprecated. please use s:on_load_pre() instead")
+		default:
+			l.lintUnknownFunc(ident, defined)
+		}
+	}
+}
+
+// lintLoadedOn checks the return values of s:loaded_on() are valid string
+// literals, and the excmds of "excmd=..." are defined by the plugin.
+func (l *linter) lintLoadedOn(fn *ast.Function) {
+	ast.Inspect(fn, func(node ast.Node) bool {
+		ret, ok := node.(*ast.Return)
+		if !ok {
+			return true
+		}
+		lit, ok := ret.Result.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			l.report(ret.Pos(), false, nil, "the return value of s:loaded_on() must be a string literal")
+			return true
+		}
+		value := lit.Value[1 : len(lit.Value)-1]
+		switch {
+		case value == "start", strings.HasPrefix(value, "filetype="):
+		case strings.HasPrefix(value, "excmd="):
+			defined, err := l.pluginExcmds()
+			if err != nil {
+				l.report(lit.Pos(), true, nil, "could not check excmds: %s", err)
+				return true
+			}
+			for _, excmd := range strings.Split(strings.TrimPrefix(value, "excmd="), ",") {
+				if !defined[excmd] {
+					l.report(lit.Pos(), true, nil, "excmd '%s' is not defined in plugin/ directory of %s", excmd, l.reposPath)
+				}
+			}
+		default:
+			l.report(lit.Pos(), false, nil, "invalid return value of s:loaded_on(): %s (must be 'start', 'filetype=...', or 'excmd=...')", lit.Value)
+		}
+		return true
+	})
+}
+
+// lintDepends checks the return values of s:depends() are lists of string
+// literals, and the repositories are in lock.json.
+func (l *linter) lintDepends(fn *ast.Function) {
+	ast.Inspect(fn, func(node ast.Node) bool {
+		ret, ok := node.(*ast.Return)
+		if !ok {
+			return true
+		}
+		list, ok := ret.Result.(*ast.List)
+		if !ok {
+			l.report(ret.Pos(), false, nil, "the return value of s:depends() must be a list literal")
+			return true
+		}
+		for _, value := range list.Values {
+			lit, ok := value.(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				l.report(value.Pos(), false, nil, "the elements of s:depends() must be string literals")
+				continue
+			}
+			reposPath, err := pathutil.NormalizeRepos(lit.Value[1 : len(lit.Value)-1])
+			if err != nil {
+				l.report(lit.Pos(), false, nil, "invalid repository %s: %s", lit.Value, err)
+				continue
+			}
+			if l.lockJSON.Repos.FindByPath(reposPath) == nil {
+				l.report(lit.Pos(), false, nil, "repository '%s' is not installed. please run 'volt get %s'", reposPath, reposPath)
+			}
+		}
+		return true
+	})
+}
+
+// lintUnknownFunc checks s: function which is not a hook is called in
+// plugconf. A function which is never called may be a typo of hook name.
+func (l *linter) lintUnknownFunc(ident *ast.Ident, defined map[string]bool) {
+	if !strings.HasPrefix(ident.Name, "s:") {
+		return
+	}
+	name := strings.TrimPrefix(ident.Name, "s:")
+	rx := regexp.MustCompile(`(?:\bs:|<SID>)` + regexp.QuoteMeta(name) + `\b`)
+	if len(rx.FindAllIndex(l.src, 2)) > 1 {
+		return
+	}
+
+	// Suggest the nearest hook name
+	var suggestion string
+	nearest := 4
+	for _, hook := range hookNames {
+		d := levenshtein(ident.Name, hook)
+		if d < nearest || suggestion == "" && strings.HasPrefix(hook, ident.Name) && len(name) >= 4 {
+			suggestion, nearest = hook, d
+		}
+	}
+	if suggestion == "" {
+		l.report(ident.NamePos, true, nil, "%s() is not a hook of plugconf, and never called", ident.Name)
+		return
+	}
+	var fix *lintFix
+	if !defined[suggestion] {
+		fix = &lintFix{Offset: ident.NamePos.Offset, Old: ident.Name, New: suggestion}
+		defined[suggestion] = true
+	}
+	l.report(ident.NamePos, true, fix, "%s() is not a hook of plugconf, and never called. did you mean %s()?", ident.Name, suggestion)
+}
+
+var rxCommandDef = regexp.MustCompile(`(?m)^\s*com(?:m(?:a(?:n(?:d)?)?)?)?!?\s+(?:-\S+\s+)*([A-Z]\w*)`)
+
+// pluginExcmds returns excmds defined in plugin/ and after/plugin/ directory
+// of the plugin.
+func (l *linter) pluginExcmds() (map[string]bool, error) {
+	excmds := make(map[string]bool)
+	fullpath := l.reposPath.FullPath()
+	if !pathutil.Exists(fullpath) {
+		return nil, errors.New(l.reposPath.String() + " is not installed")
+	}
+	for _, dir := range []string{
+		filepath.Join(fullpath, "plugin"),
+		filepath.Join(fullpath, "after", "plugin"),
+	} {
+		if !pathutil.Exists(dir) {
+			continue
+		}
+		err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if fi.IsDir() || filepath.Ext(path) != ".vim" {
+				return nil
+			}
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			for _, m := range rxCommandDef.FindAllSubmatch(content, -1) {
+				excmds[string(m[1])] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return excmds, nil
+}
+
+// levenshtein returns the edit distance between s1 and s2.
+func levenshtein(s1, s2 string) int {
+	prev := make([]int, len(s2)+1)
+	cur := make([]int, len(s2)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s1); i++ {
+		cur[0] = i
+		for j := 1; j <= len(s2); j++ {
+			cost := 1
+			if s1[i-1] == s2[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(s2)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,96 @@
+package plugconf
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/haya14busa/go-vimlparser"
+	"github.com/pkg/errors"
+)
+
+// rxAnyFuncName is a pattern which matches to the name of a function
+// definition.
+var rxAnyFuncName = regexp.MustCompile(`\Afu\w*!?\s+([^\s(]+)`)
+
+// Merge merges changes between base and theirs into local by each function
+// (three-way merge), and returns the merged plugconf content.
+// If a function was changed in both local and theirs, the function of local
+// is kept, and the function name is returned as a conflict.
+func Merge(base, local, theirs *ParsedInfo) ([]byte, []string, error) {
+	baseFuncs, _ := base.funcMap()
+	localFuncs, names := local.funcMap()
+	theirFuncs, theirNames := theirs.funcMap()
+	for _, name := range theirNames {
+		if _, exists := localFuncs[name]; !exists {
+			names = append(names, name)
+		}
+	}
+
+	merged := make(map[string]string, len(names))
+	var conflicts []string
+	for _, name := range names {
+		b, l, r := baseFuncs[name], localFuncs[name], theirFuncs[name]
+		switch {
+		case l == r || r == b:
+			merged[name] = l
+		case l == b:
+			merged[name] = r
+		default:
+			merged[name] = l
+			conflicts = append(conflicts, name)
+		}
+	}
+
+	result := &ParsedInfo{
+		onLoadPreFunc:  merged["s:on_load_pre"],
+		onLoadPostFunc: merged["s:on_load_post"],
+		loadOnFunc:     merged["s:loaded_on"],
+		dependsFunc:    merged["s:depends"],
+	}
+	for _, name := range names[len(specialFuncNames):] {
+		if merged[name] != "" {
+			result.functions = append(result.functions, merged[name])
+		}
+	}
+	content, err := result.GeneratePlugconf()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Check if the merged plugconf is valid
+	file, err := vimlparser.ParseFile(bytes.NewReader(content), "", nil)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "merged plugconf is invalid")
+	}
+	if _, parseErr := ParsePlugconf(file, content, ""); parseErr.HasErrs() {
+		return nil, nil, errors.Wrap(parseErr.ErrorsAndWarns(), "merged plugconf is invalid")
+	}
+	return content, conflicts, nil
+}
+
+var specialFuncNames = []string{"s:on_load_pre", "s:on_load_post", "s:loaded_on", "s:depends"}
+
+// funcMap returns function definitions keyed by the function names, and the
+// names in order. Empty functions are mapped to empty string.
+func (pi *ParsedInfo) funcMap() (map[string]string, []string) {
+	names := make([]string, 0, len(specialFuncNames)+len(pi.functions))
+	names = append(names, specialFuncNames...)
+	funcs := map[string]string{
+		"s:on_load_pre":  pi.onLoadPreFunc,
+		"s:on_load_post": pi.onLoadPostFunc,
+		"s:loaded_on":    pi.loadOnFunc,
+		"s:depends":      pi.dependsFunc,
+	}
+	for _, f := range pi.functions {
+		name := f
+		if m := rxAnyFuncName.FindStringSubmatch(f); m != nil {
+			name = m[1]
+		}
+		if _, exists := funcs[name]; exists {
+			continue
+		}
+		funcs[name] = f
+		names = append(names, name)
+	}
+	return funcs, names
+}
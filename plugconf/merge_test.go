@@ -0,0 +1,74 @@
+package plugconf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func parseTemplate(t *testing.T, content string) *ParsedInfo {
+	pi, merr := (&Template{[]byte(content)}).Parse("")
+	if merr.ErrorOrNil() != nil {
+		t.Fatalf("failed to parse %q: %s", content, merr.Error())
+	}
+	return pi
+}
+
+func TestMerge(t *testing.T) {
+	const (
+		preFoo   = "function! s:on_load_pre()\n  let g:foo = 1\nendfunction"
+		preBar   = "function! s:on_load_pre()\n  let g:bar = 1\nendfunction"
+		startOn  = "function! s:loaded_on()\n  return 'start'\nendfunction"
+		vimOn    = "function! s:loaded_on()\n  return 'filetype=vim'\nendfunction"
+		excmdOn  = "function! s:loaded_on()\n  return 'excmd=Hello'\nendfunction"
+		helperV1 = "function! s:helper()\n  return 1\nendfunction"
+		helperV2 = "function! s:helper()\n  return 2\nendfunction"
+	)
+	var tests = []struct {
+		base      string
+		local     string
+		theirs    string
+		contains  []string
+		excludes  []string
+		conflicts []string
+	}{
+		// Takes theirs if local was not changed
+		{vimOn, vimOn, excmdOn, []string{excmdOn}, []string{vimOn}, nil},
+		// Keeps local if theirs was not changed
+		{vimOn, excmdOn, vimOn, []string{excmdOn}, []string{vimOn}, nil},
+		// Merges changes of different functions
+		{vimOn, preFoo + "\n" + vimOn, excmdOn, []string{preFoo, excmdOn}, []string{vimOn}, nil},
+		// Same changes are not conflicts
+		{vimOn, excmdOn, excmdOn, []string{excmdOn}, []string{vimOn}, nil},
+		// Keeps local if both were changed
+		{vimOn, startOn, excmdOn, []string{startOn}, []string{excmdOn}, []string{"s:loaded_on"}},
+		{"", preFoo, preBar, []string{preFoo}, []string{preBar}, []string{"s:on_load_pre"}},
+		// Adds, updates, and removes other functions
+		{"", "", helperV1, []string{helperV1}, nil, nil},
+		{helperV1, helperV1, helperV2, []string{helperV2}, []string{helperV1}, nil},
+		{helperV1, helperV1, "", nil, []string{helperV1}, nil},
+		{helperV1, helperV2, "", []string{helperV2}, nil, []string{"s:helper"}},
+	}
+	for i, tt := range tests {
+		content, conflicts, err := Merge(
+			parseTemplate(t, tt.base), parseTemplate(t, tt.local), parseTemplate(t, tt.theirs),
+		)
+		if err != nil {
+			t.Errorf("[%d] unexpected error: %s", i, err.Error())
+			continue
+		}
+		for _, s := range tt.contains {
+			if !strings.Contains(string(content), s) {
+				t.Errorf("[%d] expected %q in merged plugconf but got:\n%s", i, s, string(content))
+			}
+		}
+		for _, s := range tt.excludes {
+			if strings.Contains(string(content), s) {
+				t.Errorf("[%d] expected no %q in merged plugconf but got:\n%s", i, s, string(content))
+			}
+		}
+		if !reflect.DeepEqual(conflicts, tt.conflicts) {
+			t.Errorf("[%d] expected conflicts %v but got %v", i, tt.conflicts, conflicts)
+		}
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"path"
 	"path/filepath"
 	"regexp"
 	"sort"
@@ -14,7 +13,6 @@ import (
 	"github.com/pkg/errors"
 
 	multierror "github.com/hashicorp/go-multierror"
-	"github.com/vim-volt/volt/httputil"
 	"github.com/vim-volt/volt/lockjson"
 	"github.com/vim-volt/volt/pathutil"
 
@@ -71,6 +69,20 @@ func (pi *ParsedInfo) ConvertConfigToOnLoadPreFunc() bool {
 	return true
 }
 
+// LoadOn returns the return value of s:loaded_on() ("start",
+// "filetype={filetypes}", or "excmd={excmds}").
+// If s:loaded_on() is not defined, returns "start".
+func (pi *ParsedInfo) LoadOn() string {
+	switch pi.loadOn {
+	case loadOnFileType:
+		return "filetype=" + pi.loadOnArg
+	case loadOnExcmd:
+		return "excmd=" + pi.loadOnArg
+	default:
+		return "start"
+	}
+}
+
 // GeneratePlugconf generates a plugconf file placed at
 // "$VOLTPATH/plugconf/{repos}.vim".
 func (pi *ParsedInfo) GeneratePlugconf() ([]byte, error) {
@@ -701,6 +713,63 @@ endfunction
 	return buf.Bytes(), nil
 }
 
+// GenerateProfileSelector generates the content of a bundled plugconf which
+// selects a profile at startup, and sources the bundled plugconf of the
+// profile. A profile is selected by (in order of priority):
+// 1. $VOLT_PROFILE environment variable
+// 2. The first non-empty line of the nearest ".volt-profile" file
+// 3. currentProfile
+// profilePlugconfs is a map whose key is profile name, and value is fullpath
+// of bundled plugconf of the profile.
+func GenerateProfileSelector(currentProfile string, profilePlugconfs map[string]string) ([]byte, error) {
+	currentJSON, err := json.Marshal(currentProfile)
+	if err != nil {
+		return nil, err
+	}
+	plugconfsJSON, err := json.Marshal(profilePlugconfs)
+	if err != nil {
+		return nil, err
+	}
+	profileFile := strings.Replace(pathutil.VoltProfileFile, "'", "''", -1)
+
+	var buf bytes.Buffer
+	buf.WriteString(`if exists('g:loaded_volt_system_profile_selector')
+  finish
+endif
+let g:loaded_volt_system_profile_selector = 1
+
+let s:current_profile = ` + string(currentJSON) + `
+let s:profile_plugconfs = ` + string(plugconfsJSON) + `
+
+function! s:detect_profile() abort
+  if $VOLT_PROFILE !=# ''
+    return $VOLT_PROFILE
+  endif
+  let file = findfile('` + profileFile + `', escape(getcwd(), ' ,') . ';')
+  if file ==# ''
+    return s:current_profile
+  endif
+  for line in readfile(file)
+    let name = matchstr(line, '^\s*\zs.\{-}\ze\s*$')
+    if name !=# ''
+      return name
+    endif
+  endfor
+  return s:current_profile
+endfunction
+
+let g:volt_profile = s:detect_profile()
+if !has_key(s:profile_plugconfs, g:volt_profile)
+  echohl WarningMsg
+  echomsg printf('[volt] Profile ''%s'' is not found: use ''%s'' instead', g:volt_profile, s:current_profile)
+  echohl None
+  let g:volt_profile = s:current_profile
+endif
+execute 'source' fnameescape(s:profile_plugconfs[g:volt_profile])`)
+
+	return buf.Bytes(), nil
+}
+
 // Each iterates each repository by given func.
 func (mp *MultiParsedInfo) Each(f func(pathutil.ReposPath, *ParsedInfo)) {
 	for reposPath, info := range mp.plugconfMap {
@@ -723,6 +792,17 @@ func RdepsOf(reposPath pathutil.ReposPath, reposList []lockjson.Repos) (pathutil
 	return rdeps, nil
 }
 
+// DepsMap returns a map whose key is a repository path of reposList, and
+// value is depended (required) plugins of the repository.
+func DepsMap(reposList []lockjson.Repos) (map[pathutil.ReposPath]pathutil.ReposPathList, error) {
+	plugconfMap, parseErr := parsePlugconfAsMap(reposList)
+	if parseErr.HasErrs() {
+		return nil, parseErr.ErrorsAndWarns()
+	}
+	_, depsMap, _ := getDepMaps(reposList, plugconfMap)
+	return depsMap, nil
+}
+
 // Parse plugconf of reposList and return parsed plugconf info as map
 func parsePlugconfAsMap(reposList []lockjson.Repos) (map[pathutil.ReposPath]*ParsedInfo, MultiParseError) {
 	parseErrAll := make(MultiParseError, 0, len(reposList))
@@ -836,18 +916,6 @@ type Template struct {
 	template []byte
 }
 
-// FetchPlugconfTemplate fetches reposPath's plugconf from vim-volt/plugconf-templates
-// repository.
-// Fetched URL: https://raw.githubusercontent.com/vim-volt/plugconf-templates/master/templates/{reposPath}.vim
-func FetchPlugconfTemplate(reposPath pathutil.ReposPath) (*Template, error) {
-	url := path.Join("https://raw.githubusercontent.com/vim-volt/plugconf-templates/master/templates", reposPath.String()+".vim")
-	content, err := httputil.GetContent(url)
-	if err != nil {
-		return nil, err
-	}
-	return &Template{content}, nil
-}
-
 const skeletonPlugconfOnLoadPre = `" Plugin configuration like the code written in vimrc.
 " This configuration is executed *before* a plugin is loaded.
 function! s:on_load_pre()
@@ -882,20 +950,28 @@ function! s:depends()
   return []
 endfunction`
 
+// Parse parses Template. If pt is nil, it returns ParsedInfo of the skeleton
+// plugconf.
+func (pt *Template) Parse(path string) (*ParsedInfo, *multierror.Error) {
+	if pt == nil {
+		return &ParsedInfo{}, nil
+	}
+	tmpl, err := vimlparser.ParseFile(bytes.NewReader(pt.template), path, nil)
+	if err != nil {
+		return nil, multierror.Append(nil, err)
+	}
+	result, parseErr := ParsePlugconf(tmpl, pt.template, path)
+	if parseErr.HasErrs() {
+		return nil, parseErr.ErrorsAndWarns()
+	}
+	return result, nil
+}
+
 // Generate generates plugconf content from Template.
 func (pt *Template) Generate(path string) ([]byte, *multierror.Error) {
-	result := &ParsedInfo{}
-	if pt != nil {
-		// Parse fetched plugconf
-		tmpl, err := vimlparser.ParseFile(bytes.NewReader(pt.template), path, nil)
-		if err != nil {
-			return nil, multierror.Append(nil, err)
-		}
-		var parseErr *ParseError
-		result, parseErr = ParsePlugconf(tmpl, pt.template, path)
-		if parseErr.HasErrs() {
-			return nil, parseErr.ErrorsAndWarns()
-		}
+	result, merr := pt.Parse(path)
+	if merr.ErrorOrNil() != nil {
+		return nil, merr
 	}
 	content, err := result.GeneratePlugconf()
 	if err != nil {
@@ -0,0 +1,296 @@
+package plugconf
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/fileutil"
+	"github.com/vim-volt/volt/gitutil"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// errTemplateFound stops reading a tree when a template was found.
+var errTemplateFound = errors.New("template was found")
+
+// TemplateFetcher fetches plugconf templates from template sources
+// (plugconf.template_sources of config.toml).
+//
+// A template of {repository} is "templates/{repository}.vim" or
+// "{repository}.vim" in a source. Git repositories are cloned to
+// "$VOLTPATH/cache/plugconf-templates/git/{hash of URL}", and updated at most
+// once by a TemplateFetcher. If updating failed (e.g. offline), the cache is
+// used.
+// Templates of local directories are versioned by the hash of the content,
+// and copied to "$VOLTPATH/cache/plugconf-templates/local/{hash}.vim".
+type TemplateFetcher struct {
+	sources []string
+	git     gitutil.Backend
+	mu      sync.Mutex
+	updated map[string]bool
+}
+
+// NewTemplateFetcher returns TemplateFetcher which uses git to clone and
+// update remote sources.
+func NewTemplateFetcher(sources []string, git gitutil.Backend) *TemplateFetcher {
+	return &TemplateFetcher{
+		sources: sources,
+		git:     git,
+		updated: make(map[string]bool),
+	}
+}
+
+// Fetch returns reposPath's plugconf template, and where it was fetched from.
+// If recorded is non-nil, the template at recorded source and version is
+// tried first, so that the same plugconf is generated from lock.json.
+func (f *TemplateFetcher) Fetch(reposPath pathutil.ReposPath, recorded *lockjson.PlugconfTemplate) (*Template, *lockjson.PlugconfTemplate, error) {
+	if recorded != nil {
+		content, err := f.fetchFrom(recorded.Source, recorded.Version, reposPath)
+		if err == nil {
+			return &Template{content}, recorded, nil
+		}
+		logger.Debugf("could not fetch plugconf template of %s from recorded source %s: %s", reposPath, recorded.Source, err.Error())
+	}
+	for _, src := range f.sources {
+		content, version, err := f.fetchLatest(src, reposPath)
+		if err != nil {
+			logger.Debugf("could not fetch plugconf template of %s from %s: %s", reposPath, src, err.Error())
+			continue
+		}
+		return &Template{content}, &lockjson.PlugconfTemplate{Source: src, Version: version}, nil
+	}
+	return nil, nil, errors.Errorf("plugconf template of %s was not found in template sources", reposPath)
+}
+
+// FetchRecorded returns reposPath's plugconf template at recorded source and
+// version. Unlike Fetch(), it does not try other sources.
+func (f *TemplateFetcher) FetchRecorded(reposPath pathutil.ReposPath, recorded *lockjson.PlugconfTemplate) (*Template, error) {
+	content, err := f.fetchFrom(recorded.Source, recorded.Version, reposPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{content}, nil
+}
+
+// Install creates the plugconf file of reposPath from the template, and
+// returns where the template was fetched from (see Fetch()).
+// If the template was not found, skeleton plugconf is created and nil source
+// is returned. It does nothing if the plugconf file already exists.
+func (f *TemplateFetcher) Install(reposPath pathutil.ReposPath, recorded *lockjson.PlugconfTemplate) (*lockjson.PlugconfTemplate, error) {
+	log := logger.With("repos", reposPath)
+	path := reposPath.Plugconf()
+	if pathutil.Exists(path) {
+		log.Debugf("plugconf '%s' exists... skip", path)
+		return nil, nil
+	}
+
+	// If non-nil error returned from Fetch(),
+	// create skeleton plugconf file
+	tmpl, source, err := f.Fetch(reposPath, recorded)
+	if err != nil {
+		log.Debug(err.Error())
+		// empty tmpl is returned when err != nil
+	}
+	content, merr := tmpl.Generate(path)
+	if merr.ErrorOrNil() != nil {
+		return nil, errors.Errorf("parse error in fetched plugconf %s: %s", reposPath, merr.Error())
+	}
+	os.MkdirAll(filepath.Dir(path), 0755)
+	err = ioutil.WriteFile(path, content, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// fetchLatest reads the template of reposPath in src, and returns it with
+// the version (the commit hash of a git repository, or the hash of the
+// content of a local directory).
+func (f *TemplateFetcher) fetchLatest(src string, reposPath pathutil.ReposPath) ([]byte, string, error) {
+	if isLocalSource(src) {
+		content, err := readLocalTemplate(localSourceDir(src), reposPath)
+		if err != nil {
+			return nil, "", err
+		}
+		version, err := saveLocalTemplate(content)
+		return content, version, err
+	}
+	dir, err := f.cache(src, "")
+	if err != nil {
+		return nil, "", err
+	}
+	head, err := gitutil.GetHEAD(f.git, dir)
+	if err != nil {
+		return nil, "", err
+	}
+	content, err := f.readGitTemplate(dir, head, reposPath)
+	return content, head, err
+}
+
+// fetchFrom reads the template of reposPath at version of src.
+func (f *TemplateFetcher) fetchFrom(src, version string, reposPath pathutil.ReposPath) ([]byte, error) {
+	if isLocalSource(src) {
+		content, err := readLocalTemplate(localSourceDir(src), reposPath)
+		if err == nil && (version == "" || contentHash(content) == version) {
+			return content, nil
+		}
+		// The template was changed or removed
+		return ioutil.ReadFile(localTemplateCache(version))
+	}
+	if version == "" {
+		return nil, errors.New("no version is recorded")
+	}
+	dir, err := f.cache(src, version)
+	if err != nil {
+		return nil, err
+	}
+	return f.readGitTemplate(dir, version, reposPath)
+}
+
+// cache returns the directory of the cloned src.
+// It clones src if the cache does not exist, or updates it if version is
+// empty or not found in the cache.
+func (f *TemplateFetcher) cache(src, version string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dir := cacheDir(src)
+	if _, err := f.git.IsBare(dir); err != nil {
+		// Remove broken cache
+		if err := os.RemoveAll(dir); err != nil {
+			return "", err
+		}
+		if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+			return "", err
+		}
+		logger.Debugf("Cloning plugconf templates %s ...", src)
+		if err := f.git.Clone(src, dir); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+		f.updated[src] = true
+		return dir, nil
+	}
+	if f.updated[src] {
+		return dir, nil
+	}
+	if version != "" {
+		if _, err := f.git.ResolveRef(dir, version); err == nil {
+			return dir, nil
+		}
+	}
+	logger.Debugf("Updating plugconf templates %s ...", src)
+	if err := f.git.FastForward(dir); err != nil && err != gitutil.ErrAlreadyUpToDate {
+		logger.Debugf("could not update %s, use the cache: %s", dir, err.Error())
+	}
+	f.updated[src] = true
+	return dir, nil
+}
+
+func (f *TemplateFetcher) readGitTemplate(dir, commit string, reposPath pathutil.ReposPath) ([]byte, error) {
+	names := templateNames(reposPath)
+	found := make([][]byte, len(names))
+	err := f.git.ReadTree(dir, commit, func(file *gitutil.TreeFile) error {
+		for i := range names {
+			if file.Name != names[i] {
+				continue
+			}
+			r, err := file.Reader()
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+			found[i], err = ioutil.ReadAll(r)
+			if err != nil {
+				return err
+			}
+			if i == 0 {
+				return errTemplateFound
+			}
+		}
+		return nil
+	})
+	if err != nil && err != errTemplateFound {
+		return nil, err
+	}
+	for i := range found {
+		if found[i] != nil {
+			return found[i], nil
+		}
+	}
+	return nil, errors.New("template was not found")
+}
+
+func readLocalTemplate(dir string, reposPath pathutil.ReposPath) ([]byte, error) {
+	for _, name := range templateNames(reposPath) {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if pathutil.Exists(file) {
+			return ioutil.ReadFile(file)
+		}
+	}
+	return nil, errors.New("template was not found")
+}
+
+// saveLocalTemplate copies content to the cache, and returns the version.
+func saveLocalTemplate(content []byte) (string, error) {
+	version := contentHash(content)
+	file := localTemplateCache(version)
+	if pathutil.Exists(file) {
+		return version, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return "", err
+	}
+	// The cache is used as a valid one only by its name
+	return version, fileutil.WriteFileAtomic(file, content, 0644)
+}
+
+func contentHash(content []byte) string {
+	return fmt.Sprintf("%x", sha1.Sum(content))
+}
+
+func localTemplateCache(version string) string {
+	return filepath.Join(pathutil.CacheDir(), "plugconf-templates", "local", version+".vim")
+}
+
+// templateNames returns slash-separated paths of reposPath's template in a
+// source, in priority order.
+func templateNames(reposPath pathutil.ReposPath) []string {
+	name := reposPath.String() + ".vim"
+	return []string{path.Join("templates", name), name}
+}
+
+func isLocalSource(src string) bool {
+	return strings.HasPrefix(src, "file://") || filepath.IsAbs(src)
+}
+
+func localSourceDir(src string) string {
+	return filepath.FromSlash(strings.TrimPrefix(src, "file://"))
+}
+
+// cacheDir returns the directory where src is cloned to
+// (e.g. "https://github.com/vim-volt/plugconf-templates" ->
+// "$VOLTPATH/cache/plugconf-templates/git/{hash}").
+// The directory name is the hash of normalized src, so that src which has
+// ".." or only a host never points to the outside of the cache or to other
+// sources' directories, which may be removed as broken caches.
+func cacheDir(src string) string {
+	name := src
+	if i := strings.Index(name, "://"); i >= 0 {
+		name = name[i+len("://"):]
+	}
+	if i := strings.Index(name, "@"); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSuffix(strings.TrimRight(name, "/"), ".git")
+	name = strings.Replace(name, ":", "/", -1)
+	return filepath.Join(pathutil.CacheDir(), "plugconf-templates", "git", contentHash([]byte(name)))
+}
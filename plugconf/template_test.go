@@ -0,0 +1,199 @@
+package plugconf
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/gitutil"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func TestCacheDir(t *testing.T) {
+	root := filepath.Join(pathutil.CacheDir(), "plugconf-templates", "git")
+
+	// Sources which differ only in userinfo, ".git" or trailing slash share a cache
+	var sameTests = [][]string{
+		{"https://github.com/vim-volt/plugconf-templates", "https://github.com/vim-volt/plugconf-templates/", "https://user@github.com/vim-volt/plugconf-templates.git"},
+		{"ssh://git@git.example.com/team/templates", "git@git.example.com:team/templates.git"},
+	}
+	for _, srcs := range sameTests {
+		for _, src := range srcs[1:] {
+			if cacheDir(src) != cacheDir(srcs[0]) {
+				t.Errorf("cacheDir(%q) and cacheDir(%q) must be the same: %q, %q", src, srcs[0], cacheDir(src), cacheDir(srcs[0]))
+			}
+		}
+	}
+
+	// Every source has its own directory directly under the cache root
+	srcs := []string{
+		"https://github.com/vim-volt/plugconf-templates",
+		"https://github.com",
+		"https://x/../../..",
+		"https://x/..",
+		"file:///../templates.git",
+		"ssh://git@git.example.com:2222/team/templates",
+	}
+	dirs := make(map[string]string, len(srcs))
+	for _, src := range srcs {
+		dir := cacheDir(src)
+		if filepath.Dir(dir) != root {
+			t.Errorf("cacheDir(%q) is not a child of %q: %q", src, root, dir)
+		}
+		if other, exists := dirs[dir]; exists {
+			t.Errorf("cacheDir(%q) and cacheDir(%q) must differ: %q", src, other, dir)
+		}
+		dirs[dir] = src
+	}
+}
+
+func TestTemplateFetcherGitSource(t *testing.T) {
+	voltpath, err := ioutil.TempDir("", "volt-test-plugconf-")
+	if err != nil {
+		t.Fatal("failed to create temp dir: " + err.Error())
+	}
+	defer os.RemoveAll(voltpath)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", voltpath)
+
+	const src = "https://git.example.com/team/templates"
+	reposPath := pathutil.ReposPath("github.com/tyru/caw.vim")
+	upstream := &gitutil.FakeRepos{}
+	commit := func(hash, content string) {
+		upstream.Commits = append(upstream.Commits, gitutil.Commit{Hash: hash, When: time.Now()})
+		upstream.Files[hash] = []gitutil.FakeFile{
+			{Name: "README.md", Mode: 0644, Content: []byte("templates")},
+			{Name: "templates/github.com/tyru/caw.vim.vim", Mode: 0644, Content: []byte(content)},
+		}
+	}
+	git := gitutil.NewFakeBackend()
+	git.AddRepos(src, upstream)
+	commit("1111111111111111111111111111111111111111", "first")
+
+	// Clones the source, and returns the template at HEAD
+	tmpl, source, err := NewTemplateFetcher([]string{src}, git).Fetch(reposPath, nil)
+	expected := lockjson.PlugconfTemplate{Source: src, Version: "1111111111111111111111111111111111111111"}
+	if err != nil || string(tmpl.template) != "first" || *source != expected {
+		t.Fatalf("expected first template from %+v but got %v, %+v, %v", expected, tmpl, source, err)
+	}
+
+	// Recorded version is used even if the source was updated
+	commit("2222222222222222222222222222222222222222", "second")
+	tmpl, source, err = NewTemplateFetcher([]string{src}, git).Fetch(reposPath, &expected)
+	if err != nil || string(tmpl.template) != "first" || *source != expected {
+		t.Errorf("expected first template from %+v but got %v, %+v, %v", expected, tmpl, source, err)
+	}
+
+	// Updates the cache, and returns the template at new HEAD
+	tmpl, source, err = NewTemplateFetcher([]string{src}, git).Fetch(reposPath, nil)
+	expected.Version = "2222222222222222222222222222222222222222"
+	if err != nil || string(tmpl.template) != "second" || *source != expected {
+		t.Errorf("expected second template from %+v but got %v, %+v, %v", expected, tmpl, source, err)
+	}
+
+	// Returns an error if no source has the template
+	_, _, err = NewTemplateFetcher([]string{src}, git).Fetch(pathutil.ReposPath("github.com/tyru/open-browser.vim"), nil)
+	if err == nil {
+		t.Error("expected an error but got nil")
+	}
+}
+
+func newHTTPConfig() *config.Config {
+	cfg := &config.Config{}
+	cfg.HTTP.Timeout = "10s"
+	return cfg
+}
+
+// Checks:
+// (A) Git sources are cloned via http.proxy
+// (B) Git sources are cloned with certificates of http.ca_file
+// (C) Cloning git sources times out by http.timeout
+func TestTemplateFetcherHTTPConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "volt-test-plugconf-")
+	if err != nil {
+		t.Fatal("failed to create temp dir: " + err.Error())
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("VOLTPATH", os.Getenv("VOLTPATH"))
+	os.Setenv("VOLTPATH", dir)
+	// Do not use credential helpers of the host
+	defer os.Setenv("HOME", os.Getenv("HOME"))
+	os.Setenv("HOME", dir)
+
+	reposPath := pathutil.ReposPath("github.com/tyru/caw.vim")
+	fetch := func(cfg *config.Config, src string) error {
+		cfg.Get.GitBackend = config.GoGitBackend
+		git, err := gitutil.NewBackend(cfg)
+		if err != nil {
+			t.Fatal("failed to create git backend: " + err.Error())
+		}
+		os.RemoveAll(pathutil.CacheDir())
+		_, _, err = NewTemplateFetcher([]string{src}, git).Fetch(reposPath, nil)
+		return err
+	}
+	// requested returns a handler which records requested hosts
+	requested := func(hosts *[]string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*hosts = append(*hosts, r.Host)
+			http.NotFound(w, r)
+		})
+	}
+
+	// (A)
+	var proxied []string
+	proxy := httptest.NewServer(requested(&proxied))
+	defer proxy.Close()
+	cfg := newHTTPConfig()
+	cfg.HTTP.Proxy = proxy.URL
+	fetch(cfg, "http://templates.example.com/team/templates")
+	if len(proxied) == 0 || proxied[0] != "templates.example.com" {
+		t.Errorf("expected the source is cloned via proxy but got requests of %v", proxied)
+	}
+
+	// (B)
+	var hosts []string
+	srv := httptest.NewUnstartedServer(requested(&hosts))
+	srv.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+	fetch(newHTTPConfig(), srv.URL+"/templates")
+	if len(hosts) != 0 {
+		t.Errorf("expected the certificate is not trusted without ca_file but got requests of %v", hosts)
+	}
+	caFile := filepath.Join(dir, "ca.pem")
+	content := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := ioutil.WriteFile(caFile, content, 0644); err != nil {
+		t.Fatal("failed to write CA file: " + err.Error())
+	}
+	cfg = newHTTPConfig()
+	cfg.HTTP.CAFile = caFile
+	fetch(cfg, srv.URL+"/templates")
+	if len(hosts) == 0 {
+		t.Error("expected the source is cloned with ca_file but no requests")
+	}
+
+	// (C)
+	done := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer slow.Close()
+	defer close(done)
+	cfg = newHTTPConfig()
+	cfg.HTTP.Timeout = "100ms"
+	start := time.Now()
+	if err := fetch(cfg, slow.URL+"/templates"); err == nil {
+		t.Error("expected fetching from slow source fails")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected cloning times out after 100ms but took %s", elapsed)
+	}
+}
@@ -0,0 +1,236 @@
+package subcmd
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/fileutil"
+	"github.com/vim-volt/volt/internal/builder"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/plugconf"
+	"github.com/vim-volt/volt/transaction"
+)
+
+func init() {
+	cmdMap["add"] = &addCmd{}
+}
+
+type addCmd struct {
+	helped bool
+	name   string
+	link   bool
+}
+
+func (cmd *addCmd) ProhibitRootExecution(args []string) bool { return true }
+
+func (cmd *addCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Println(`
+Usage
+  volt add [-help] [-name {repository}] [-link] {directory}
+
+Quick example
+  $ volt add ~/.vim/plugin                        # will add localhost/local/plugin
+  $ volt add -name localhost/my/vimdir ~/.vim     # will add localhost/my/vimdir
+  $ volt add -link ~/src/my-plugin                # will symlink the directory instead of copying
+
+Description
+  Import an existing local {directory} as a static repository.
+
+  {directory} is copied to $VOLTPATH/repos/{repository}
+  (or a symbolic link to {directory} is created there if -link was given),
+  then {repository} is added to lock.json and current profile,
+  a skeleton plugconf is created at $VOLTPATH/plugconf/{repository}.vim,
+  and ~/.vim/pack/volt is rebuilt.
+
+  If -name was not given, {repository} is "localhost/local/{basename of directory}".
+  Symbolic links, named pipes, sockets, and devices under {directory} are not copied.
+
+  After importing, the repository can be managed like other plugins.
+  "volt rm -r {repository}" removes the copied directory
+  (or only the symbolic link if -link was given).
+
+Options`)
+		fs.PrintDefaults()
+		fmt.Println()
+		cmd.helped = true
+	}
+	fs.StringVar(&cmd.name, "name", "", "repository name (default: localhost/local/{basename of directory})")
+	fs.BoolVar(&cmd.link, "link", false, "create a symbolic link to the directory instead of copying it")
+	return fs
+}
+
+func (cmd *addCmd) Run(args []string) *Error {
+	dir, reposPath, err := cmd.parseArgs(args)
+	if err == ErrShowedHelp {
+		return nil
+	}
+	if err != nil {
+		return &Error{Code: 10, Msg: "Failed to parse args: " + err.Error()}
+	}
+
+	err = cmd.doAdd(dir, reposPath)
+	if err != nil {
+		return &Error{Code: 11, Msg: err.Error()}
+	}
+
+	return nil
+}
+
+func (cmd *addCmd) parseArgs(args []string) (string, pathutil.ReposPath, error) {
+	fs := cmd.FlagSet()
+	fs.Parse(args)
+	if cmd.helped {
+		return "", "", ErrShowedHelp
+	}
+
+	if len(fs.Args()) != 1 {
+		fs.Usage()
+		return "", "", errors.New("exactly one directory must be given")
+	}
+
+	dir, err := filepath.Abs(fs.Arg(0))
+	if err != nil {
+		return "", "", err
+	}
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return "", "", errors.Errorf("'%s' is not a directory", fs.Arg(0))
+	}
+
+	name := cmd.name
+	if name == "" {
+		name = "localhost/local/" + filepath.Base(dir)
+	}
+	reposPath, err := pathutil.NormalizeRepos(name)
+	if err != nil {
+		return "", "", err
+	}
+	return dir, reposPath, nil
+}
+
+func (cmd *addCmd) doAdd(dir string, reposPath pathutil.ReposPath) (err error) {
+	// Read lock.json
+	lockJSON, err := lockjson.Read()
+	if err != nil {
+		err = errors.Wrap(err, "could not read lock.json")
+		return
+	}
+	if lockJSON.Repos.FindByPath(reposPath) != nil {
+		err = errors.Errorf("'%s' already exists in lock.json", reposPath)
+		return
+	}
+	profile, err := lockJSON.Profiles.FindByName(lockJSON.CurrentProfileName)
+	if err != nil {
+		// this must not be occurred because lockjson.Read()
+		// validates if the matching profile exists
+		return
+	}
+
+	fullReposPath := reposPath.FullPath()
+	if pathutil.Exists(fullReposPath) {
+		err = errors.Errorf("'%s' already exists", fullReposPath)
+		return
+	}
+	if strings.HasPrefix(fullReposPath, dir+string(filepath.Separator)) {
+		err = errors.Errorf("cannot import '%s' into itself", dir)
+		return
+	}
+
+	// Begin transaction
+	trx, err := transaction.Start()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if e := trx.Done(); e != nil {
+			err = e
+		}
+	}()
+
+	// Read config.toml
+	cfg, err := config.Read()
+	if err != nil {
+		err = errors.Wrap(err, "could not read config.toml")
+		return
+	}
+
+	// Copy or symlink the directory to $VOLTPATH/repos/{repository}
+	if err = cmd.importDir(dir, fullReposPath); err != nil {
+		os.RemoveAll(fullReposPath)
+		fileutil.RemoveDirs(filepath.Dir(fullReposPath))
+		err = errors.Wrapf(err, "failed to import '%s'", dir)
+		return
+	}
+
+	// Create skeleton plugconf
+	if *cfg.Get.CreateSkeletonPlugconf {
+		if err = cmd.createPlugconf(reposPath); err != nil {
+			err = errors.Wrap(err, "failed to create plugconf")
+			return
+		}
+	}
+
+	// Add repos to 'repos' and 'profiles[]/repos_path'
+	lockJSON.Repos = append(lockJSON.Repos, lockjson.Repos{
+		Type: lockjson.ReposStaticType,
+		Path: reposPath,
+	})
+	if !profile.ReposPath.Contains(reposPath) {
+		profile.ReposPath = append(profile.ReposPath, reposPath)
+	}
+
+	// Write to lock.json
+	err = lockJSON.Write()
+	if err != nil {
+		err = errors.Wrap(err, "could not write to lock.json")
+		return
+	}
+
+	// Build ~/.vim/pack/volt dir
+	err = builder.Build(false)
+	if err != nil {
+		err = errors.Wrap(err, "could not build "+pathutil.VimVoltDir())
+		return
+	}
+
+	fmt.Printf(fmtAddedRepos+"\n", reposPath)
+	return
+}
+
+func (cmd *addCmd) importDir(dir, fullReposPath string) error {
+	if err := os.MkdirAll(filepath.Dir(fullReposPath), 0755); err != nil {
+		return err
+	}
+	if cmd.link {
+		logger.Debugf("Creating a symbolic link %s -> %s ...", fullReposPath, dir)
+		return os.Symlink(dir, fullReposPath)
+	}
+	logger.Debugf("Copying %s to %s ...", dir, fullReposPath)
+	return fileutil.CopyDir(dir, fullReposPath, nil, 0755, builder.BuildModeInvalidType)
+}
+
+func (*addCmd) createPlugconf(reposPath pathutil.ReposPath) error {
+	path := reposPath.Plugconf()
+	if pathutil.Exists(path) {
+		logger.Debugf("plugconf '%s' exists... skip", path)
+		return nil
+	}
+	var tmpl *plugconf.Template
+	content, merr := tmpl.Generate(path)
+	if merr.ErrorOrNil() != nil {
+		return merr
+	}
+	os.MkdirAll(filepath.Dir(path), 0755)
+	return ioutil.WriteFile(path, content, 0644)
+}
@@ -0,0 +1,159 @@
+package subcmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/fileutil"
+	"github.com/vim-volt/volt/internal/testutil"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// Checks:
+// (A) Does not show `[ERROR]`, `[WARN]` messages
+// (B) Exit with zero status
+// (C) The directory is copied (or symlinked if -link) to `$VOLTPATH/repos/<repos>/`
+// (D) Plugconf file is created at `$VOLTPATH/plugconf/<repos>.vim`
+// (E) Directory is copied to `~/.vim/pack/volt/<repos>/`, and the contents are same
+// (F) Entry is added to lock.json as a static repository
+// (G) Output contains "+ {repos} > added repository to current profile"
+
+// Run `volt add [-link] {dir}` (A, B, C, D, E, F, G)
+func TestVoltAdd(t *testing.T) {
+	for _, link := range []bool{false, true} {
+		t.Run(fmt.Sprintf("link=%v", link), func(t *testing.T) {
+			testGetMatrix(t, func(t *testing.T, strategy string) {
+				// =============== setup =============== //
+
+				testutil.SetUpEnv(t)
+				defer testutil.CleanUpEnv(t)
+				testutil.InstallConfig(t, "strategy-"+strategy+".toml")
+				dir := setUpAddDir(t)
+
+				// =============== run =============== //
+
+				args := []string{"add"}
+				if link {
+					args = append(args, "-link")
+				}
+				out, err := testutil.RunVolt(append(args, dir)...)
+				// (A, B)
+				testutil.SuccessExit(t, out, err)
+				reposPath := pathutil.ReposPath("localhost/local/hello")
+
+				// (C)
+				fi, err := os.Lstat(reposPath.FullPath())
+				if err != nil {
+					t.Fatal("repos was not created: " + err.Error())
+				}
+				if link != (fi.Mode()&os.ModeSymlink != 0) {
+					t.Errorf("expected symlink=%v but got mode %v", link, fi.Mode())
+				}
+				if !pathutil.Exists(filepath.Join(reposPath.FullPath(), "plugin", "hello.vim")) {
+					t.Error("plugin/hello.vim does not exist in " + reposPath.FullPath())
+				}
+
+				// (D)
+				if !pathutil.Exists(reposPath.Plugconf()) {
+					t.Error("plugconf was not created: " + reposPath.Plugconf())
+				}
+
+				// (E)
+				if !pathutil.Exists(reposPath.EncodeToPlugDirName()) {
+					t.Error("vim repos was not created: " + reposPath.EncodeToPlugDirName())
+				}
+				checkCopied(t, reposPath, strategy)
+
+				// (F)
+				testReposPathWereAdded(t, reposPath)
+				lockJSON, err := lockjson.Read()
+				if err != nil {
+					t.Fatal("lockjson.Read() returned non-nil error: " + err.Error())
+				}
+				if r := lockJSON.Repos.FindByPath(reposPath); r == nil || r.Type != lockjson.ReposStaticType {
+					t.Errorf("expected static repository in lock.json but got %+v", r)
+				}
+
+				// (G)
+				if !strings.Contains(string(out), fmt.Sprintf(fmtAddedRepos, reposPath)) {
+					t.Errorf("expected output contains %q but got: %s", fmt.Sprintf(fmtAddedRepos, reposPath), string(out))
+				}
+			})
+		})
+	}
+}
+
+// Run `volt add -name {repos} {dir}` (A, B, C, F)
+func TestVoltAddName(t *testing.T) {
+	// =============== setup =============== //
+
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	dir := setUpAddDir(t)
+
+	// =============== run =============== //
+
+	out, err := testutil.RunVolt("add", "-name", "localhost/my/vimdir", dir)
+	// (A, B)
+	testutil.SuccessExit(t, out, err)
+	reposPath := pathutil.ReposPath("localhost/my/vimdir")
+
+	// (C)
+	if !pathutil.Exists(filepath.Join(reposPath.FullPath(), "plugin", "hello.vim")) {
+		t.Error("plugin/hello.vim does not exist in " + reposPath.FullPath())
+	}
+
+	// (F)
+	testReposPathWereAdded(t, reposPath)
+}
+
+// [error] Run `volt add {dir}` when the repository already exists (!A, !B, !F)
+func TestErrVoltAddExists(t *testing.T) {
+	// =============== setup =============== //
+
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	dir := setUpAddDir(t)
+	reposPath := pathutil.ReposPath("localhost/local/hello")
+	os.MkdirAll(reposPath.FullPath(), 0755)
+
+	// =============== run =============== //
+
+	out, err := testutil.RunVolt("add", dir)
+	// (!A, !B)
+	testutil.FailExit(t, out, err)
+
+	// (!F)
+	testReposPathWereNotAdded(t, reposPath)
+}
+
+// [error] Run `volt add {file}` (!A, !B)
+func TestErrVoltAddNotDirectory(t *testing.T) {
+	// =============== setup =============== //
+
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	dir := setUpAddDir(t)
+
+	// =============== run =============== //
+
+	out, err := testutil.RunVolt("add", filepath.Join(dir, "plugin", "hello.vim"))
+	// (!A, !B)
+	testutil.FailExit(t, out, err)
+}
+
+// setUpAddDir copies testdata/local/hello to a temporary directory under
+// $HOME, because -link makes the build write doc/tags into the directory.
+func setUpAddDir(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(os.Getenv("HOME"), "src", "hello")
+	src := filepath.Join(testutil.TestdataDir(), "local", "hello")
+	if err := fileutil.CopyDir(src, dir, nil, 0755, 0); err != nil {
+		t.Fatal("failed to copy testdata: " + err.Error())
+	}
+	return dir
+}
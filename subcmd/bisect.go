@@ -0,0 +1,342 @@
+package subcmd
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/plugconf"
+)
+
+func init() {
+	cmdMap["bisect"] = &bisectCmd{}
+}
+
+type bisectCmd struct {
+	helped  bool
+	profile string
+	run     string
+	step    int
+	stdin   *bufio.Reader
+}
+
+// errBisectQuit is returned when user quit bisecting.
+var errBisectQuit = errors.New("quit bisecting")
+
+func (cmd *bisectCmd) ProhibitRootExecution(args []string) bool { return true }
+
+func (cmd *bisectCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  volt bisect [-help] [-p {profile}] [-run {command}] [-- {vim args}]
+
+Quick example
+  $ volt bisect                    # find the plugins which cause a problem of current profile interactively
+  $ volt bisect -p foo -- a.txt    # find the plugins of "foo" profile, and open a.txt in each step
+  $ volt bisect -run 'vim -u "$VOLT_BISECT_VIMRC" -es -c "if exists(\":Foo\") | cquit | endif" -c "qa!"'
+                                   # find the plugins which define :Foo command non-interactively
+
+Description
+  Find the minimal set of plugins which cause a problem, from the plugins of {profile} (default: current profile).
+
+  In each step, volt launches Vim with a part of the plugins the same way as "volt run", and asks whether the problem occurred or not after Vim exits. Type "good" if the problem did not occur, or "bad" if it occurred. The dependencies of the plugins (s:depends() in plugconf) are always loaded together.
+  Finally, the minimal set of the suspect plugins is shown.
+
+  If -run option was given, volt executes {command} by shell instead of launching Vim and asking, and decides it from the exit status of {command}: zero means "good", non-zero means "bad". In {command}, "vim" loads the plugins of each step by $VIMINIT environment variable. But note that $VIMINIT is not used when "vim" is invoked with "-u" or "-es" options. Then use "-u $VOLT_BISECT_VIMRC" options for such cases.
+
+  ~/.vim/pack/volt/ directory and $VOLTPATH/lock.json are not modified.` + "\n\n")
+		fmt.Println("Options")
+		fs.PrintDefaults()
+		fmt.Println()
+		cmd.helped = true
+	}
+	fs.StringVar(&cmd.profile, "p", "", "profile name (default: current profile)")
+	fs.StringVar(&cmd.run, "run", "", "test command which exits with non-zero status when the problem occurred")
+	return fs
+}
+
+func (cmd *bisectCmd) Run(args []string) *Error {
+	vimArgs, err := cmd.parseArgs(args)
+	if err == ErrShowedHelp {
+		return nil
+	}
+	if err != nil {
+		return &Error{Code: 10, Msg: "Failed to parse args: " + err.Error()}
+	}
+
+	err = cmd.doBisect(vimArgs)
+	if err == errBisectQuit {
+		logger.Info("Quit bisecting")
+		return nil
+	}
+	if err != nil {
+		return &Error{Code: 11, Msg: "Failed to bisect: " + err.Error()}
+	}
+	return nil
+}
+
+func (cmd *bisectCmd) parseArgs(args []string) ([]string, error) {
+	// Split arguments at "--" before flag.FlagSet.Parse() removes it
+	var vimArgs []string
+	for i := range args {
+		if args[i] == "--" {
+			vimArgs = args[i+1:]
+			args = args[:i]
+			break
+		}
+	}
+
+	fs := cmd.FlagSet()
+	fs.Parse(args)
+	if cmd.helped {
+		return nil, ErrShowedHelp
+	}
+	if len(fs.Args()) > 0 {
+		return nil, errors.Errorf("invalid argument '%s': use '--' to pass arguments to vim", fs.Args()[0])
+	}
+	if cmd.run != "" && len(vimArgs) > 0 {
+		return nil, errors.New("-run option and vim arguments cannot be specified together")
+	}
+	return vimArgs, nil
+}
+
+func (cmd *bisectCmd) doBisect(vimArgs []string) error {
+	lockJSON, err := lockjson.Read()
+	if err != nil {
+		return errors.Wrap(err, "could not read lock.json")
+	}
+
+	profileName := cmd.profile
+	if profileName == "" {
+		profileName = lockJSON.CurrentProfileName
+	}
+	profile, err := lockJSON.Profiles.FindByName(profileName)
+	if err != nil {
+		return err
+	}
+	reposList, err := lockJSON.GetReposListByProfile(profile)
+	if err != nil {
+		return err
+	}
+	depsMap, err := plugconf.DepsMap(reposList)
+	if err != nil {
+		return err
+	}
+
+	candidates := make(pathutil.ReposPathList, 0, len(reposList))
+	for i := range reposList {
+		candidates = append(candidates, reposList[i].Path)
+	}
+
+	test := func(reposPathList pathutil.ReposPathList) (bool, error) {
+		reposPathList = cmd.withDependencies(reposPathList, depsMap)
+		tmpLockJSON, err := makeTemporaryLockJSON(lockJSON, profileName, reposPathList)
+		if err != nil {
+			return false, err
+		}
+		cmd.step++
+		logger.Infof("Step %d: testing %d plugin(s) of profile '%s' ...", cmd.step, len(reposPathList), profileName)
+		for _, reposPath := range reposPathList {
+			logger.Debug("  " + reposPath.String())
+		}
+		return cmd.isBad(tmpLockJSON, vimArgs)
+	}
+
+	// Check the problem occurs with all plugins, and does not occur without
+	// plugins
+	bad, err := test(candidates)
+	if err != nil {
+		return err
+	}
+	if !bad {
+		return errors.New("the problem did not occur with all plugins of profile '" + profileName + "'")
+	}
+	bad, err = test(pathutil.ReposPathList{})
+	if err != nil {
+		return err
+	}
+	if bad {
+		return errors.New("the problem occurred without plugins (the cause may be vimrc or Vim itself)")
+	}
+
+	suspects, err := cmd.minimize(test, pathutil.ReposPathList{}, candidates)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Suspect plugin(s) of profile '%s' (found in %d steps):\n", profileName, cmd.step)
+	for _, reposPath := range suspects {
+		fmt.Println("  " + reposPath.String())
+		deps := cmd.withDependencies(pathutil.ReposPathList{reposPath}, depsMap)[1:]
+		if len(deps) > 0 {
+			fmt.Println("    (depends on: " + strings.Join(deps.Strings(), ", ") + ")")
+		}
+	}
+	return nil
+}
+
+// minimize returns the minimal subset of candidates which reproduces the
+// problem together with fixed.
+// The problem must be reproduced with fixed + candidates, and must not be
+// reproduced with fixed.
+func (cmd *bisectCmd) minimize(test func(pathutil.ReposPathList) (bool, error), fixed, candidates pathutil.ReposPathList) (pathutil.ReposPathList, error) {
+	if len(candidates) <= 1 {
+		return candidates, nil
+	}
+	half := len(candidates) / 2
+	first := candidates[:half:half]
+	second := candidates[half:]
+
+	// The problem occurs with only one of the halves
+	for _, c := range []pathutil.ReposPathList{first, second} {
+		bad, err := test(cmd.union(fixed, c))
+		if err != nil {
+			return nil, err
+		}
+		if bad {
+			return cmd.minimize(test, fixed, c)
+		}
+	}
+
+	// The problem occurs with the plugins of both halves
+	firstSuspects, err := cmd.minimize(test, cmd.union(fixed, second), first)
+	if err != nil {
+		return nil, err
+	}
+	secondSuspects, err := cmd.minimize(test, cmd.union(fixed, firstSuspects), second)
+	if err != nil {
+		return nil, err
+	}
+	return cmd.union(firstSuspects, secondSuspects), nil
+}
+
+func (*bisectCmd) union(list1, list2 pathutil.ReposPathList) pathutil.ReposPathList {
+	result := make(pathutil.ReposPathList, 0, len(list1)+len(list2))
+	result = append(result, list1...)
+	for _, reposPath := range list2 {
+		if !result.Contains(reposPath) {
+			result = append(result, reposPath)
+		}
+	}
+	return result
+}
+
+// withDependencies returns reposPathList and the plugins which they depend on
+// (recursively).
+func (*bisectCmd) withDependencies(reposPathList pathutil.ReposPathList, depsMap map[pathutil.ReposPath]pathutil.ReposPathList) pathutil.ReposPathList {
+	result := make(pathutil.ReposPathList, 0, len(reposPathList))
+	var visit func(pathutil.ReposPath)
+	visit = func(reposPath pathutil.ReposPath) {
+		if result.Contains(reposPath) {
+			return
+		}
+		result = append(result, reposPath)
+		for _, dep := range depsMap[reposPath] {
+			visit(dep)
+		}
+	}
+	for _, reposPath := range reposPathList {
+		visit(reposPath)
+	}
+	return result
+}
+
+// isBad launches Vim or executes the test command with the plugins of
+// lockJSON, and returns true if the problem occurred.
+func (cmd *bisectCmd) isBad(lockJSON *lockjson.LockJSON, vimArgs []string) (bad bool, result error) {
+	result = buildTemporaryVimDir(lockJSON, func(vimDir, runVimrc string) error {
+		// Vim handles interrupts by itself.
+		// Catch them (and SIGTERM) here not to exit before removing the
+		// temporary directory.
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sigCh)
+
+		if cmd.run != "" {
+			var err error
+			bad, err = cmd.runTestCommand(runVimrc)
+			return err
+		}
+
+		vimExePath, err := pathutil.VimExecutable()
+		if err != nil {
+			return err
+		}
+		args := makeRunVimArgs(vimDir, runVimrc, vimArgs)
+		logger.Debugf("Executing '%s %s' ...", vimExePath, strings.Join(args, " "))
+		vimCmd := exec.Command(vimExePath, args...)
+		vimCmd.Stdin = os.Stdin
+		vimCmd.Stdout = os.Stdout
+		vimCmd.Stderr = os.Stderr
+		if err := vimCmd.Run(); err != nil {
+			logger.Warn("Vim exited with error: " + err.Error())
+		}
+		bad, err = cmd.ask()
+		return err
+	})
+	return
+}
+
+func (cmd *bisectCmd) runTestCommand(runVimrc string) (bool, error) {
+	var testCmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		testCmd = exec.Command("cmd", "/c", cmd.run)
+	} else {
+		testCmd = exec.Command("sh", "-c", cmd.run)
+	}
+	vimrc := strings.Replace(runVimrc, "'", "''", -1)
+	testCmd.Env = append(os.Environ(),
+		"VIMINIT=execute 'source' fnameescape('"+vimrc+"')",
+		"VOLT_BISECT_VIMRC="+runVimrc,
+	)
+	testCmd.Stdout = os.Stdout
+	testCmd.Stderr = os.Stderr
+	logger.Debugf("Executing '%s' ...", cmd.run)
+	err := testCmd.Run()
+	if err == nil {
+		logger.Info("  => good")
+		return false, nil
+	}
+	if _, ok := err.(*exec.ExitError); ok {
+		logger.Info("  => bad")
+		return true, nil
+	}
+	return false, errors.Wrap(err, "could not execute test command")
+}
+
+func (cmd *bisectCmd) ask() (bool, error) {
+	if cmd.stdin == nil {
+		cmd.stdin = bufio.NewReader(os.Stdin)
+	}
+	for {
+		fmt.Print("Did the problem occur? [good/bad/quit]: ")
+		line, err := cmd.stdin.ReadString('\n')
+		answer := strings.ToLower(strings.TrimSpace(line))
+		switch {
+		case answer == "":
+			if err != nil {
+				return false, errBisectQuit
+			}
+		case strings.HasPrefix("good", answer):
+			return false, nil
+		case strings.HasPrefix("bad", answer):
+			return true, nil
+		case strings.HasPrefix("quit", answer):
+			return false, errBisectQuit
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package subcmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/internal/testutil"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// Checks:
+// (A) Does not show `[ERROR]`, `[WARN]` messages
+// (B) Exit with zero status
+// (C) Shows the minimal set of suspect plugins
+// (D) Shows the dependencies of suspect plugins
+// (E) lock.json is not changed
+// (F) Temporary directory is removed
+//
+// * Run `volt bisect -run <command>` (<command>: fails with one plugin) (A, B, C, E, F)
+// * Run `volt bisect -run <command>` (<command>: fails with two plugins) (A, B, C, E, F)
+// * Run `volt bisect -run <command>` (<command>: fails with a plugin which has dependency) (A, B, C, D, E, F)
+// * Run `volt bisect -run <command>` (<command>: does not fail with all plugins) (!A, !B, E, F)
+// * Run `volt bisect -run <command>` (<command>: fails without plugins) (!A, !B, E, F)
+func TestVoltBisect(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		condition string
+		success   bool
+		suspects  []string
+		deps      string
+	}{
+		{"one plugin", `exists(":BisectB") == 2`, true, []string{"localhost/local/b"}, ""},
+		{"two plugins", `exists(":BisectA") == 2 && exists(":BisectD") == 2`, true, []string{"localhost/local/a", "localhost/local/d"}, ""},
+		{"dependency", `exists(":BisectC") == 2`, true, []string{"localhost/local/c"}, "localhost/local/b"},
+		{"not reproduced", `0`, false, nil, ""},
+		{"without plugins", `1`, false, nil, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			testProfileMatrix(t, func(t *testing.T, strategy string) {
+				// =============== setup =============== //
+
+				testutil.SetUpEnv(t)
+				defer testutil.CleanUpEnv(t)
+				testutil.InstallConfig(t, "strategy-"+strategy+".toml")
+				setUpBisectRepos(t)
+
+				oldLockJSON, err := ioutil.ReadFile(pathutil.LockJSON())
+				if err != nil {
+					t.Fatal("failed to read lock.json: " + err.Error())
+				}
+
+				// =============== run =============== //
+
+				testCmd := `vim -u "$VOLT_BISECT_VIMRC" -es -c 'if ` + tt.condition + ` | cquit | endif' -c 'qa!'`
+				out, err := testutil.RunVolt("bisect", "-run", testCmd)
+				if tt.success {
+					// (A, B)
+					testutil.SuccessExit(t, out, err)
+					// (C)
+					var suspects []string
+					for _, line := range strings.Split(string(out), "\n") {
+						if strings.HasPrefix(line, "  localhost/") {
+							suspects = append(suspects, strings.TrimSpace(line))
+						}
+					}
+					if strings.Join(suspects, ",") != strings.Join(tt.suspects, ",") {
+						t.Errorf("expected suspects are %v but got %v: %s", tt.suspects, suspects, string(out))
+					}
+					// (D)
+					if tt.deps != "" && !bytes.Contains(out, []byte("(depends on: "+tt.deps+")")) {
+						t.Errorf("expected output shows dependency %q but got: %s", tt.deps, string(out))
+					}
+				} else {
+					// (!A, !B)
+					testutil.FailExit(t, out, err)
+				}
+
+				// (E)
+				lockJSON, err := ioutil.ReadFile(pathutil.LockJSON())
+				if err != nil {
+					t.Fatal("failed to read lock.json: " + err.Error())
+				}
+				if !bytes.Equal(oldLockJSON, lockJSON) {
+					t.Error("expected lock.json is not changed but changed")
+				}
+
+				// (F)
+				if matches, _ := filepath.Glob(filepath.Join(pathutil.TempDir(), "run-*")); len(matches) > 0 {
+					t.Errorf("expected temporary directory was removed but exists: %v", matches)
+				}
+			})
+		})
+	}
+}
+
+// setUpBisectRepos installs static repositories localhost/local/{a,b,c,d}
+// which define :BisectA, :BisectB, :BisectC, :BisectD commands.
+// localhost/local/c depends on localhost/local/b.
+func setUpBisectRepos(t *testing.T) {
+	for _, name := range []string{"a", "b", "c", "d"} {
+		reposPath := pathutil.ReposPath("localhost/local/" + name)
+		dir := filepath.Join(reposPath.FullPath(), "plugin")
+		os.MkdirAll(dir, 0777)
+		src := "command! Bisect" + strings.ToUpper(name) + " echo '" + name + "'\n"
+		if err := ioutil.WriteFile(filepath.Join(dir, name+".vim"), []byte(src), 0644); err != nil {
+			t.Fatal("failed to write plugin: " + err.Error())
+		}
+		out, err := testutil.RunVolt("get", reposPath.String())
+		testutil.SuccessExit(t, out, err)
+	}
+
+	plugconf := pathutil.ReposPath("localhost/local/c").Plugconf()
+	os.MkdirAll(filepath.Dir(plugconf), 0777)
+	src := "function! s:depends() abort\n  return ['localhost/local/b']\nendfunction\n"
+	if err := ioutil.WriteFile(plugconf, []byte(src), 0644); err != nil {
+		t.Fatal("failed to write plugconf: " + err.Error())
+	}
+}
@@ -1,12 +1,14 @@
 package subcmd
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
 
-	"github.com/vim-volt/volt/subcmd/builder"
-	"github.com/vim-volt/volt/transaction"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/pkg/volt"
 )
 
 func init() {
@@ -14,8 +16,10 @@ func init() {
 }
 
 type buildCmd struct {
-	helped bool
-	full   bool
+	helped   bool
+	full     bool
+	dryRun   bool
+	rollback bool
 }
 
 func (cmd *buildCmd) ProhibitRootExecution(args []string) bool { return true }
@@ -26,11 +30,13 @@ func (cmd *buildCmd) FlagSet() *flag.FlagSet {
 	fs.Usage = func() {
 		fmt.Print(`
 Usage
-  volt build [-help] [-full]
+  volt build [-help] [-full] [-dry-run] [-rollback]
 
 Quick example
-  $ volt build        # builds directories under ~/.vim/pack/volt
-  $ volt build -full  # full build (remove ~/.vim/pack/volt, and re-create all)
+  $ volt build            # builds directories under ~/.vim/pack/volt
+  $ volt build -full      # full build (remove ~/.vim/pack/volt, and re-create all)
+  $ volt build -dry-run   # shows what will be changed, without changing any files
+  $ volt build -rollback  # restores ~/.vim/pack/volt to the previous build
 
 Description
   Build ~/.vim/pack/volt/opt/ directory:
@@ -42,17 +48,27 @@ Description
   ~/.vim/pack/volt/build-info.json is a file which holds the information that what vim plugins are installed in ~/.vim/pack/volt/ and its type (git repository, static repository, or system repository), its version. A user normally doesn't need to know the contents of build-info.json .
 
   If -full option was given, remove all directories in ~/.vim/pack/volt/opt/ , and copy repositories' files into above vim directories.
-  Otherwise, it will perform smart build: copy / remove only changed repositories' files.` + "\n\n")
+  Otherwise, it will perform smart build: copy / remove only changed repositories' files.
+
+  ~/.vim/pack/volt is a symlink (a junction on Windows) to a build in ~/.vim/pack/.volt-builds/ . A new build is built in ~/.vim/pack/.volt-staging/ at first, and it is moved to ~/.vim/pack/.volt-builds/ after the build succeeded. Then ~/.vim/pack/volt is switched to it by renaming a new symlink over the old one, so Vim sees either the old build or the new build (on Windows, the old junction is removed just before the rename). ~/.vim/pack/volt is not changed if the build failed. The previous build is kept, and ~/.vim/pack/.volt-prev refers to it. Older builds are removed.
+
+  If -rollback option was given, switch ~/.vim/pack/volt to the previous build. Running it again restores the build before rollback. Note that vimrc, gvimrc, and ~/.vim/pack/volt-{profile}/ (see per_profile below) are not rolled back.
+
+  If -dry-run option was given, show the build plan and exit without changing any files. The plan lists repositories to add / update / remove in ~/.vim/pack/volt/opt/ , vimrc and gvimrc to install (or refuse to install because they were not generated by volt), and the unified diff of bundled plugconf.
+
+  If per_profile = true is set in [build] section of config.toml, repositories of all profiles are installed into ~/.vim/pack/volt/opt/ , and bundled plugconf of each profile is generated under ~/.vim/pack/volt-{profile}/ . A profile is selected at Vim startup by $VOLT_PROFILE or the first line of the nearest .volt-profile file (current profile is used if neither is found), so switching profiles needs no rebuild.` + "\n\n")
 		fmt.Println("Options")
 		fs.PrintDefaults()
 		fmt.Println()
 		cmd.helped = true
 	}
 	fs.BoolVar(&cmd.full, "full", false, "full build")
+	fs.BoolVar(&cmd.dryRun, "dry-run", false, "show the build plan without changing any files")
+	fs.BoolVar(&cmd.rollback, "rollback", false, "restore the previous build")
 	return fs
 }
 
-func (cmd *buildCmd) Run(args []string) (result *Error) {
+func (cmd *buildCmd) Run(args []string) *Error {
 	// Parse args
 	fs := cmd.FlagSet()
 	fs.Parse(args)
@@ -60,23 +76,93 @@ func (cmd *buildCmd) Run(args []string) (result *Error) {
 		return nil
 	}
 
-	// Begin transaction
-	trx, err := transaction.Start()
+	result, err := volt.Build(context.Background(), volt.BuildOptions{
+		Full:     cmd.full,
+		DryRun:   cmd.dryRun,
+		Rollback: cmd.rollback,
+		Logger:   logger.Default(),
+	})
 	if err != nil {
-		result = &Error{Code: 11, Msg: "Failed to begin transaction: " + err.Error()}
-		return
+		return cmd.newError(err)
+	}
+	if cmd.dryRun {
+		cmd.printPlan(result)
+	}
+	return nil
+}
+
+// newError returns the error of the step where volt.Build() failed.
+// -dry-run and -rollback fail with the same code because they are not given
+// at the same time.
+func (cmd *buildCmd) newError(err error) *Error {
+	e, ok := err.(*volt.BuildError)
+	if !ok {
+		return &Error{Code: 12, Msg: "Failed to build: " + err.Error()}
+	}
+	switch e.Step {
+	case volt.BuildStepBeginTransaction:
+		return &Error{Code: 11, Msg: "Failed to begin transaction: " + e.Err.Error()}
+	case volt.BuildStepEndTransaction:
+		return &Error{Code: 13, Msg: "Failed to end transaction: " + e.Err.Error()}
+	case volt.BuildStepPlan:
+		return &Error{Code: 14, Msg: "Failed to make build plan: " + e.Err.Error()}
+	case volt.BuildStepRollback:
+		return &Error{Code: 14, Msg: "Failed to rollback: " + e.Err.Error()}
+	default:
+		return &Error{Code: 12, Msg: "Failed to build: " + e.Err.Error()}
+	}
+}
+
+func (cmd *buildCmd) printPlan(plan *volt.BuildResult) {
+	full := "no"
+	if plan.Full {
+		full = "yes"
+	}
+	fmt.Printf("Build plan (strategy: %s, full build: %s)\n", plan.Strategy, full)
+
+	fmt.Println()
+	fmt.Println("Repositories in " + pathutil.VimVoltOptDir() + ":")
+	if len(plan.Repos) == 0 {
+		fmt.Println("  (no changes)")
 	}
-	defer func() {
-		if err := trx.Done(); err != nil {
-			result = &Error{Code: 13, Msg: "Failed to end transaction: " + err.Error()}
+	for i := range plan.Repos {
+		change := &plan.Repos[i]
+		line := fmt.Sprintf("  %-7s %s", change.Action, change.Path)
+		if change.Reason != "" {
+			line += " (" + change.Reason + ")"
 		}
-	}()
+		if change.Err != nil {
+			line += " (error: " + change.Err.Error() + ")"
+		}
+		fmt.Println(line)
+	}
 
-	err = builder.Build(cmd.full)
-	if err != nil {
-		result = &Error{Code: 12, Msg: "Failed to build: " + err.Error()}
-		return
+	fmt.Println()
+	fmt.Println("RC files:")
+	if len(plan.RCFiles) == 0 {
+		fmt.Println("  (no changes)")
+	}
+	for i := range plan.RCFiles {
+		change := &plan.RCFiles[i]
+		line := fmt.Sprintf("  %-7s %s", change.Action, change.Path)
+		if change.Err != nil {
+			line += " (" + change.Err.Error() + ")"
+		}
+		fmt.Println(line)
 	}
 
-	return
+	fmt.Println()
+	fmt.Println("Bundled plugconf:")
+	if len(plan.Plugconfs) == 0 {
+		fmt.Println("  (no changes)")
+	}
+	for i := range plan.Plugconfs {
+		change := &plan.Plugconfs[i]
+		fmt.Printf("  %-7s %s\n", change.Action, change.Path)
+		if change.Diff != "" {
+			fmt.Println()
+			fmt.Print(change.Diff)
+			fmt.Println()
+		}
+	}
 }
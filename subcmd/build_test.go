@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -13,10 +14,11 @@ import (
 	"github.com/haya14busa/go-vimlparser"
 	"github.com/vim-volt/volt/config"
 	"github.com/vim-volt/volt/fileutil"
+	"github.com/vim-volt/volt/internal/builder"
 	"github.com/vim-volt/volt/internal/testutil"
 	"github.com/vim-volt/volt/lockjson"
 	"github.com/vim-volt/volt/pathutil"
-	"github.com/vim-volt/volt/subcmd/builder"
+	"github.com/vim-volt/volt/transaction"
 )
 
 // Checks:
@@ -496,6 +498,47 @@ func TestVoltBuildT4NoVimrcGvimrc(t *testing.T) {
 
 // ===========================================================
 
+// * (case t1) profile vimrc:exists (current profile and extended profile)
+//             profile gvimrc:not exist
+//             user vimrc:not exist
+//             user gvimrc:not exist
+//             vimrc magic comment:N/A
+//             gvimrc magic comment:N/A (F, G, !H)
+//   installed vimrc has the content of extended profile vimrc, and current
+//   profile vimrc in this order.
+func TestVoltBuildT1ExtendedProfileVimrcExists(t *testing.T) {
+	// =============== setup =============== //
+
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+
+	out, err := testutil.RunVolt("profile", "new", "base")
+	testutil.SuccessExit(t, out, err)
+	out, err = testutil.RunVolt("profile", "extend", "default", "base")
+	testutil.SuccessExit(t, out, err)
+	installProfileRC(t, "base", "vimrc-nomagic.vim", pathutil.ProfileVimrc)
+	installProfileRC(t, "default", "vimrc-nomagic.vim", pathutil.ProfileVimrc)
+
+	// =============== run =============== //
+
+	out, err = testutil.RunVolt("build")
+	// (A, B)
+	testutil.SuccessExit(t, out, err)
+
+	// (F, G, !H)
+	checkRCInstalled(t, 1, 1, 0, -1)
+
+	vimrc, err := ioutil.ReadFile(filepath.Join(pathutil.VimDir(), pathutil.Vimrc))
+	if err != nil {
+		t.Fatal("failed to read vimrc: " + err.Error())
+	}
+	baseIdx := bytes.Index(vimrc, []byte(filepath.Join(pathutil.RCDir("base"), pathutil.ProfileVimrc)))
+	defaultIdx := bytes.Index(vimrc, []byte(filepath.Join(pathutil.RCDir("default"), pathutil.ProfileVimrc)))
+	if baseIdx < 0 || defaultIdx < 0 || baseIdx > defaultIdx {
+		t.Errorf("expected vimrc of 'base' and 'default' are installed in this order but got:\n%s", string(vimrc))
+	}
+}
+
 // * Run `volt build` (repos: exists, vim repos: not exist) (git repository)
 // * Run `volt build -full` (repos: exists, vim repos: not exist) (git repository)
 //   (A, B, D, E, !F, !H, J, K)
@@ -800,6 +843,263 @@ func voltBuildStaticVimDirNewer(t *testing.T, full bool, strategy string) {
 	checkSyntax(t, bundledPlugconf)
 }
 
+// * Run `volt build` (repos: files were added) (static repository) (A, B, C, E)
+//   added files are installed, and doc/tags is generated
+// * Run `volt build` (repos: files were removed) (static repository) (A, B, C, E)
+//   removed files are removed from vim repos
+func TestVoltBuildStaticFilesChanged(t *testing.T) {
+	testBuildMatrix(t, voltBuildStaticFilesChanged)
+}
+
+func voltBuildStaticFilesChanged(t *testing.T, full bool, strategy string) {
+	// =============== setup =============== //
+
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	reposPath := pathutil.ReposPath("localhost/local/hello")
+	teardown := testutil.SetUpRepos(t, "hello", lockjson.ReposStaticType, []pathutil.ReposPath{reposPath}, strategy)
+	defer teardown()
+	testutil.InstallConfig(t, "strategy-"+strategy+".toml")
+	out, err := testutil.RunVolt("build")
+	testutil.SuccessExit(t, out, err)
+
+	args := []string{"build"}
+	if full {
+		args = append(args, "-full")
+	}
+	added := []string{
+		filepath.Join("autoload", "hello.vim"),
+		filepath.Join("doc", "hello.txt"),
+	}
+	for _, tt := range []struct {
+		name   string
+		change func(path string) error
+		exists bool
+	}{
+		{"added", func(path string) error {
+			os.MkdirAll(filepath.Dir(path), 0777)
+			return ioutil.WriteFile(path, []byte("*hello.txt*\n"), 0644)
+		}, true},
+		{"removed", os.Remove, false},
+	} {
+		// =============== run =============== //
+
+		for _, name := range added {
+			if err := tt.change(filepath.Join(reposPath.FullPath(), name)); err != nil {
+				t.Fatalf("failed to change file (%s): %s", tt.name, err.Error())
+			}
+		}
+		out, err = testutil.RunVolt(args...)
+		// (A, B)
+		testutil.SuccessExit(t, out, err)
+
+		// (C) and (D)
+		checkBuildOutput(t, full, out, strategy)
+
+		// (E)
+		checkCopied(t, reposPath, strategy)
+		for _, name := range added {
+			path := filepath.Join(reposPath.EncodeToPlugDirName(), name)
+			if pathutil.Exists(path) != tt.exists {
+				t.Errorf("expected %s exists is %v (%s) but got %v", path, tt.exists, tt.name, !tt.exists)
+			}
+		}
+		if tt.exists {
+			tags := filepath.Join(reposPath.EncodeToPlugDirName(), "doc", "tags")
+			if !pathutil.Exists(tags) {
+				t.Errorf("expected %s was generated but does not exist", tags)
+			}
+		}
+	}
+}
+
+// * Run `volt build` (build.helptags = true) (A, B, E)
+//   doc/tags is generated
+// * Run `volt build` (build.helptags = false) (A, B, E)
+//   doc/tags is not generated
+func TestVoltBuildHelptags(t *testing.T) {
+	testBuildMatrix(t, voltBuildHelptags)
+}
+
+func voltBuildHelptags(t *testing.T, full bool, strategy string) {
+	for _, helptags := range []bool{true, false} {
+		t.Run(fmt.Sprintf("helptags=%v", helptags), func(t *testing.T) {
+			// =============== setup =============== //
+
+			testutil.SetUpEnv(t)
+			defer testutil.CleanUpEnv(t)
+			reposPath := pathutil.ReposPath("localhost/local/hello")
+			teardown := testutil.SetUpRepos(t, "hello", lockjson.ReposStaticType, []pathutil.ReposPath{reposPath}, strategy)
+			defer teardown()
+			doc := filepath.Join(reposPath.FullPath(), "doc", "hello.txt")
+			os.MkdirAll(filepath.Dir(doc), 0777)
+			if err := ioutil.WriteFile(doc, []byte("*hello.txt*\n"), 0644); err != nil {
+				t.Fatal("failed to write doc: " + err.Error())
+			}
+			content := fmt.Sprintf("[build]\nstrategy = %q\nhelptags = %v\n", strategy, helptags)
+			if err := ioutil.WriteFile(pathutil.ConfigTOML(), []byte(content), 0644); err != nil {
+				t.Fatal("failed to write config.toml: " + err.Error())
+			}
+
+			// =============== run =============== //
+
+			args := []string{"build"}
+			if full {
+				args = append(args, "-full")
+			}
+			out, err := testutil.RunVolt(args...)
+			// (A, B)
+			testutil.SuccessExit(t, out, err)
+
+			// (E)
+			checkCopied(t, reposPath, strategy)
+
+			tags := filepath.Join(reposPath.EncodeToPlugDirName(), "doc", "tags")
+			if pathutil.Exists(tags) != helptags {
+				t.Errorf("expected %s exists is %v but got %v", tags, helptags, !helptags)
+			}
+		})
+	}
+}
+
+// * Run `volt build` (build.per_profile = true) (A, B, E, J, K)
+//   bundled plugconf of each profile is generated, and a selector of them is
+//   installed as bundled plugconf
+// * Run `volt build` (build.per_profile = false) (A, B, J, K)
+//   bundled plugconf of each profile is removed
+func TestVoltBuildPerProfile(t *testing.T) {
+	testBuildMatrix(t, voltBuildPerProfile)
+}
+
+func voltBuildPerProfile(t *testing.T, full bool, strategy string) {
+	// =============== setup =============== //
+
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	reposPath := pathutil.ReposPath("localhost/local/hello")
+	teardown := testutil.SetUpRepos(t, "hello", lockjson.ReposStaticType, []pathutil.ReposPath{reposPath}, strategy)
+	defer teardown()
+	testutil.InstallConfig(t, "per-profile-"+strategy+".toml")
+
+	out, err := testutil.RunVolt("profile", "new", "foo")
+	testutil.SuccessExit(t, out, err)
+	out, err = testutil.RunVolt("profile", "set", "foo")
+	testutil.SuccessExit(t, out, err)
+
+	// =============== run =============== //
+
+	args := []string{"build"}
+	if full {
+		args = append(args, "-full")
+	}
+	out, err = testutil.RunVolt(args...)
+	// (A, B)
+	testutil.SuccessExit(t, out, err)
+
+	// (E)
+	checkCopied(t, reposPath, strategy)
+
+	// (J, K)
+	for _, path := range []string{
+		pathutil.BundledPlugConf(),
+		pathutil.ProfileBundledPlugConf("default"),
+		pathutil.ProfileBundledPlugConf("foo"),
+	} {
+		if !pathutil.Exists(path) {
+			t.Errorf("%s does not exist", path)
+			continue
+		}
+		checkSyntax(t, path)
+	}
+
+	packadd := []byte("packadd " + filepath.Base(reposPath.EncodeToPlugDirName()))
+	for _, tt := range []struct {
+		profile  string
+		expected bool
+	}{
+		{"default", true},
+		{"foo", false},
+	} {
+		content, err := ioutil.ReadFile(pathutil.ProfileBundledPlugConf(tt.profile))
+		if err != nil {
+			t.Errorf("failed to read bundled plugconf of profile '%s': %s", tt.profile, err.Error())
+			continue
+		}
+		if bytes.Contains(content, packadd) != tt.expected {
+			t.Errorf("expected bundled plugconf of profile '%s' loads '%s' is %v, but got %v", tt.profile, reposPath, tt.expected, !tt.expected)
+		}
+	}
+
+	testutil.InstallConfig(t, "strategy-"+strategy+".toml")
+	out, err = testutil.RunVolt(args...)
+	// (A, B)
+	testutil.SuccessExit(t, out, err)
+
+	// (J, K)
+	checkSyntax(t, pathutil.BundledPlugConf())
+	for _, profile := range []string{"default", "foo"} {
+		if dir := pathutil.VimVoltProfileDir(profile); pathutil.Exists(dir) {
+			t.Errorf("expected %s was removed but exists", dir)
+		}
+	}
+}
+
+// * Run `volt build` (strategy = "hardlink-store") (A, B, E)
+//   files of git repository are extracted to `$VOLTPATH/store` and
+//   `~/.vim/pack/volt/opt/<repos>` files are hard links to them
+// * Run `volt build -full` again (strategy = "hardlink-store") (A, B, E)
+//   store entries are reused
+func TestVoltBuildHardlinkStore(t *testing.T) {
+	// =============== setup =============== //
+
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	reposPath := pathutil.ReposPath("github.com/tyru/caw.vim")
+	teardown := testutil.SetUpRepos(t, "caw.vim", lockjson.ReposGitType, []pathutil.ReposPath{reposPath}, config.HardlinkStoreBuilder)
+	defer teardown()
+	testutil.InstallConfig(t, "strategy-hardlink-store.toml")
+
+	// =============== run =============== //
+
+	var entries []os.FileInfo
+	for i, args := range [][]string{{"build"}, {"build", "-full"}} {
+		out, err := testutil.RunVolt(args...)
+		// (A, B)
+		testutil.SuccessExit(t, out, err)
+
+		// (E)
+		checkCopied(t, reposPath, config.HardlinkStoreBuilder)
+
+		storeEntries, err := ioutil.ReadDir(pathutil.StoreDir())
+		if err != nil {
+			t.Fatal("failed to read store directory: " + err.Error())
+		}
+		if len(storeEntries) == 0 {
+			t.Fatal("no files were extracted to store directory")
+		}
+		if i > 0 && len(storeEntries) != len(entries) {
+			t.Errorf("expected store entries are reused but got %d entries (previous: %d)", len(storeEntries), len(entries))
+		}
+		entries = storeEntries
+
+		plugin := filepath.Join(reposPath.EncodeToPlugDirName(), "plugin", "caw.vim")
+		fi, err := os.Stat(plugin)
+		if err != nil {
+			t.Fatal("failed to stat installed file: " + err.Error())
+		}
+		var linked bool
+		for _, entry := range storeEntries {
+			if os.SameFile(fi, entry) {
+				linked = true
+				break
+			}
+		}
+		if !linked {
+			t.Errorf("%s is not a hard link to a store entry", plugin)
+		}
+	}
+}
+
 // ============================================
 
 func testBuildMatrix(t *testing.T, f func(*testing.T, bool, string)) {
@@ -983,3 +1283,259 @@ func checkSyntax(t *testing.T, bundledPlugconf string) {
 		t.Errorf("failed to parse %s: %s", bundledPlugconf, err.Error())
 	}
 }
+
+// * Run `volt build -dry-run` before build (A, B, !E, !J)
+//   the repository is planned to be added
+// * Run `volt build -dry-run` after build (A, B)
+//   nothing is planned in smart build
+// * Run `volt build -dry-run` with user vimrc which has no magic comment (A, B, F, !G)
+//   user vimrc is planned to be refused
+func TestVoltBuildDryRun(t *testing.T) {
+	testBuildMatrix(t, voltBuildDryRun)
+}
+
+func voltBuildDryRun(t *testing.T, full bool, strategy string) {
+	// =============== setup =============== //
+
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	reposPath := pathutil.ReposPath("localhost/local/hello")
+	teardown := testutil.SetUpRepos(t, "hello", lockjson.ReposStaticType, []pathutil.ReposPath{reposPath}, strategy)
+	defer teardown()
+	testutil.InstallConfig(t, "strategy-"+strategy+".toml")
+	os.RemoveAll(pathutil.VimVoltDir())
+
+	// =============== run =============== //
+
+	args := []string{"build", "-dry-run"}
+	if full {
+		args = append(args, "-full")
+	}
+	out, err := testutil.RunVolt(args...)
+	// (A, B)
+	testutil.SuccessExit(t, out, err)
+	if !bytes.Contains(out, []byte("add     "+reposPath.String())) {
+		t.Errorf("expected %s is planned to be added but got: %s", reposPath, string(out))
+	}
+	// (!E, !J)
+	if pathutil.Exists(pathutil.VimVoltDir()) {
+		t.Errorf("expected %s is not created but it exists", pathutil.VimVoltDir())
+	}
+
+	out, err = testutil.RunVolt("build")
+	testutil.SuccessExit(t, out, err)
+
+	out, err = testutil.RunVolt(args...)
+	// (A, B)
+	testutil.SuccessExit(t, out, err)
+	// symlink strategy always does full build
+	rebuild := full || strategy == config.SymlinkBuilder
+	if planned := bytes.Contains(out, []byte("update  "+reposPath.String())); planned != rebuild {
+		t.Errorf("expected %s is planned to be updated is %v but got %v: %s", reposPath, rebuild, planned, string(out))
+	}
+	if !bytes.Contains(out, []byte("Bundled plugconf:\n  (no changes)")) {
+		t.Errorf("expected bundled plugconf is not changed but got: %s", string(out))
+	}
+
+	installProfileRC(t, "default", "vimrc-nomagic.vim", pathutil.ProfileVimrc)
+	installVimRC(t, "vimrc-nomagic.vim", pathutil.Vimrc)
+	out, err = testutil.RunVolt(args...)
+	// (A, B)
+	testutil.SuccessExit(t, out, err)
+	vimrc := filepath.Join(pathutil.VimDir(), pathutil.Vimrc)
+	if !bytes.Contains(out, []byte("refuse  "+vimrc)) {
+		t.Errorf("expected %s is planned to be refused but got: %s", vimrc, string(out))
+	}
+	// (F, !G, !H)
+	checkRCInstalled(t, 1, 0, 0, -1)
+}
+
+// * Run `volt build` after a file was added (A, B, E)
+// * Run `volt build -rollback` (A, B): the added file is removed
+// * Run `volt build -rollback` again (A, B): the added file is restored
+// * Run `volt build` when the repository was removed (!A, !B): ~/.vim/pack/volt is not changed
+func TestVoltBuildRollback(t *testing.T) {
+	testBuildMatrix(t, voltBuildRollback)
+}
+
+func voltBuildRollback(t *testing.T, full bool, strategy string) {
+	if strategy == config.SymlinkBuilder {
+		t.Skip("installed files are always same as repository in symlink strategy")
+	}
+
+	// =============== setup =============== //
+
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	reposPath := pathutil.ReposPath("localhost/local/hello")
+	teardown := testutil.SetUpRepos(t, "hello", lockjson.ReposStaticType, []pathutil.ReposPath{reposPath}, strategy)
+	defer teardown()
+	testutil.InstallConfig(t, "strategy-"+strategy+".toml")
+	out, err := testutil.RunVolt("build")
+	testutil.SuccessExit(t, out, err)
+
+	args := []string{"build"}
+	if full {
+		args = append(args, "-full")
+	}
+	added := filepath.Join("autoload", "hello.vim")
+	os.MkdirAll(filepath.Join(reposPath.FullPath(), "autoload"), 0777)
+	if err := ioutil.WriteFile(filepath.Join(reposPath.FullPath(), added), []byte("\n"), 0644); err != nil {
+		t.Fatal("failed to write file: " + err.Error())
+	}
+
+	// =============== run =============== //
+
+	out, err = testutil.RunVolt(args...)
+	// (A, B)
+	testutil.SuccessExit(t, out, err)
+	// (E)
+	checkCopied(t, reposPath, strategy)
+
+	installed := filepath.Join(reposPath.EncodeToPlugDirName(), added)
+	for _, exists := range []bool{false, true} {
+		out, err = testutil.RunVolt("build", "-rollback")
+		// (A, B)
+		testutil.SuccessExit(t, out, err)
+		if pathutil.Exists(installed) != exists {
+			t.Errorf("expected %s exists is %v after rollback but got %v", installed, exists, !exists)
+		}
+	}
+
+	if err := os.RemoveAll(reposPath.FullPath()); err != nil {
+		t.Fatal("failed to remove repository: " + err.Error())
+	}
+	out, err = testutil.RunVolt(args...)
+	// (!A, !B)
+	testutil.FailExit(t, out, err)
+	if !pathutil.Exists(installed) {
+		t.Errorf("expected %s is not changed by failed build but it was removed", installed)
+	}
+	if pathutil.Exists(pathutil.VimVoltStagingDir()) {
+		t.Errorf("expected %s is removed but it exists", pathutil.VimVoltStagingDir())
+	}
+}
+
+// Checks:
+// (A) Exit with the code of the step which failed
+// (B) Show the error message of the step which failed
+//
+// * Run `volt build -rollback` when no previous build exists (A, B): 14
+// * Run `volt build` when the repository was removed (A, B): 12
+// * Run `volt build -dry-run` when build-info.json is broken (A, B): 14
+// * Run `volt build` when other transaction is running (A, B): 11
+func TestVoltBuildExitCode(t *testing.T) {
+	// =============== setup =============== //
+
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	reposPath := pathutil.ReposPath("localhost/local/hello")
+	teardown := testutil.SetUpRepos(t, "hello", lockjson.ReposStaticType, []pathutil.ReposPath{reposPath}, config.CopyBuilder)
+	defer teardown()
+	testutil.InstallConfig(t, "strategy-copy.toml")
+
+	// =============== run =============== //
+
+	checkExit := func(code int, msg string, args ...string) {
+		t.Helper()
+		out, err := testutil.RunVolt(args...)
+		testutil.FailExit(t, out, err)
+		// (A)
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != code {
+			t.Errorf("volt %s: expected exit status %d but got %v", strings.Join(args, " "), code, err)
+		}
+		// (B)
+		if !bytes.Contains(out, []byte(msg)) {
+			t.Errorf("volt %s: expected %q in the output but got: %s", strings.Join(args, " "), msg, string(out))
+		}
+	}
+
+	checkExit(14, "Failed to rollback: ", "build", "-rollback")
+
+	out, err := testutil.RunVolt("build")
+	testutil.SuccessExit(t, out, err)
+	if err := os.RemoveAll(reposPath.FullPath()); err != nil {
+		t.Fatal("failed to remove repository: " + err.Error())
+	}
+	checkExit(12, "Failed to build: ", "build")
+
+	if err := ioutil.WriteFile(pathutil.BuildInfoJSON(), []byte("{"), 0644); err != nil {
+		t.Fatal("failed to write build-info.json: " + err.Error())
+	}
+	checkExit(14, "Failed to make build plan: ", "build", "-dry-run")
+
+	if err := os.MkdirAll(transaction.LockDir(), 0755); err != nil {
+		t.Fatal("failed to create trx/lock: " + err.Error())
+	}
+	checkExit(11, "Failed to begin transaction: ", "build")
+}
+
+// * Run `volt build` when ~/.vim/pack/volt is a directory built by older versions (A, B, E)
+//   ~/.vim/pack/volt becomes a symlink to (vim dir)/pack/.volt-builds/{build}
+// * Run `volt build` again (A, B): only the current and the previous builds are kept
+// * Run `volt build -rollback` (A, B): ~/.vim/pack/volt refers to the previous build
+func TestVoltBuildSwapLink(t *testing.T) {
+	// =============== setup =============== //
+
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	reposPath := pathutil.ReposPath("localhost/local/hello")
+	teardown := testutil.SetUpRepos(t, "hello", lockjson.ReposStaticType, []pathutil.ReposPath{reposPath}, config.CopyBuilder)
+	defer teardown()
+	testutil.InstallConfig(t, "strategy-copy.toml")
+	legacy := filepath.Join(pathutil.VimVoltDir(), "legacy.txt")
+	os.MkdirAll(pathutil.VimVoltDir(), 0755)
+	if err := ioutil.WriteFile(legacy, []byte("\n"), 0644); err != nil {
+		t.Fatal("failed to write file: " + err.Error())
+	}
+
+	// =============== run =============== //
+
+	out, err := testutil.RunVolt("build", "-full")
+	// (A, B)
+	testutil.SuccessExit(t, out, err)
+	// (E)
+	checkCopied(t, reposPath, config.CopyBuilder)
+	current := readBuildLink(t, pathutil.VimVoltDir())
+	if filepath.Dir(current) != pathutil.VimVoltBuildsDir() {
+		t.Fatalf("expected %s refers to a directory in %s but got %s", pathutil.VimVoltDir(), pathutil.VimVoltBuildsDir(), current)
+	}
+	prev := readBuildLink(t, pathutil.VimVoltPrevDir())
+	if !pathutil.Exists(filepath.Join(prev, "legacy.txt")) {
+		t.Errorf("expected the directory built by older versions is kept as the previous build: %s", prev)
+	}
+
+	out, err = testutil.RunVolt("build", "-full")
+	// (A, B)
+	testutil.SuccessExit(t, out, err)
+	if p := readBuildLink(t, pathutil.VimVoltPrevDir()); p != current {
+		t.Errorf("expected the previous build is %s but got %s", current, p)
+	}
+	entries, err := ioutil.ReadDir(pathutil.VimVoltBuildsDir())
+	if err != nil {
+		t.Fatal("failed to read builds directory: " + err.Error())
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected 2 builds are kept but got %d", len(entries))
+	}
+	latest := readBuildLink(t, pathutil.VimVoltDir())
+
+	out, err = testutil.RunVolt("build", "-rollback")
+	// (A, B)
+	testutil.SuccessExit(t, out, err)
+	if c := readBuildLink(t, pathutil.VimVoltDir()); c != current {
+		t.Errorf("expected %s refers to %s after rollback but got %s", pathutil.VimVoltDir(), current, c)
+	}
+	if p := readBuildLink(t, pathutil.VimVoltPrevDir()); p != latest {
+		t.Errorf("expected the previous build is %s after rollback but got %s", latest, p)
+	}
+}
+
+func readBuildLink(t *testing.T, link string) string {
+	t.Helper()
+	target, err := os.Readlink(link)
+	if err != nil {
+		t.Fatalf("expected %s is a symlink: %s", link, err)
+	}
+	return filepath.Join(filepath.Dir(link), target)
+}
@@ -26,7 +26,7 @@ type Cmd interface {
 type RunnerFunc func(c Cmd, args []string) *Error
 
 // Error is a command error.
-// It also has a exit code.
+// It also has a exit code. If Msg is empty, volt exits silently with the code.
 type Error struct {
 	Code int
 	Msg  string
@@ -47,21 +47,37 @@ func Run(args []string, cont RunnerFunc) *Error {
 		logger.SetLevel(logger.DebugLevel)
 	}
 
+	args = parseGlobalFlags(args)
 	if len(args) <= 1 {
 		args = append(args, "help")
 	}
 	subCmd := args[1]
 	args = args[2:]
 
-	// Expand subcommand alias
-	subCmd, args, err := expandAlias(subCmd, args)
+	cfg, err := config.Read()
 	if err != nil {
-		return &Error{Code: 1, Msg: err.Error()}
+		return &Error{Code: 1, Msg: "could not read config.toml: " + err.Error()}
 	}
 
+	// Open log file
+	if cfg.Log.File != "" {
+		err = logger.OpenFile(cfg.Log.File, cfg.Log.Format)
+		if err != nil {
+			return &Error{Code: 1, Msg: "could not open log file: " + err.Error()}
+		}
+	}
+
+	// Expand subcommand alias
+	subCmd, args = expandAlias(cfg, subCmd, args)
+
 	c, exists := cmdMap[subCmd]
 	if !exists {
-		return &Error{Code: 3, Msg: "unknown command '" + subCmd + "'"}
+		// Fall back to external command "volt-{subCmd}"
+		ext, found := lookUpExternalCmd(subCmd)
+		if !found {
+			return &Error{Code: 3, Msg: "unknown command '" + subCmd + "'"}
+		}
+		c = ext
 	}
 
 	// Disallow executing the commands which may modify files in root priviledge
@@ -75,16 +91,31 @@ func Run(args []string, cont RunnerFunc) *Error {
 	return cont(c, args)
 }
 
-func expandAlias(subCmd string, args []string) (string, []string, error) {
-	cfg, err := config.Read()
-	if err != nil {
-		return "", nil, errors.Wrap(err, "could not read config.toml")
+// parseGlobalFlags parses the flags before subcommand name (e.g. "volt -v
+// get ..."), and returns args without them.
+// -v (-verbose) shows debug messages, and -q (-quiet) shows only warning and
+// error messages.
+func parseGlobalFlags(args []string) []string {
+	i := 1
+	for ; i < len(args); i++ {
+		switch args[i] {
+		case "-v", "-verbose":
+			logger.SetLevel(logger.DebugLevel)
+		case "-q", "-quiet":
+			logger.SetLevel(logger.WarnLevel)
+		default:
+			return append([]string{args[0]}, args[i:]...)
+		}
 	}
+	return []string{args[0]}
+}
+
+func expandAlias(cfg *config.Config, subCmd string, args []string) (string, []string) {
 	if newArgs, exists := cfg.Alias[subCmd]; exists && len(newArgs) > 0 {
 		subCmd = newArgs[0]
 		args = append(newArgs[1:], args...)
 	}
-	return subCmd, args, nil
+	return subCmd, args
 }
 
 // On Windows, this function always returns nil.
@@ -0,0 +1,106 @@
+package subcmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/internal/testutil"
+)
+
+// Checks:
+// (A) Does not show `[ERROR]`, `[WARN]` messages
+// (B) Exit with zero status
+// (C) Does not show `[INFO]` messages
+// (D) Shows `[INFO]` messages with the time and the caller
+// (E) Log file has all messages with the transaction ID
+// (F) Each line of log file is a JSON object
+
+// Run `volt -q profile new {name}` (A, B, C)
+// Run `volt -v profile new {name}` (A, B, D)
+func TestVoltGlobalFlags(t *testing.T) {
+	// =============== setup =============== //
+
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+
+	// =============== run =============== //
+
+	out, err := testutil.RunVolt("-q", "profile", "new", "foo")
+	// (A, B)
+	testutil.SuccessExit(t, out, err)
+	// (C)
+	if len(out) != 0 {
+		t.Errorf("expected no output but got %s", string(out))
+	}
+
+	out, err = testutil.RunVolt("-v", "profile", "new", "bar")
+	// (A, B)
+	testutil.SuccessExit(t, out, err)
+	// (D)
+	if !bytes.Contains(out, []byte("[INFO][")) || !bytes.Contains(out, []byte("profile.go:")) {
+		t.Errorf("expected the time and the caller in output but got %s", string(out))
+	}
+}
+
+// Run `volt -q profile set {name}` with log.file (A, B, C, E)
+// Run `volt -q profile set {name}` with log.file and log.format = "json" (A, B, C, E, F)
+func TestVoltLogFile(t *testing.T) {
+	for _, format := range []string{"text", "json"} {
+		t.Run("log.format = "+format, func(t *testing.T) {
+			// =============== setup =============== //
+
+			testutil.SetUpEnv(t)
+			defer testutil.CleanUpEnv(t)
+			logFile := filepath.Join(os.Getenv("HOME"), "log", "volt.log")
+			defer os.Unsetenv("VOLT_LOG_FILE")
+			os.Setenv("VOLT_LOG_FILE", logFile)
+			defer os.Unsetenv("VOLT_LOG_FORMAT")
+			os.Setenv("VOLT_LOG_FORMAT", format)
+			out, err := testutil.RunVolt("-q", "profile", "new", "foo")
+			testutil.SuccessExit(t, out, err)
+
+			// =============== run =============== //
+
+			out, err = testutil.RunVolt("-q", "profile", "set", "foo")
+			// (A, B)
+			testutil.SuccessExit(t, out, err)
+			// (C)
+			if len(out) != 0 {
+				t.Errorf("expected no output but got %s", string(out))
+			}
+			// (E)
+			content, err := ioutil.ReadFile(logFile)
+			if err != nil {
+				t.Fatal("failed to read log file: " + err.Error())
+			}
+			var line string
+			for _, l := range strings.Split(string(content), "\n") {
+				if strings.Contains(l, "Changed current profile: foo") {
+					line = l
+				}
+			}
+			if line == "" {
+				t.Fatalf("expected the message in log file but got %s", string(content))
+			}
+			if format == "text" {
+				if !strings.Contains(line, " INFO ") || !strings.Contains(line, " trx=") {
+					t.Errorf("expected the level and the transaction ID but got %s", line)
+				}
+				return
+			}
+			// (F)
+			var obj map[string]string
+			if err := json.Unmarshal([]byte(line), &obj); err != nil {
+				t.Fatalf("could not parse %s: %s", line, err)
+			}
+			if obj["level"] != "info" || obj["trx"] == "" || obj["time"] == "" {
+				t.Errorf("expected the level, the time and the transaction ID but got %s", line)
+			}
+		})
+	}
+}
@@ -0,0 +1,492 @@
+package subcmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/subcmd/doctor"
+	"github.com/vim-volt/volt/subcmd/migrate"
+)
+
+func init() {
+	cmdMap["completion"] = &completionCmd{}
+	cmdMap["__complete"] = &completeCmd{}
+}
+
+type completionCmd struct {
+	helped bool
+}
+
+func (cmd *completionCmd) ProhibitRootExecution(args []string) bool { return false }
+
+func (cmd *completionCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  volt completion [-help] {shell}
+
+Quick example
+  $ source <(volt completion bash)   # add this line to ~/.bashrc
+  $ volt completion zsh > ~/.zsh/completion/_volt   # a directory in $fpath
+  $ volt completion fish > ~/.config/fish/completions/volt.fish
+
+Description
+  Output the completion script of {shell} to stdout.
+  {shell} is one of: ` + strings.Join(completionShells, ", ") + `
+
+  Subcommands and their options are embedded in the script, so please re-generate it after upgrading volt.
+  Repositories and profile names are completed by running volt.` + "\n\n")
+		//fmt.Println("Options")
+		//fs.PrintDefaults()
+		fmt.Println()
+		cmd.helped = true
+	}
+	return fs
+}
+
+func (cmd *completionCmd) Run(args []string) *Error {
+	fs := cmd.FlagSet()
+	fs.Parse(args)
+	if cmd.helped {
+		return nil
+	}
+	if len(fs.Args()) != 1 {
+		fs.Usage()
+		return &Error{Code: 10, Msg: "Must specify one shell"}
+	}
+
+	shell := fs.Arg(0)
+	tmpl, exists := completionTemplates[shell]
+	if !exists {
+		return &Error{Code: 11, Msg: "Unknown shell: " + shell}
+	}
+	t, err := template.New(shell).Funcs(template.FuncMap{
+		"join": strings.Join,
+	}).Parse(tmpl)
+	if err != nil {
+		return &Error{Code: 12, Msg: "Failed to parse template: " + err.Error()}
+	}
+	if err := t.Execute(os.Stdout, newCompletionSpec()); err != nil {
+		return &Error{Code: 13, Msg: "Failed to generate completion script: " + err.Error()}
+	}
+	return nil
+}
+
+// completionSpec is the data of completion script templates.
+type completionSpec struct {
+	Commands    []string
+	Flags       []completionFlags
+	Subcommands []completionSubcommands
+}
+
+type completionFlags struct {
+	Command string
+	Flags   []*flag.Flag
+}
+
+type completionSubcommands struct {
+	Command     string
+	Subcommands []string
+}
+
+func newCompletionSpec() *completionSpec {
+	spec := &completionSpec{Commands: completionCommands()}
+	for _, name := range spec.Commands {
+		if flags := completionFlagList(name); len(flags) > 0 {
+			spec.Flags = append(spec.Flags, completionFlags{name, flags})
+		}
+		if subCmds := completionSubcommandList(name); len(subCmds) > 0 {
+			spec.Subcommands = append(spec.Subcommands, completionSubcommands{name, subCmds})
+		}
+	}
+	return spec
+}
+
+// completionCommands returns the names of subcommands except hidden commands
+// (their names start with "__").
+func completionCommands() []string {
+	names := make([]string, 0, len(cmdMap))
+	for name := range cmdMap {
+		if !strings.HasPrefix(name, "__") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// completionFlagList returns the flags of subcommand name.
+func completionFlagList(name string) []*flag.Flag {
+	c, exists := cmdMap[name]
+	if !exists {
+		return nil
+	}
+	var flags []*flag.Flag
+	c.FlagSet().VisitAll(func(f *flag.Flag) {
+		flags = append(flags, f)
+	})
+	return flags
+}
+
+// completionSubcommandList returns the names of the subcommands of
+// subcommand name (e.g. "volt profile {subcommand}").
+func completionSubcommandList(name string) []string {
+	var names []string
+	switch name {
+	case "profile":
+		for subCmd := range profileSubCmd {
+			names = append(names, subCmd)
+		}
+		sort.Strings(names)
+	case "config":
+		for subCmd := range configSubCmd {
+			names = append(names, subCmd)
+		}
+		sort.Strings(names)
+	case "plugconf":
+		for subCmd := range plugconfSubCmd {
+			names = append(names, subCmd)
+		}
+		sort.Strings(names)
+	case "migrate":
+		for _, m := range migrate.ListMigraters() {
+			names = append(names, m.Name())
+		}
+	}
+	return names
+}
+
+var completionShells = []string{"bash", "zsh", "fish"}
+
+var completionTemplates = map[string]string{
+	"bash": `# bash completion for volt
+# This file was generated by "volt completion bash".
+
+_volt() {
+	local cur="${COMP_WORDS[COMP_CWORD]}"
+	local cmd="${COMP_WORDS[1]}"
+	COMPREPLY=()
+
+	if [[ ${COMP_CWORD} -eq 1 ]] ; then
+		COMPREPLY=( $(compgen -W "{{ join .Commands " " }}" -- "${cur}") )
+		return 0
+	fi
+
+	if [[ "${cur}" == -* ]] ; then
+		case "${cmd}" in
+{{- range .Flags }}
+			{{ .Command }}) COMPREPLY=( $(compgen -W "{{ range $i, $f := .Flags }}{{ if $i }} {{ end }}-{{ $f.Name }}{{ end }}" -- "${cur}") ) ;;
+{{- end }}
+		esac
+		return 0
+	fi
+
+	if [[ ${COMP_CWORD} -eq 2 ]] ; then
+		case "${cmd}" in
+{{- range .Subcommands }}
+			{{ .Command }}) COMPREPLY=( $(compgen -W "{{ join .Subcommands " " }}" -- "${cur}") ) ; return 0 ;;
+{{- end }}
+		esac
+	fi
+
+	local IFS=$'\n'
+	COMPREPLY=( $(volt __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null) )
+	return 0
+}
+
+complete -F _volt volt
+`,
+	"zsh": `#compdef volt
+# zsh completion for volt
+# This file was generated by "volt completion zsh".
+
+_volt() {
+	local -a candidates
+	if (( CURRENT == 2 )); then
+		candidates=({{ join .Commands " " }})
+	elif [[ "${words[CURRENT]}" == -* ]]; then
+		case "${words[2]}" in
+{{- range .Flags }}
+			{{ .Command }}) candidates=({{ range $i, $f := .Flags }}{{ if $i }} {{ end }}-{{ $f.Name }}{{ end }}) ;;
+{{- end }}
+		esac
+	elif (( CURRENT == 3 )) && [[ "${words[2]}" == ({{ range $i, $s := .Subcommands }}{{ if $i }}|{{ end }}{{ $s.Command }}{{ end }}) ]]; then
+		case "${words[2]}" in
+{{- range .Subcommands }}
+			{{ .Command }}) candidates=({{ join .Subcommands " " }}) ;;
+{{- end }}
+		esac
+	else
+		candidates=(${(f)"$(volt __complete "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+	fi
+	compadd -- "${candidates[@]}"
+}
+
+if [[ "${funcstack[1]}" == "_volt" ]]; then
+	_volt "$@"
+else
+	compdef _volt volt
+fi
+`,
+	"fish": `# fish completion for volt
+# This file was generated by "volt completion fish".
+
+function __volt_complete
+	set -l words (commandline -opc) (commandline -ct)
+	volt __complete $words[2..-1] 2>/dev/null
+end
+
+complete -c volt -f
+complete -c volt -n '__fish_use_subcommand' -a '{{ join .Commands " " }}'
+{{- range $c := .Flags }}
+{{- range .Flags }}
+complete -c volt -n '__fish_seen_subcommand_from {{ $c.Command }}' -o '{{ .Name }}'
+{{- end }}
+{{- end }}
+{{- range .Subcommands }}
+complete -c volt -n '__fish_seen_subcommand_from {{ .Command }}; and test (count (commandline -opc)) -eq 2' -a '{{ join .Subcommands " " }}'
+{{- end }}
+complete -c volt -n 'not __fish_use_subcommand; and not string match -q -- "-*" (commandline -ct)' -a '(__volt_complete)'
+`,
+}
+
+type completeCmd struct{}
+
+func (cmd *completeCmd) ProhibitRootExecution(args []string) bool { return false }
+
+func (cmd *completeCmd) FlagSet() *flag.FlagSet {
+	return flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+}
+
+// Run prints the candidates of the last word of args.
+// args are the words of the command line after "volt", and the last word is
+// the word being completed (it is empty if the cursor is after a space).
+// This command is hidden, and is called by completion scripts.
+func (cmd *completeCmd) Run(args []string) *Error {
+	if len(args) == 0 {
+		return nil
+	}
+	cur := args[len(args)-1]
+	for _, c := range complete(args[:len(args)-1], cur) {
+		if strings.HasPrefix(c, cur) {
+			fmt.Println(c)
+		}
+	}
+	return nil
+}
+
+// complete returns the candidates of the word after words.
+func complete(words []string, cur string) []string {
+	if len(words) == 0 {
+		return completionCommands()
+	}
+	name := words[0]
+	if strings.HasPrefix(cur, "-") {
+		var names []string
+		for _, f := range completionFlagList(name) {
+			names = append(names, "-"+f.Name)
+		}
+		return names
+	}
+
+	// Split words into flags and positional arguments
+	prev := words[len(words)-1]
+	flags := make(map[string]bool)
+	var args []string
+	for _, w := range words[1:] {
+		// "volt profile" has no flags, but "-current" is an argument
+		if strings.HasPrefix(w, "-") && name != "profile" {
+			flags[strings.TrimLeft(w, "-")] = true
+		} else {
+			args = append(args, w)
+		}
+	}
+
+	switch name {
+	case "run", "bisect":
+		if prev == "-p" {
+			return completeProfiles()
+		}
+		if name == "run" && strings.HasPrefix(cur, "+") {
+			var repos []string
+			for _, r := range completeRepos("", "all") {
+				repos = append(repos, "+"+r)
+			}
+			return repos
+		}
+	case "get":
+		if flags["l"] {
+			return nil
+		}
+		if flags["u"] {
+			return completeRepos("", "all")
+		}
+		return completeRepos("", "not-in")
+	case "add":
+		if prev == "-name" {
+			return nil
+		}
+		return completeDirs(cur)
+	case "enable":
+		return completeRepos("", "not-in")
+	case "rm", "disable", "edit", "lint":
+		return completeRepos("", "in")
+	case "doctor":
+		var names []string
+		for _, c := range doctor.ListCheckers() {
+			names = append(names, c.Name())
+		}
+		return names
+	case "help":
+		if len(args) == 0 {
+			return completionCommands()
+		}
+	case "completion":
+		if len(args) == 0 {
+			return completionShells
+		}
+	case "profile", "plugconf", "config", "migrate":
+		if len(args) == 0 {
+			return completionSubcommandList(name)
+		}
+		switch name {
+		case "profile":
+			return completeProfileArgs(args[0], args[1:])
+		case "plugconf":
+			return completeRepos("", "all")
+		case "config":
+			return completeConfigArgs(args[0], args[1:])
+		}
+	}
+	return nil
+}
+
+// completeProfileArgs returns the candidates of the argument of
+// "volt profile {subCmd} {args} ...".
+func completeProfileArgs(subCmd string, args []string) []string {
+	switch subCmd {
+	case "set", "show", "destroy", "rename":
+		if len(args) == 0 {
+			return completeProfiles()
+		}
+	case "add", "rm":
+		if len(args) == 0 {
+			return append(completeProfiles(), "-current")
+		}
+		filter := "not-in"
+		if subCmd == "rm" {
+			filter = "in"
+		}
+		return completeRepos(args[0], filter)
+	case "extend", "unextend":
+		return completeProfiles()
+	}
+	return nil
+}
+
+// completeConfigArgs returns the candidates of the argument of
+// "volt config {subCmd} {args} ...".
+func completeConfigArgs(subCmd string, args []string) []string {
+	switch subCmd {
+	case "get", "set", "unset":
+		if len(args) > 0 {
+			return nil
+		}
+		values, err := config.List()
+		if err != nil {
+			return nil
+		}
+		var keys []string
+		for _, v := range values {
+			keys = append(keys, v.Key)
+		}
+		return keys
+	}
+	return nil
+}
+
+// completeProfiles returns all profile names.
+func completeProfiles() []string {
+	lockJSON, err := lockjson.ReadNoMigrationMsg()
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(lockJSON.Profiles))
+	for i := range lockJSON.Profiles {
+		names = append(names, lockJSON.Profiles[i].Name)
+	}
+	return names
+}
+
+// completeRepos returns repositories filtered by filter, which is "all" (all
+// repositories in lock.json), "in" (repositories in profile), or "not-in"
+// (repositories not in profile).
+// If profileName is empty or "-current", current profile is used.
+// "github.com/" prefix is omitted because volt can complement it.
+func completeRepos(profileName, filter string) []string {
+	lockJSON, err := lockjson.ReadNoMigrationMsg()
+	if err != nil {
+		return nil
+	}
+	if profileName == "" || profileName == "-current" {
+		profileName = lockJSON.CurrentProfileName
+	}
+	var inProfile lockjson.ReposList
+	if filter != "all" {
+		profile, err := lockJSON.Profiles.FindByName(profileName)
+		if err != nil {
+			return nil
+		}
+		inProfile, err = lockJSON.GetReposListByProfile(profile)
+		if err != nil {
+			return nil
+		}
+	}
+	var names []string
+	for i := range lockJSON.Repos {
+		path := lockJSON.Repos[i].Path
+		if filter == "in" && !inProfile.Contains(path) ||
+			filter == "not-in" && inProfile.Contains(path) {
+			continue
+		}
+		names = append(names, shortReposPath(path))
+	}
+	return names
+}
+
+// shortReposPath omits "github.com/" of path.
+func shortReposPath(path pathutil.ReposPath) string {
+	s := path.String()
+	for _, prefix := range []string{"github.com/", "www.github.com/"} {
+		if strings.HasPrefix(s, prefix) {
+			return s[len(prefix):]
+		}
+	}
+	return s
+}
+
+// completeDirs returns directories which start with cur.
+// A trailing slash is appended to each directory.
+func completeDirs(cur string) []string {
+	matches, err := filepath.Glob(cur + "*")
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	for _, m := range matches {
+		if fi, err := os.Stat(m); err == nil && fi.IsDir() {
+			dirs = append(dirs, m+"/")
+		}
+	}
+	return dirs
+}
@@ -0,0 +1,135 @@
+package subcmd
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/internal/testutil"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// Checks:
+// (A) Does not show `[ERROR]`, `[WARN]` messages
+// (B) Exit with zero status
+// (C) Output contains all commands and their flags
+// (D) Output is syntax OK (bash only)
+// (E) Output is the expected candidates, which do not include hidden commands
+
+// Run `volt completion {shell}` (A, B, C, D)
+func TestVoltCompletion(t *testing.T) {
+	for _, shell := range completionShells {
+		t.Run(shell, func(t *testing.T) {
+			// =============== setup =============== //
+
+			testutil.SetUpEnv(t)
+			defer testutil.CleanUpEnv(t)
+
+			// =============== run =============== //
+
+			out, err := testutil.RunVolt("completion", shell)
+			// (A, B)
+			testutil.SuccessExit(t, out, err)
+
+			// (C)
+			cmdlist, err := testutil.GetCmdList()
+			if err != nil {
+				t.Fatal("testutil.GetCmdList() returned non-nil error: " + err.Error())
+			}
+			for _, cmd := range cmdlist {
+				if !strings.Contains(string(out), cmd) {
+					t.Errorf("expected output contains command %q but not", cmd)
+				}
+			}
+			for _, flag := range []string{"dry-run", "lockjson", "unextend"} {
+				if !strings.Contains(string(out), flag) {
+					t.Errorf("expected output contains %q but not", flag)
+				}
+			}
+
+			// (D)
+			if shell == "bash" {
+				script := filepath.Join(pathutil.VoltPath(), "volt.bash")
+				if err := ioutil.WriteFile(script, out, 0644); err != nil {
+					t.Fatal("failed to write script: " + err.Error())
+				}
+				if out, err := exec.Command("bash", "-n", script).CombinedOutput(); err != nil {
+					t.Errorf("bash -n failed: %s: %s", err.Error(), string(out))
+				}
+			}
+		})
+	}
+}
+
+// [error] Run `volt completion {unknown shell}` (!A, !B)
+func TestErrVoltCompletionUnknownShell(t *testing.T) {
+	// =============== setup =============== //
+
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+
+	// =============== run =============== //
+
+	out, err := testutil.RunVolt("completion", "cmd.exe")
+	// (!A, !B)
+	testutil.FailExit(t, out, err)
+}
+
+// Run `volt __complete {words}` (A, B, E)
+func TestVoltCompleteWords(t *testing.T) {
+	// =============== setup =============== //
+
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	for _, name := range []string{"hello", "world"} {
+		plugin := filepath.Join(pathutil.ReposPath("localhost/local/"+name).FullPath(), "plugin", name+".vim")
+		os.MkdirAll(filepath.Dir(plugin), 0777)
+		if err := ioutil.WriteFile(plugin, []byte("\n"), 0644); err != nil {
+			t.Fatal("failed to write plugin: " + err.Error())
+		}
+		out, err := testutil.RunVolt("get", "localhost/local/"+name)
+		testutil.SuccessExit(t, out, err)
+	}
+	out, err := testutil.RunVolt("disable", "localhost/local/world")
+	testutil.SuccessExit(t, out, err)
+	out, err = testutil.RunVolt("profile", "new", "foo")
+	testutil.SuccessExit(t, out, err)
+
+	// =============== run =============== //
+
+	for _, tt := range []struct {
+		words    []string
+		expected []string
+	}{
+		{[]string{"bu"}, []string{"build"}},
+		{[]string{"__"}, nil},
+		{[]string{"build", "-f"}, []string{"-full"}},
+		{[]string{"profile", "e"}, []string{"extend"}},
+		{[]string{"profile", "set", ""}, []string{"default", "foo"}},
+		{[]string{"profile", "add", "-current", ""}, []string{"localhost/local/world"}},
+		{[]string{"profile", "rm", "foo", ""}, nil},
+		{[]string{"migrate", "lock"}, []string{"lockjson"}},
+		{[]string{"plugconf", "u"}, []string{"update"}},
+		{[]string{"config", "s"}, []string{"set"}},
+		{[]string{"config", "get", "build.s"}, []string{"build.strategy"}},
+		{[]string{"plugconf", "diff", ""}, []string{"localhost/local/hello", "localhost/local/world"}},
+		{[]string{"rm", ""}, []string{"localhost/local/hello"}},
+		{[]string{"enable", ""}, []string{"localhost/local/world"}},
+		{[]string{"add", "-name", ""}, nil},
+		{[]string{"get", "-u", ""}, []string{"localhost/local/hello", "localhost/local/world"}},
+		{[]string{"run", "+localhost/local/h"}, []string{"+localhost/local/hello"}},
+		{[]string{"run", "-p", "f"}, []string{"foo"}},
+	} {
+		out, err := testutil.RunVolt(append([]string{"__complete"}, tt.words...)...)
+		// (A, B)
+		testutil.SuccessExit(t, out, err)
+		// (E)
+		got := strings.Fields(string(out))
+		if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("volt __complete %v: expected %v but got %v", tt.words, tt.expected, got)
+		}
+	}
+}
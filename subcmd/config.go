@@ -0,0 +1,240 @@
+package subcmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+type configCmd struct {
+	helped bool
+}
+
+// configSubCmd is a map from the name of config subcommand to the function
+// which runs it.
+var configSubCmd = make(map[string]func(*configCmd, []string) error)
+
+func init() {
+	cmdMap["config"] = &configCmd{}
+	configSubCmd["get"] = (*configCmd).doGet
+	configSubCmd["set"] = (*configCmd).doSet
+	configSubCmd["unset"] = (*configCmd).doUnset
+	configSubCmd["list"] = (*configCmd).doList
+}
+
+func (cmd *configCmd) ProhibitRootExecution(args []string) bool {
+	if len(args) == 0 {
+		return true
+	}
+	switch args[0] {
+	case "get", "list":
+		return false
+	case "set", "unset":
+		// System config file is usually writable only by root
+		return len(args) < 2 || args[1] != "-system"
+	default:
+		return true
+	}
+}
+
+func (cmd *configCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  config [-help] {command}
+
+Command
+  config get {key}
+    Show the effective value of {key}.
+
+  config set [-system | -project] {key} {value}
+    Set {key} to {value} in user config file ($VOLTPATH/config.toml).
+    If -system was given, system config file is changed instead.
+    If -project was given, project config file (.volt.toml) is changed instead.
+    Only the keys marked with "project" can be set in project config file.
+    An array value is a TOML array (e.g. '["a", "b"]') or comma-separated
+    values (e.g. "a,b").
+
+  config unset [-system | -project] {key}
+    Remove {key} from user config file (or system / project config file).
+
+  config list
+    Show all effective values and where they come from.
+
+Quick example
+  $ volt config get build.strategy
+  symlink
+  $ volt config set build.strategy copy
+  $ volt config set alias.up '["get", "-u"]'
+  $ volt config list
+  build.strategy = "copy" # /home/user/volt/config.toml
+  build.per_profile = false # default
+  ...
+  alias.up = ["get", "-u"] # /home/user/volt/config.toml
+  $ volt config unset build.strategy
+
+Configuration layers
+  Values are read from the following layers. Later ones override earlier ones.
+
+  1. Built-in defaults
+  2. System config file: /etc/volt/config.toml
+     (%ProgramData%\volt\config.toml on Windows, or $VOLT_SYSTEM_CONFIG if set)
+  3. User config file: $VOLTPATH/config.toml
+  4. Project config file: .volt.toml in current directory or the nearest
+     parent directory. Only the keys marked with "project" below are read
+     from this file, because it may be written by others (e.g. a cloned
+     repository). Other keys are warned and ignored.
+  5. Environment variables: $VOLT_{TABLE}_{KEY} (e.g. $VOLT_BUILD_STRATEGY).
+     An array value is comma-separated values
+     (e.g. VOLT_HTTP_NO_PROXY=localhost,example.com).
+
+  Unknown keys in config files are warned.
+
+Keys` + "\n")
+		for _, key := range config.Keys() {
+			if key.Project {
+				fmt.Printf("  %s (%s, project)\n", key.Name, key.Type)
+			} else {
+				fmt.Printf("  %s (%s)\n", key.Name, key.Type)
+			}
+		}
+		fmt.Println()
+		cmd.helped = true
+	}
+	return fs
+}
+
+func (cmd *configCmd) Run(args []string) *Error {
+	// Parse args
+	args, err := cmd.parseArgs(args)
+	if err == ErrShowedHelp {
+		return nil
+	}
+	if err != nil {
+		return &Error{Code: 10, Msg: err.Error()}
+	}
+
+	subCmd := args[0]
+	fn, exists := configSubCmd[subCmd]
+	if !exists {
+		return &Error{Code: 11, Msg: "Unknown subcommand: " + subCmd}
+	}
+	err = fn(cmd, args[1:])
+	if err != nil {
+		return &Error{Code: 20, Msg: err.Error()}
+	}
+
+	return nil
+}
+
+func (cmd *configCmd) parseArgs(args []string) ([]string, error) {
+	fs := cmd.FlagSet()
+	fs.Parse(args)
+	if cmd.helped {
+		return nil, ErrShowedHelp
+	}
+	if len(fs.Args()) == 0 {
+		fs.Usage()
+		logger.Error("must specify subcommand")
+		return nil, ErrShowedHelp
+	}
+	return fs.Args(), nil
+}
+
+// configFile returns the config file specified by "-system" or "-project"
+// of args, and the rest of args.
+func (*configCmd) configFile(args []string) (string, []string, error) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "-system":
+			return pathutil.SystemConfigTOML(), args[1:], nil
+		case "-project":
+			file, err := config.ProjectConfigTOML()
+			return file, args[1:], err
+		}
+	}
+	return pathutil.ConfigTOML(), args, nil
+}
+
+func (cmd *configCmd) doGet(args []string) error {
+	if len(args) != 1 {
+		cmd.FlagSet().Usage()
+		logger.Error("'volt config get' receives a key.")
+		return nil
+	}
+	v, err := config.Get(args[0])
+	if err != nil {
+		return err
+	}
+	switch value := v.Value.(type) {
+	case string:
+		fmt.Println(value)
+	default:
+		fmt.Println(v.String())
+	}
+	return nil
+}
+
+func (cmd *configCmd) doSet(args []string) error {
+	project := len(args) > 0 && args[0] == "-project"
+	file, args, err := cmd.configFile(args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 {
+		cmd.FlagSet().Usage()
+		logger.Error("'volt config set' receives a key and a value.")
+		return nil
+	}
+	if project {
+		if err := config.CheckProjectKey(args[0]); err != nil {
+			return err
+		}
+	}
+	if err := config.Set(file, args[0], args[1]); err != nil {
+		return errors.Wrap(err, "could not set "+args[0])
+	}
+	logger.Debugf("Set %s to %s in %s", args[0], args[1], file)
+	return nil
+}
+
+func (cmd *configCmd) doUnset(args []string) error {
+	file, args, err := cmd.configFile(args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		cmd.FlagSet().Usage()
+		logger.Error("'volt config unset' receives a key.")
+		return nil
+	}
+	if err := config.Unset(file, args[0]); err != nil {
+		return errors.Wrap(err, "could not unset "+args[0])
+	}
+	logger.Debugf("Unset %s in %s", args[0], file)
+	return nil
+}
+
+func (cmd *configCmd) doList(args []string) error {
+	if len(args) != 0 {
+		cmd.FlagSet().Usage()
+		logger.Error("'volt config list' receives no arguments.")
+		return nil
+	}
+	values, err := config.List()
+	if err != nil {
+		return err
+	}
+	for _, v := range values {
+		fmt.Printf("%s = %s # %s\n", v.Key, v.String(), v.Origin)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package subcmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/internal/testutil"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// Checks:
+// (A) Does not show `[ERROR]`, `[WARN]` messages
+// (B) Exit with zero status
+// (C) `volt config get {key}` shows the expected value
+// (D) `volt config list` shows the expected value and origin
+// (E) Config file is not changed
+
+// * Run `volt config set {key} {value}` (A, B, C, D)
+// * Run `volt config unset {key}` (A, B, C)
+// * Run `volt config set -project {key} {value}` (A, B, C, D)
+// * Run `volt config get {key}` with `$VOLT_{TABLE}_{KEY}` (A, B, C, D)
+func TestVoltConfigSetGet(t *testing.T) {
+	t.Run("Run `volt config set {key} {value}` and `volt config unset {key}`", func(t *testing.T) {
+		// =============== setup =============== //
+
+		testutil.SetUpEnv(t)
+		defer testutil.CleanUpEnv(t)
+
+		// =============== run =============== //
+
+		out, err := testutil.RunVolt("config", "set", "build.strategy", "copy")
+		// (A, B)
+		testutil.SuccessExit(t, out, err)
+		// (C, D)
+		checkConfigValue(t, "build.strategy", "copy", `"copy" # `+pathutil.ConfigTOML())
+
+		out, err = testutil.RunVolt("config", "unset", "build.strategy")
+		// (A, B)
+		testutil.SuccessExit(t, out, err)
+		// (C, D)
+		checkConfigValue(t, "build.strategy", "symlink", `"symlink" # default`)
+	})
+
+	t.Run("Run `volt config set -project {key} {value}`", func(t *testing.T) {
+		// =============== setup =============== //
+
+		testutil.SetUpEnv(t)
+		defer testutil.CleanUpEnv(t)
+		project := filepath.Join(os.Getenv("HOME"), "project")
+		os.MkdirAll(filepath.Join(project, "sub"), 0755)
+		wd, err := os.Getwd()
+		if err != nil {
+			t.Fatal("failed to get current directory: " + err.Error())
+		}
+		defer os.Chdir(wd)
+		os.Chdir(project)
+
+		// =============== run =============== //
+
+		out, err := testutil.RunVolt("config", "set", "-project", "build.per_profile", "true")
+		// (A, B)
+		testutil.SuccessExit(t, out, err)
+		file := filepath.Join(project, ".volt.toml")
+		if !pathutil.Exists(file) {
+			t.Fatal(".volt.toml was not created")
+		}
+		// (C, D) The nearest .volt.toml is used in subdirectories
+		os.Chdir(filepath.Join(project, "sub"))
+		checkConfigValue(t, "build.per_profile", "true", "true # "+file)
+	})
+
+	t.Run("Run `volt config get {key}` with `$VOLT_{TABLE}_{KEY}`", func(t *testing.T) {
+		// =============== setup =============== //
+
+		testutil.SetUpEnv(t)
+		defer testutil.CleanUpEnv(t)
+		out, err := testutil.RunVolt("config", "set", "http.no_proxy", "localhost")
+		testutil.SuccessExit(t, out, err)
+		defer os.Unsetenv("VOLT_HTTP_NO_PROXY")
+		os.Setenv("VOLT_HTTP_NO_PROXY", "localhost,example.com")
+
+		// =============== run =============== //
+
+		// (A, B, C, D)
+		checkConfigValue(t, "http.no_proxy", `["localhost", "example.com"]`, `["localhost", "example.com"] # $VOLT_HTTP_NO_PROXY`)
+	})
+}
+
+// * Run `volt config set {key} {value}` with invalid value (!A, !B, E)
+// * Run `volt config set {key} {value}` with unknown key (!A, !B, E)
+func TestVoltConfigSetInvalid(t *testing.T) {
+	for _, args := range [][]string{
+		{"build.strategy", "unknown"},
+		{"build.helptags", "maybe"},
+		{"build.stratgy", "copy"},
+		{"-project", "edit.editor", "vi"},
+	} {
+		t.Run("Run `volt config set "+strings.Join(args, " ")+"`", func(t *testing.T) {
+			// =============== setup =============== //
+
+			testutil.SetUpEnv(t)
+			defer testutil.CleanUpEnv(t)
+			content := []byte("[build]\nstrategy = \"copy\"\n")
+			os.MkdirAll(filepath.Dir(pathutil.ConfigTOML()), 0755)
+			if err := ioutil.WriteFile(pathutil.ConfigTOML(), content, 0644); err != nil {
+				t.Fatal("failed to write config.toml: " + err.Error())
+			}
+
+			// =============== run =============== //
+
+			out, err := testutil.RunVolt(append([]string{"config", "set"}, args...)...)
+			// (!A, !B)
+			testutil.FailExit(t, out, err)
+			// (E)
+			if actual, _ := ioutil.ReadFile(pathutil.ConfigTOML()); !bytes.Equal(actual, content) {
+				t.Errorf("config.toml was changed:\n%s", string(actual))
+			}
+		})
+	}
+}
+
+// * Run `volt config list` with unknown key in config.toml (!A, B)
+func TestVoltConfigUnknownKey(t *testing.T) {
+	// =============== setup =============== //
+
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	os.MkdirAll(filepath.Dir(pathutil.ConfigTOML()), 0755)
+	content := []byte("[build]\nstratgy = \"copy\"\n")
+	if err := ioutil.WriteFile(pathutil.ConfigTOML(), content, 0644); err != nil {
+		t.Fatal("failed to write config.toml: " + err.Error())
+	}
+
+	// =============== run =============== //
+
+	out, err := testutil.RunVolt("config", "list")
+	// (B)
+	if err != nil {
+		t.Errorf("expected success exit but exited with failure: status=%q, out=%s", err, string(out))
+	}
+	// (!A)
+	expected := `[WARN] unknown key "build.stratgy" (did you mean "build.strategy"?) in ` + pathutil.ConfigTOML()
+	if !bytes.Contains(out, []byte(expected)) {
+		t.Errorf("expected %q in the output but got:\n%s", expected, string(out))
+	}
+}
+
+func checkConfigValue(t *testing.T, key, expected, expectedList string) {
+	t.Helper()
+	out, err := testutil.RunVolt("config", "get", key)
+	testutil.SuccessExit(t, out, err)
+	if actual := strings.TrimSpace(string(out)); actual != expected {
+		t.Errorf("expected %s = %s but got %s", key, expected, actual)
+	}
+	out, err = testutil.RunVolt("config", "list")
+	testutil.SuccessExit(t, out, err)
+	line := key + " = " + expectedList + "\n"
+	if !bytes.Contains(out, []byte(line)) {
+		t.Errorf("expected %q in the output but got:\n%s", line, string(out))
+	}
+}
@@ -0,0 +1,132 @@
+package subcmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/vim-volt/volt/subcmd/doctor"
+)
+
+func init() {
+	cmdMap["doctor"] = &doctorCmd{}
+}
+
+type doctorCmd struct {
+	helped bool
+	fix    bool
+}
+
+func (cmd *doctorCmd) ProhibitRootExecution(args []string) bool { return true }
+
+func (cmd *doctorCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  volt doctor [-help] [-fix] [{check} ...]
+
+Quick example
+  $ volt doctor           # run all checks
+  $ volt doctor vim       # run only "vim" check
+  $ volt doctor -fix      # run all checks, and fix problems if possible
+
+Description
+  Check common problems of volt environment, and show the result of each check as PASS, WARN or FAIL with a hint to fix the problem.
+  If {check} was given, run only given checks.
+  If -fix option was given, volt fixes the problems which can be fixed safely, and runs the check again.
+  This command exits with non-zero status if any check failed.
+
+Available checks` + "\n")
+		cmd.showAvailableChecks(func(line string) {
+			fmt.Println(line)
+		})
+		fmt.Println()
+		fmt.Println("Options")
+		fs.PrintDefaults()
+		fmt.Println()
+		cmd.helped = true
+	}
+	fs.BoolVar(&cmd.fix, "fix", false, "fix problems if possible")
+	return fs
+}
+
+func (cmd *doctorCmd) Run(args []string) *Error {
+	checkers, err := cmd.parseArgs(args)
+	if err == ErrShowedHelp {
+		return nil
+	}
+	if err != nil {
+		return &Error{Code: 10, Msg: "Failed to parse args: " + err.Error()}
+	}
+
+	failed := cmd.doCheck(checkers)
+	if failed > 0 {
+		return &Error{Code: 11, Msg: fmt.Sprintf("%d check(s) failed", failed)}
+	}
+	return nil
+}
+
+func (cmd *doctorCmd) parseArgs(args []string) ([]doctor.Checker, error) {
+	fs := cmd.FlagSet()
+	fs.Parse(args)
+	if cmd.helped {
+		return nil, ErrShowedHelp
+	}
+	if len(fs.Args()) == 0 {
+		return doctor.ListCheckers(), nil
+	}
+	checkers := make([]doctor.Checker, 0, len(fs.Args()))
+	for _, name := range fs.Args() {
+		c, err := doctor.GetChecker(name)
+		if err != nil {
+			return nil, err
+		}
+		checkers = append(checkers, c)
+	}
+	return checkers, nil
+}
+
+// doCheck runs checkers and returns the number of failed checks.
+func (cmd *doctorCmd) doCheck(checkers []doctor.Checker) int {
+	var failed int
+	for _, c := range checkers {
+		result := c.Check()
+		cmd.printResult(c, result)
+		if cmd.fix && result.Status != doctor.StatusPass && result.Fixable {
+			if err := c.Fix(); err != nil {
+				fmt.Printf("      Failed to fix: %s\n", err)
+			} else {
+				result = c.Check()
+				fmt.Print("      After fix: ")
+				cmd.printResult(c, result)
+			}
+		}
+		if result.Status == doctor.StatusFail {
+			failed++
+		}
+	}
+	return failed
+}
+
+func (cmd *doctorCmd) printResult(c doctor.Checker, result *doctor.Result) {
+	// Indent continuation lines of message
+	msg := strings.Replace(result.Msg, "\n", "\n        ", -1)
+	fmt.Printf("%s  %s: %s\n", result.Status, c.Name(), msg)
+	if result.Status == doctor.StatusPass {
+		return
+	}
+	fmt.Printf("      Hint: %s\n", result.Hint)
+	if result.Fixable && !cmd.fix {
+		fmt.Println("      (this can be fixed by 'volt doctor -fix')")
+	}
+}
+
+func (cmd *doctorCmd) showAvailableChecks(write func(string)) {
+	for _, c := range doctor.ListCheckers() {
+		write(fmt.Sprintf("  %s", c.Name()))
+		write(fmt.Sprintf("    %s", c.Description()))
+	}
+}
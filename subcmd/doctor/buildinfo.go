@@ -0,0 +1,71 @@
+package doctor
+
+import (
+	"strings"
+
+	"github.com/vim-volt/volt/internal/builder"
+	"github.com/vim-volt/volt/internal/buildinfo"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/transaction"
+)
+
+type buildinfoChecker struct {
+	full bool
+}
+
+func (*buildinfoChecker) Name() string {
+	return "buildinfo"
+}
+
+func (*buildinfoChecker) Description() string {
+	return "checks ~/.vim/pack/volt/build-info.json is valid, and repositories of current profile are built"
+}
+
+func (c *buildinfoChecker) Check() *Result {
+	buildInfo, err := buildinfo.Read()
+	if err != nil {
+		c.full = true
+		r := fail(err.Error(), "run 'volt build -full'")
+		r.Fixable = true
+		return r
+	}
+	lockJSON, err := lockjson.ReadNoMigrationMsg()
+	if err != nil {
+		return warn("skipped: could not read lock.json", "see the result of 'lockjson' check")
+	}
+	reposList, err := lockJSON.GetCurrentReposList()
+	if err != nil {
+		return warn("skipped: "+err.Error(), "see the result of 'lockjson' check")
+	}
+
+	var notBuilt []string
+	for i := range reposList {
+		if buildInfo.Repos.FindByReposPath(reposList[i].Path) == nil {
+			notBuilt = append(notBuilt, reposList[i].Path.String())
+		}
+	}
+	if len(notBuilt) > 0 {
+		c.full = false
+		r := warn("repositories of current profile are not built into "+pathutil.VimVoltDir()+":\n"+strings.Join(notBuilt, "\n"),
+			"run 'volt build'")
+		r.Fixable = true
+		return r
+	}
+	return pass("build-info.json is valid")
+}
+
+func (c *buildinfoChecker) Fix() (err error) {
+	// Begin transaction
+	trx, err := transaction.Start()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if e := trx.Done(); e != nil {
+			err = e
+		}
+	}()
+
+	return builder.Build(c.full)
+}
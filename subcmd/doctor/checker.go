@@ -0,0 +1,94 @@
+package doctor
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Checker checks a problem of volt environment.
+type Checker interface {
+	Name() string
+	Description() string
+	// Check checks the environment and returns the result.
+	Check() *Result
+	// Fix fixes the problem found by Check().
+	// This is called only when Result.Fixable is true.
+	Fix() error
+}
+
+// Status is a status of a check result.
+type Status int
+
+const (
+	// StatusPass means no problem was found.
+	StatusPass Status = iota
+	// StatusWarn means a problem was found but volt still works.
+	StatusWarn
+	// StatusFail means a problem was found and volt does not work properly.
+	StatusFail
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusPass:
+		return "PASS"
+	case StatusWarn:
+		return "WARN"
+	default:
+		return "FAIL"
+	}
+}
+
+// Result is a result of Checker.Check().
+type Result struct {
+	Status Status
+	Msg    string
+	// Hint describes how to fix the problem
+	Hint string
+	// Fixable is true if Checker.Fix() can fix the problem
+	Fixable bool
+}
+
+func pass(msg string) *Result {
+	return &Result{Status: StatusPass, Msg: msg}
+}
+
+func warn(msg, hint string) *Result {
+	return &Result{Status: StatusWarn, Msg: msg, Hint: hint}
+}
+
+func fail(msg, hint string) *Result {
+	return &Result{Status: StatusFail, Msg: msg, Hint: hint}
+}
+
+// checkers are run in this order.
+// The checks whose problems cause other checks to fail come first (e.g.
+// "volt doctor -fix" cannot build ~/.vim/pack/volt while stale
+// $VOLTPATH/trx/lock exists).
+var checkers = []Checker{
+	&vimChecker{},
+	&trxLockChecker{},
+	&lockjsonChecker{},
+	&plugconfChecker{},
+	&vimrcChecker{},
+	&optDirChecker{},
+	&buildinfoChecker{},
+}
+
+// GetChecker gets Checker of specified name.
+func GetChecker(name string) (Checker, error) {
+	for _, c := range checkers {
+		if c.Name() == name {
+			return c, nil
+		}
+	}
+	return nil, errors.New("no such check: " + name)
+}
+
+// ListCheckers lists all checkers.
+func ListCheckers() []Checker {
+	return append([]Checker{}, checkers...)
+}
+
+// errNotFixable is returned by Checker.Fix() of the checkers which cannot fix
+// any problems.
+var errNotFixable = errors.New("this problem cannot be fixed automatically")
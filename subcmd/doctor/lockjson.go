@@ -0,0 +1,93 @@
+package doctor
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/subcmd/migrate"
+)
+
+type lockjsonChecker struct{}
+
+func (*lockjsonChecker) Name() string {
+	return "lockjson"
+}
+
+func (*lockjsonChecker) Description() string {
+	return "checks $VOLTPATH/lock.json is valid, and its repositories are installed"
+}
+
+func (c *lockjsonChecker) Check() *Result {
+	if !pathutil.Exists(pathutil.LockJSON()) {
+		return pass("lock.json does not exist (no plugins are installed)")
+	}
+	lockJSON, err := lockjson.ReadNoMigrationMsg()
+	if err != nil {
+		return fail(err.Error(), "fix "+pathutil.LockJSON()+" manually")
+	}
+
+	cfg, err := config.Read()
+	if err != nil {
+		return fail("could not read config.toml: "+err.Error(), "fix "+pathutil.ConfigTOML()+" manually")
+	}
+
+	var missing, missingSystem []string
+	for i := range lockJSON.Repos {
+		repos := &lockJSON.Repos[i]
+		if repos.Type == lockjson.ReposSystemType {
+			if repos.Path.SystemFullPath(cfg.System.ReposPath) == "" {
+				missingSystem = append(missingSystem, repos.Path.String())
+			}
+		} else if !pathutil.Exists(repos.Path.FullPath()) {
+			missing = append(missing, repos.Path.String())
+		}
+	}
+	if len(missing) > 0 {
+		return fail("repositories in lock.json are not found in $VOLTPATH/repos:\n"+strings.Join(missing, "\n"),
+			"run 'volt get "+strings.Join(missing, " ")+"' to re-install them, or 'volt rm' to remove them from lock.json")
+	}
+	if len(missingSystem) > 0 {
+		return fail("system repositories in lock.json are not found in system.repos_path:\n"+strings.Join(missingSystem, "\n"),
+			"ask the administrator to install them, or run 'volt rm' to remove them from lock.json")
+	}
+
+	version, err := c.readVersion()
+	if err != nil {
+		return fail(err.Error(), "fix "+pathutil.LockJSON()+" manually")
+	}
+	if version < lockJSON.Version {
+		r := warn("lock.json format is old", "run 'volt migrate lockjson'")
+		r.Fixable = true
+		return r
+	}
+	return pass("lock.json is valid")
+}
+
+func (*lockjsonChecker) Fix() error {
+	m, err := migrate.GetMigrater("lockjson")
+	if err != nil {
+		return err
+	}
+	return m.Migrate()
+}
+
+// readVersion reads "version" of lock.json without auto-migration.
+func (*lockjsonChecker) readVersion() (int64, error) {
+	content, err := ioutil.ReadFile(pathutil.LockJSON())
+	if err != nil {
+		return 0, errors.Wrap(err, "could not read lock.json")
+	}
+	var v struct {
+		Version int64 `json:"version"`
+	}
+	if err := json.Unmarshal(content, &v); err != nil {
+		return 0, errors.Wrap(err, "could not parse lock.json")
+	}
+	return v.Version, nil
+}
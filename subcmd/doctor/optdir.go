@@ -0,0 +1,71 @@
+package doctor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/pathutil"
+)
+
+type optDirChecker struct{}
+
+func (*optDirChecker) Name() string {
+	return "opt-dir"
+}
+
+func (*optDirChecker) Description() string {
+	return "checks no broken symlink exists in ~/.vim/pack/volt/opt"
+}
+
+func (c *optDirChecker) Check() *Result {
+	broken, err := c.brokenSymlinks()
+	if err != nil {
+		return fail(err.Error(), "check the permission of "+pathutil.VimVoltOptDir())
+	}
+	if len(broken) == 0 {
+		return pass("no broken symlink")
+	}
+	r := fail("broken symlink(s) found:\n"+strings.Join(broken, "\n"),
+		"remove the symlink(s) and run 'volt build'")
+	r.Fixable = true
+	return r
+}
+
+func (c *optDirChecker) Fix() error {
+	broken, err := c.brokenSymlinks()
+	if err != nil {
+		return err
+	}
+	for _, path := range broken {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (*optDirChecker) brokenSymlinks() ([]string, error) {
+	optDir := pathutil.VimVoltOptDir()
+	if !pathutil.Exists(optDir) {
+		return nil, nil
+	}
+	infos, err := ioutil.ReadDir(optDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read "+optDir)
+	}
+	var broken []string
+	for _, fi := range infos {
+		if fi.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		path := filepath.Join(optDir, fi.Name())
+		if _, err := os.Stat(path); err != nil {
+			broken = append(broken, path)
+		}
+	}
+	return broken, nil
+}
@@ -0,0 +1,44 @@
+package doctor
+
+import (
+	"strings"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/plugconf"
+)
+
+type plugconfChecker struct{}
+
+func (*plugconfChecker) Name() string {
+	return "plugconf"
+}
+
+func (*plugconfChecker) Description() string {
+	return "checks plugconf files of installed repositories can be parsed"
+}
+
+func (*plugconfChecker) Check() *Result {
+	lockJSON, err := lockjson.ReadNoMigrationMsg()
+	if err != nil {
+		return warn("skipped: could not read lock.json", "see the result of 'lockjson' check")
+	}
+	// ParseMultiPlugconf() sorts the given slice
+	reposList := append([]lockjson.Repos{}, lockJSON.Repos...)
+	_, parseErr := plugconf.ParseMultiPlugconf(reposList)
+	if parseErr.HasErrs() || parseErr.HasWarns() {
+		var msgs []string
+		for _, err := range parseErr.ErrorsAndWarns().Errors {
+			msgs = append(msgs, err.Error())
+		}
+		const hint = "fix the plugconf files above (run 'volt edit {repository}' to edit plugconf)"
+		if parseErr.HasErrs() {
+			return fail("plugconf has error(s):\n"+strings.Join(msgs, "\n"), hint)
+		}
+		return warn("plugconf has warning(s):\n"+strings.Join(msgs, "\n"), hint)
+	}
+	return pass("all plugconf files are valid")
+}
+
+func (*plugconfChecker) Fix() error {
+	return errNotFixable
+}
@@ -0,0 +1,54 @@
+package doctor
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/transaction"
+)
+
+type trxLockChecker struct{}
+
+func (*trxLockChecker) Name() string {
+	return "trx-lock"
+}
+
+func (*trxLockChecker) Description() string {
+	return "checks no stale lock directory of transaction ($VOLTPATH/trx/lock) exists"
+}
+
+func (c *trxLockChecker) Check() *Result {
+	lockDir := transaction.LockDir()
+	if !pathutil.Exists(lockDir) {
+		return pass("no transaction is running")
+	}
+	owner, err := transaction.ReadLockOwner()
+	if err != nil {
+		// The lock was created by older versions, or the owner is just
+		// beginning a transaction
+		return warn(lockDir+" exists: other volt process is running, or a volt process crashed earlier (unknown owner: "+err.Error()+")",
+			"make sure no other volt process is running, and remove "+lockDir)
+	}
+	running, err := owner.IsRunning()
+	if err != nil {
+		return warn(fmt.Sprintf("%s exists: could not check the owner process (pid %d): %s", lockDir, owner.PID, err.Error()),
+			"make sure no other volt process is running, and remove "+lockDir)
+	}
+	if running {
+		return warn(fmt.Sprintf("%s exists: other volt process (pid %d) is running", lockDir, owner.PID),
+			"wait for the process to finish")
+	}
+	r := warn(fmt.Sprintf("%s exists: the volt process (pid %d) crashed earlier", lockDir, owner.PID),
+		"remove "+lockDir)
+	r.Fixable = true
+	return r
+}
+
+// Fix removes the lock only if the owner process is not running.
+func (c *trxLockChecker) Fix() error {
+	if !c.Check().Fixable {
+		return errNotFixable
+	}
+	return os.RemoveAll(transaction.LockDir())
+}
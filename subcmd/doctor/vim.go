@@ -0,0 +1,37 @@
+package doctor
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/vim-volt/volt/pathutil"
+)
+
+type vimChecker struct{}
+
+func (*vimChecker) Name() string {
+	return "vim"
+}
+
+func (*vimChecker) Description() string {
+	return "checks Vim executable is found in $VOLT_VIM or $PATH"
+}
+
+func (*vimChecker) Check() *Result {
+	const hint = "install Vim and add the directory to $PATH, or set $VOLT_VIM to the path of Vim executable"
+	vim, err := pathutil.VimExecutable()
+	if err != nil {
+		return fail("Vim executable is not found: "+err.Error(), hint)
+	}
+	// $VOLT_VIM is returned as it is
+	if os.Getenv("VOLT_VIM") != "" {
+		if _, err := exec.LookPath(vim); err != nil {
+			return fail("$VOLT_VIM is not an executable: "+err.Error(), hint)
+		}
+	}
+	return pass("found " + vim)
+}
+
+func (*vimChecker) Fix() error {
+	return errNotFixable
+}
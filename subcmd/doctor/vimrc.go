@@ -0,0 +1,69 @@
+package doctor
+
+import (
+	"path/filepath"
+
+	"github.com/vim-volt/volt/internal/builder"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+type vimrcChecker struct{}
+
+func (*vimrcChecker) Name() string {
+	return "vimrc"
+}
+
+func (*vimrcChecker) Description() string {
+	return "checks vimrc and gvimrc of profiles can be installed, and are read by Vim"
+}
+
+func (c *vimrcChecker) Check() *Result {
+	lockJSON, err := lockjson.ReadNoMigrationMsg()
+	if err != nil {
+		return warn("skipped: could not read lock.json", "see the result of 'lockjson' check")
+	}
+	profile, err := lockJSON.Profiles.FindByName(lockJSON.CurrentProfileName)
+	if err != nil {
+		return warn("skipped: "+err.Error(), "see the result of 'lockjson' check")
+	}
+	profiles, err := lockJSON.ResolveProfiles(profile)
+	if err != nil {
+		return warn("skipped: "+err.Error(), "see the result of 'lockjson' check")
+	}
+
+	for _, rc := range []struct {
+		src      string
+		dst      string
+		lookedUp []string
+	}{
+		{pathutil.ProfileVimrc, pathutil.Vimrc, pathutil.LookUpVimrc()},
+		{pathutil.ProfileGvimrc, pathutil.Gvimrc, pathutil.LookUpGvimrc()},
+	} {
+		var src string
+		for _, p := range profiles {
+			path := filepath.Join(pathutil.RCDir(p.Name), rc.src)
+			if pathutil.Exists(path) {
+				src = path
+			}
+		}
+		dst := filepath.Join(pathutil.VimDir(), rc.dst)
+		if !pathutil.Exists(dst) {
+			continue
+		}
+		generated := (&builder.BaseBuilder{}).HasMagicComment(dst)
+		if !generated && src != "" {
+			return fail("'"+dst+"' does not have the magic comment of volt, so 'volt build' cannot install '"+src+"'",
+				"move '"+dst+"' to '"+pathutil.RCDir(profile.Name)+"' (or merge it into '"+src+"'), and run 'volt build'")
+		}
+		if generated && len(rc.lookedUp) > 1 && rc.lookedUp[0] != dst {
+			return warn("'"+rc.lookedUp[0]+"' exists, so Vim does not read '"+dst+"' generated by volt",
+				"move '"+rc.lookedUp[0]+"' to '"+pathutil.RCDir(profile.Name)+"', and run 'volt build'")
+		}
+	}
+	return pass("vimrc and gvimrc are installed properly")
+}
+
+func (*vimrcChecker) Fix() error {
+	return errNotFixable
+}
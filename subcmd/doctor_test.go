@@ -0,0 +1,128 @@
+package subcmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/internal/testutil"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// Checks:
+// (A) Does not show `[ERROR]`, `[WARN]` messages
+// (B) Exit with zero status
+// (C) Shows the expected result of the check
+// (D) The problem is fixed
+//
+// * Run `volt doctor` (no problems) (A, B, C)
+// * Run `volt doctor` ($VOLTPATH/trx/lock exists) (A, B, C, !D)
+// * Run `volt doctor -fix` ($VOLTPATH/trx/lock of a crashed process exists) (A, B, C, D)
+// * Run `volt doctor -fix` ($VOLTPATH/trx/lock of a running process exists) (A, B, C, !D)
+// * Run `volt doctor -fix` ($VOLTPATH/trx/lock of unknown owner exists) (A, B, C, !D)
+// * Run `volt doctor` (broken symlink exists in opt dir) (!A, !B, C, !D)
+// * Run `volt doctor -fix` (broken symlink exists in opt dir) (A, B, C, D)
+// * Run `volt doctor opt-dir` (no problems) (A, B, C)
+// * Run `volt doctor not_existing_check` (!A, !B)
+func TestVoltDoctor(t *testing.T) {
+	trxLock := func() string {
+		return filepath.Join(pathutil.TrxDir(), "lock")
+	}
+	trxLockOf := func(pid int) func() string {
+		return func() string {
+			lock := trxLock()
+			hostname, _ := os.Hostname()
+			content := fmt.Sprintf(`{"pid":%d,"hostname":%q}`, pid, hostname)
+			os.MkdirAll(lock, 0755)
+			if err := ioutil.WriteFile(filepath.Join(lock, "owner"), []byte(content), 0644); err != nil {
+				t.Fatal("failed to write owner: " + err.Error())
+			}
+			return lock
+		}
+	}
+	// PID of a process which exited
+	exited := exec.Command(os.Args[0], "-test.run=^$")
+	if err := exited.Run(); err != nil {
+		t.Fatal("failed to run a process: " + err.Error())
+	}
+	crashedPID := exited.ProcessState.Pid()
+	brokenSymlink := func() string {
+		return filepath.Join(pathutil.VimVoltOptDir(), "broken")
+	}
+	for _, tt := range []struct {
+		name     string
+		args     []string
+		problem  func() string
+		success  bool
+		expected string
+		fixed    bool
+	}{
+		{"no problems", []string{}, nil, true, "PASS  trx-lock", false},
+		{"trx lock", []string{}, trxLock, true, "WARN  trx-lock", false},
+		{"fix trx lock", []string{"-fix"}, trxLockOf(crashedPID), true, "After fix: PASS  trx-lock", true},
+		{"fix trx lock of running process", []string{"-fix"}, trxLockOf(os.Getpid()), true, "other volt process (pid", false},
+		{"fix trx lock of unknown owner", []string{"-fix"}, trxLock, true, "unknown owner", false},
+		{"broken symlink", []string{}, brokenSymlink, false, "FAIL  opt-dir", false},
+		{"fix broken symlink", []string{"-fix"}, brokenSymlink, true, "After fix: PASS  opt-dir", true},
+		{"specified check", []string{"opt-dir"}, nil, true, "PASS  opt-dir", false},
+		{"not existing check", []string{"not_existing_check"}, nil, false, "", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			testProfileMatrix(t, func(t *testing.T, strategy string) {
+				// =============== setup =============== //
+
+				testutil.SetUpEnv(t)
+				defer testutil.CleanUpEnv(t)
+				reposPath := pathutil.ReposPath("localhost/local/hello")
+				teardown := testutil.SetUpRepos(t, "hello", lockjson.ReposStaticType, []pathutil.ReposPath{reposPath}, strategy)
+				defer teardown()
+				testutil.InstallConfig(t, "strategy-"+strategy+".toml")
+				out, err := testutil.RunVolt("build")
+				testutil.SuccessExit(t, out, err)
+
+				var problem string
+				if tt.problem != nil {
+					problem = tt.problem()
+					if strings.HasSuffix(problem, "lock") {
+						err = os.MkdirAll(problem, 0755)
+					} else {
+						err = os.Symlink(filepath.Join(pathutil.VimVoltOptDir(), "not_existing"), problem)
+					}
+					if err != nil {
+						t.Fatal("failed to make a problem: " + err.Error())
+					}
+				}
+
+				// =============== run =============== //
+
+				out, err = testutil.RunVolt(append([]string{"doctor"}, tt.args...)...)
+				if tt.success {
+					// (A, B)
+					testutil.SuccessExit(t, out, err)
+				} else {
+					// (!A, !B)
+					testutil.FailExit(t, out, err)
+				}
+
+				// (C)
+				if !bytes.Contains(out, []byte(tt.expected)) {
+					t.Errorf("expected output contains %q but got: %s", tt.expected, string(out))
+				}
+
+				// (D)
+				if problem != "" {
+					_, err := os.Lstat(problem)
+					if fixed := os.IsNotExist(err); fixed != tt.fixed {
+						t.Errorf("expected fixed is %v but got %v: %s", tt.fixed, fixed, problem)
+					}
+				}
+			})
+		})
+	}
+}
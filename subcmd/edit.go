@@ -8,9 +8,11 @@ import (
 	"os/exec"
 
 	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/internal/builder"
+	"github.com/vim-volt/volt/lockjson"
 	"github.com/vim-volt/volt/logger"
 	"github.com/vim-volt/volt/pathutil"
-	"github.com/vim-volt/volt/subcmd/builder"
+	"github.com/vim-volt/volt/transaction"
 )
 
 func init() {
@@ -88,7 +90,19 @@ func (cmd *editCmd) doEdit(reposPathList []pathutil.ReposPath) (bool, error) {
 		return false, &Error{Code: 30, Msg: "No usable editor found"}
 	}
 
+	// Read lock.json
+	lockJSON, err := lockjson.Read()
+	if err != nil {
+		return false, errors.New("could not read lock.json: " + err.Error())
+	}
+
+	templates, err := newTemplateFetcher(cfg)
+	if err != nil {
+		return false, err
+	}
+
 	changeWasMade := false
+	tmplSources := make(map[pathutil.ReposPath]*lockjson.PlugconfTemplate)
 	for _, reposPath := range reposPathList {
 
 		// Edit plugconf file
@@ -96,9 +110,16 @@ func (cmd *editCmd) doEdit(reposPathList []pathutil.ReposPath) (bool, error) {
 
 		// Install a new template if none exists
 		if !pathutil.Exists(plugconfPath) {
-			getCmd := new(getCmd)
 			logger.Debugf("Installing new plugconf for '%s'.", reposPath)
-			getCmd.downloadPlugconf(reposPath)
+			var recorded *lockjson.PlugconfTemplate
+			repos := lockJSON.Repos.FindByPath(reposPath)
+			if repos != nil {
+				recorded = repos.PlugconfTemplate
+			}
+			source, err := templates.Install(reposPath, recorded)
+			if err == nil && repos != nil && source != nil && (recorded == nil || *source != *recorded) {
+				tmplSources[reposPath] = source
+			}
 		}
 
 		// Remember modification time before opening the editor
@@ -113,7 +134,7 @@ func (cmd *editCmd) doEdit(reposPathList []pathutil.ReposPath) (bool, error) {
 		editorCmd.Stdin = os.Stdin
 		editorCmd.Stdout = os.Stdout
 		if err = editorCmd.Run(); err != nil {
-			logger.Error("Error calling editor for '%s': %s", reposPath, err.Error)
+			logger.Errorf("Error calling editor for '%s': %s", reposPath, err.Error())
 			continue
 		}
 
@@ -128,9 +149,44 @@ func (cmd *editCmd) doEdit(reposPathList []pathutil.ReposPath) (bool, error) {
 		changeWasMade = changeWasMade || mTimeAfter.After(mTimeBefore)
 	}
 
+	if len(tmplSources) > 0 {
+		if err := cmd.writeTemplateSources(tmplSources); err != nil {
+			return false, err
+		}
+	}
+
 	return changeWasMade, nil
 }
 
+// writeTemplateSources records where plugconf templates were fetched from to
+// lock.json.
+func (*editCmd) writeTemplateSources(tmplSources map[pathutil.ReposPath]*lockjson.PlugconfTemplate) (err error) {
+	// Begin transaction
+	trx, err := transaction.Start()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if e := trx.Done(); e != nil {
+			err = e
+		}
+	}()
+
+	lockJSON, err := lockjson.Read()
+	if err != nil {
+		return errors.New("could not read lock.json: " + err.Error())
+	}
+	for reposPath, source := range tmplSources {
+		if repos := lockJSON.Repos.FindByPath(reposPath); repos != nil {
+			repos.PlugconfTemplate = source
+		}
+	}
+	if err = lockJSON.Write(); err != nil {
+		return errors.New("could not write to lock.json: " + err.Error())
+	}
+	return nil
+}
+
 func (cmd *editCmd) parseArgs(args []string) (pathutil.ReposPathList, error) {
 	fs := cmd.FlagSet()
 	fs.Parse(args)
@@ -0,0 +1,127 @@
+package subcmd
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// externalCmdPrefix is the prefix of external subcommand executables.
+// "volt {name}" runs "volt-{name}" if {name} is not a builtin command.
+const externalCmdPrefix = "volt-"
+
+// externalCmd runs an external subcommand executable "volt-{name}".
+type externalCmd struct {
+	name string
+	path string
+}
+
+// lookUpExternalCmd looks up "volt-{name}" executable from $VOLTPATH/bin and
+// PATH.
+func lookUpExternalCmd(name string) (*externalCmd, bool) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return nil, false
+	}
+	exe := externalCmdPrefix + name
+	if path, err := exec.LookPath(filepath.Join(pathutil.BinDir(), exe)); err == nil {
+		return &externalCmd{name: name, path: path}, true
+	}
+	if path, err := exec.LookPath(exe); err == nil {
+		return &externalCmd{name: name, path: path}, true
+	}
+	return nil, false
+}
+
+// listExternalCmds returns external subcommands found in $VOLTPATH/bin and
+// PATH, which do not conflict with builtin commands.
+// If the same name is found in some directories, the first one is used.
+func listExternalCmds() []externalCmd {
+	dirs := append([]string{pathutil.BinDir()}, filepath.SplitList(os.Getenv("PATH"))...)
+	found := make(map[string]bool)
+	var cmds []externalCmd
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, fi := range files {
+			name := fi.Name()
+			if !strings.HasPrefix(name, externalCmdPrefix) || fi.IsDir() {
+				continue
+			}
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+			name = name[len(externalCmdPrefix):]
+			if _, builtin := cmdMap[name]; builtin || found[name] {
+				continue
+			}
+			if cmd, ok := lookUpExternalCmd(name); ok {
+				found[name] = true
+				cmds = append(cmds, *cmd)
+			}
+		}
+	}
+	sort.Slice(cmds, func(i, j int) bool {
+		return cmds[i].name < cmds[j].name
+	})
+	return cmds
+}
+
+// External commands may modify files, like builtin commands
+func (cmd *externalCmd) ProhibitRootExecution(args []string) bool { return true }
+
+// FlagSet returns an empty flag set because arguments are passed through to
+// the external command.
+func (cmd *externalCmd) FlagSet() *flag.FlagSet {
+	return flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+}
+
+// Run runs the external command with args. VOLTPATH, VOLT_LOCK_JSON (the path
+// of lock.json), VOLT_CURRENT_PROFILE (current profile name), and VOLT_VIM
+// (Vim executable path) environment variables are exported.
+// If the external command exited with non-zero status, volt exits with the
+// same status.
+func (cmd *externalCmd) Run(args []string) *Error {
+	c := exec.Command(cmd.path, args...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	c.Env = append(os.Environ(), cmd.environ()...)
+	err := c.Run()
+	if err == nil {
+		return nil
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if st, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+			return &Error{Code: st.ExitStatus()}
+		}
+	}
+	return &Error{Code: 30, Msg: "Failed to run " + cmd.path + ": " + err.Error()}
+}
+
+func (cmd *externalCmd) environ() []string {
+	env := []string{
+		"VOLTPATH=" + pathutil.VoltPath(),
+		"VOLT_LOCK_JSON=" + pathutil.LockJSON(),
+	}
+	if lockJSON, err := lockjson.ReadNoMigrationMsg(); err == nil {
+		env = append(env, "VOLT_CURRENT_PROFILE="+lockJSON.CurrentProfileName)
+	}
+	if vim, err := pathutil.VimExecutable(); err == nil {
+		env = append(env, "VOLT_VIM="+vim)
+	}
+	return env
+}
@@ -0,0 +1,94 @@
+package subcmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/vim-volt/volt/internal/testutil"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// Checks:
+// (A) Does not show `[ERROR]`, `[WARN]` messages
+// (B) Exit with zero status
+// (C) External command receives arguments
+// (D) External command receives environment variables
+// (E) Exit with the exit status of external command
+// (F) `volt help` lists external commands
+
+// Run `volt {name} {args}` ($VOLTPATH/bin/volt-{name} exists) (A, B, C, D, F)
+// Run `volt {name}` (volt-{name} exits with non-zero status) (!B, E)
+// Run `volt {builtin}` (volt-{builtin} exists): builtin command is run
+func TestVoltExternalCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script is not executable on Windows")
+	}
+
+	// =============== setup =============== //
+
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	os.MkdirAll(pathutil.BinDir(), 0777)
+	script := `#!/bin/sh
+echo "args: $*"
+echo "VOLTPATH: $VOLTPATH"
+echo "VOLT_LOCK_JSON: $VOLT_LOCK_JSON"
+echo "VOLT_CURRENT_PROFILE: $VOLT_CURRENT_PROFILE"
+if [ "$1" = fail ]; then
+	exit 3
+fi
+`
+	for _, name := range []string{"hello", "version"} {
+		path := filepath.Join(pathutil.BinDir(), "volt-"+name)
+		if err := ioutil.WriteFile(path, []byte(script), 0755); err != nil {
+			t.Fatal("failed to write external command: " + err.Error())
+		}
+	}
+
+	// =============== run =============== //
+
+	out, err := testutil.RunVolt("hello", "foo", "-bar")
+	// (A, B)
+	testutil.SuccessExit(t, out, err)
+	// (C, D)
+	for _, line := range []string{
+		"args: foo -bar",
+		"VOLTPATH: " + pathutil.VoltPath(),
+		"VOLT_LOCK_JSON: " + pathutil.LockJSON(),
+		"VOLT_CURRENT_PROFILE: default",
+	} {
+		if !bytes.Contains(out, []byte(line+"\n")) {
+			t.Errorf("expected output contains %q but got: %s", line, string(out))
+		}
+	}
+
+	out, err = testutil.RunVolt("hello", "fail")
+	// (!B, E)
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 3 {
+		t.Errorf("expected exit status 3 but got %v: %s", err, string(out))
+	}
+
+	out, err = testutil.RunVolt("version")
+	testutil.SuccessExit(t, out, err)
+	if bytes.Contains(out, []byte("args:")) {
+		t.Errorf("expected builtin command is run but external command was run: %s", string(out))
+	}
+
+	// (F)
+	cmdlist, err := testutil.GetCmdList()
+	if err != nil {
+		t.Fatal("testutil.GetCmdList() returned non-nil error: " + err.Error())
+	}
+	found := false
+	for _, cmd := range cmdlist {
+		found = found || cmd == "hello"
+	}
+	if !found {
+		t.Errorf("expected 'volt help' lists external command 'hello' but got %v", cmdlist)
+	}
+}
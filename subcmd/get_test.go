@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/pkg/errors"
 
+	"github.com/vim-volt/volt/config"
 	"github.com/vim-volt/volt/internal/testutil"
 	"github.com/vim-volt/volt/lockjson"
 	"github.com/vim-volt/volt/pathutil"
@@ -36,6 +38,9 @@ import (
 // (N) Output contains "* {repos} > updated lock.json revision ({from}..{to})"
 // (O) Output contains "* {repos} > upgraded ({from}..{to})"
 // (P) Output contains "{repos}: HEAD and locked revision are different ..."
+// (Q) Output contains "+ {repos} > added system repository ({dir})"
+// (R) System repositories are not cloned at `$VOLTPATH/repos/<repos>/`
+// (S) Plugconf files are created from the template of `plugconf.template_sources`, and the source is recorded to lock.json
 
 // TODO: Add test cases
 // * Specify plugins which have dependency plugins without help (A, B, C, D, E, F, !G) / with help (A, B, C, D, E, F, G)
@@ -528,6 +533,201 @@ func TestErrVoltGetDupRepos(t *testing.T) {
 	}
 }
 
+// Run `volt get {system repos}` and `volt get -u {system repos}` (A, B, D, F, N, Q, R)
+func TestVoltGetSystemRepos(t *testing.T) {
+	testGetMatrix(t, func(t *testing.T, strategy string) {
+		// =============== setup =============== //
+
+		testutil.SetUpEnv(t)
+		defer testutil.CleanUpEnv(t)
+		reposPath := pathutil.ReposPath("localhost/system/hello")
+		sysDir, r := setUpSystemRepos(t, reposPath, strategy)
+		head, err := r.Head()
+		if err != nil {
+			t.Fatal("failed to get HEAD: " + err.Error())
+		}
+
+		// =============== run =============== //
+
+		out, err := testutil.RunVolt("get", reposPath.String())
+		// (A, B)
+		testutil.SuccessExit(t, out, err)
+
+		// (D)
+		if !pathutil.Exists(reposPath.Plugconf()) {
+			t.Error("plugconf was not created: " + reposPath.Plugconf())
+		}
+
+		// (F)
+		testReposPathWereAdded(t, reposPath)
+		checkSystemReposVersion(t, reposPath, head.Hash().String())
+
+		// (Q)
+		msg := fmt.Sprintf(fmtAddedSystemRepos, reposPath, filepath.Join(sysDir, "localhost", "system", "hello"))
+		if !bytes.Contains(out, []byte(msg)) {
+			t.Errorf("Output does not contain %q\n%s", msg, string(out))
+		}
+
+		// (R)
+		if pathutil.Exists(reposPath.FullPath()) {
+			t.Error("system repository was cloned: " + reposPath.FullPath())
+		}
+		if !pathutil.Exists(filepath.Join(reposPath.EncodeToPlugDirName(), "plugin", "hello.vim")) {
+			t.Error("system repository was not installed: " + reposPath.EncodeToPlugDirName())
+		}
+
+		// Administrator upgrades the system repository
+		w, err := r.Worktree()
+		if err != nil {
+			t.Fatal("failed to get worktree: " + err.Error())
+		}
+		next, err := w.Commit("upgrade", &git.CommitOptions{
+			Author: &object.Signature{Name: "John Doe", Email: "john@doe.org", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatal("failed to commit: " + err.Error())
+		}
+
+		out, err = testutil.RunVolt("get", "-u", reposPath.String())
+		// (A, B)
+		testutil.SuccessExit(t, out, err)
+
+		// (N)
+		msg = fmt.Sprintf(fmtRevUpdate, reposPath, head.Hash().String(), next.String())
+		if !bytes.Contains(out, []byte(msg)) {
+			t.Errorf("Output does not contain %q\n%s", msg, string(out))
+		}
+
+		// (F)
+		checkSystemReposVersion(t, reposPath, next.String())
+	})
+}
+
+// Plugconf is created from the first template source which has the template,
+// and the recorded source is used first even if other sources have the
+// template (A, B, D, S)
+func TestVoltGetPlugconfTemplateSources(t *testing.T) {
+	// =============== setup =============== //
+
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	reposPath := pathutil.ReposPath("localhost/system/hello")
+	setUpSystemRepos(t, reposPath, config.SymlinkBuilder)
+	noTmplDir := filepath.Join(os.Getenv("HOME"), "templates", "none")
+	os.MkdirAll(noTmplDir, 0755)
+	tmplDir := filepath.Join(os.Getenv("HOME"), "templates", "team")
+	writePlugconfTemplate(t, tmplDir, reposPath, "filetype=vim")
+	setUpTemplateSources(t, noTmplDir, "file://"+filepath.ToSlash(tmplDir))
+	expected := "file://" + filepath.ToSlash(tmplDir)
+
+	for i := 0; i < 2; i++ {
+		// =============== run =============== //
+
+		out, err := testutil.RunVolt("get", reposPath.String())
+		// (A, B)
+		testutil.SuccessExit(t, out, err)
+
+		// (D, S)
+		plugconf, err := ioutil.ReadFile(reposPath.Plugconf())
+		if err != nil {
+			t.Fatal("plugconf was not created: " + err.Error())
+		}
+		if !bytes.Contains(plugconf, []byte("filetype=vim")) {
+			t.Errorf("plugconf was not created from the template:\n%s", string(plugconf))
+		}
+		lockJSON, err := lockjson.Read()
+		if err != nil {
+			t.Fatal("lockjson.Read() returned non-nil error: " + err.Error())
+		}
+		repos := lockJSON.Repos.FindByPath(reposPath)
+		if repos == nil || repos.PlugconfTemplate == nil || repos.PlugconfTemplate.Source != expected || repos.PlugconfTemplate.Version == "" {
+			t.Errorf("expected plugconf template source %s but got %+v", expected, repos)
+		}
+
+		// The first source also has the template, but the recorded source
+		// is used
+		writePlugconfTemplate(t, noTmplDir, reposPath, "excmd=Hello")
+		os.Remove(reposPath.Plugconf())
+	}
+}
+
+// setUpSystemRepos creates a git repository reposPath in a system repository
+// directory, and installs config.toml which has the directory in
+// system.repos_path.
+func setUpSystemRepos(t *testing.T, reposPath pathutil.ReposPath, strategy string) (string, *git.Repository) {
+	t.Helper()
+	sysDir := filepath.Join(os.Getenv("HOME"), "system", "repos")
+	dir := filepath.Join(sysDir, filepath.FromSlash(reposPath.String()))
+	os.MkdirAll(filepath.Join(dir, "plugin"), 0755)
+	if err := ioutil.WriteFile(filepath.Join(dir, "plugin", "hello.vim"), []byte("\n"), 0644); err != nil {
+		t.Fatal("failed to write plugin: " + err.Error())
+	}
+	r, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal("failed to init repository: " + err.Error())
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal("failed to get worktree: " + err.Error())
+	}
+	if _, err := w.Add("plugin/hello.vim"); err != nil {
+		t.Fatal("failed to add file: " + err.Error())
+	}
+	_, err = w.Commit("initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "John Doe", Email: "john@doe.org", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal("failed to commit: " + err.Error())
+	}
+
+	content := fmt.Sprintf("[build]\nstrategy = %q\n\n[system]\nrepos_path = [%q]\n", strategy, sysDir)
+	if err := ioutil.WriteFile(pathutil.ConfigTOML(), []byte(content), 0644); err != nil {
+		t.Fatal("failed to write config.toml: " + err.Error())
+	}
+	return sysDir, r
+}
+
+// writePlugconfTemplate writes the template of reposPath to template source
+// dir, which returns loadOn from s:loaded_on().
+func writePlugconfTemplate(t *testing.T, dir string, reposPath pathutil.ReposPath, loadOn string) {
+	t.Helper()
+	file := filepath.Join(dir, "templates", filepath.FromSlash(reposPath.String())+".vim")
+	os.MkdirAll(filepath.Dir(file), 0755)
+	content := fmt.Sprintf("function! s:loaded_on()\n  return '%s'\nendfunction\n", loadOn)
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal("failed to write template: " + err.Error())
+	}
+}
+
+// setUpTemplateSources appends plugconf.template_sources to config.toml.
+func setUpTemplateSources(t *testing.T, sources ...string) {
+	t.Helper()
+	cfg, err := ioutil.ReadFile(pathutil.ConfigTOML())
+	if err != nil {
+		t.Fatal("failed to read config.toml: " + err.Error())
+	}
+	quoted := make([]string, 0, len(sources))
+	for i := range sources {
+		quoted = append(quoted, fmt.Sprintf("%q", sources[i]))
+	}
+	cfg = append(cfg, fmt.Sprintf("\n[plugconf]\ntemplate_sources = [%s]\n", strings.Join(quoted, ", "))...)
+	if err := ioutil.WriteFile(pathutil.ConfigTOML(), cfg, 0644); err != nil {
+		t.Fatal("failed to write config.toml: " + err.Error())
+	}
+}
+
+func checkSystemReposVersion(t *testing.T, reposPath pathutil.ReposPath, version string) {
+	t.Helper()
+	lockJSON, err := lockjson.Read()
+	if err != nil {
+		t.Fatal("lockjson.Read() returned non-nil error: " + err.Error())
+	}
+	repos := lockJSON.Repos.FindByPath(reposPath)
+	if repos == nil || repos.Type != lockjson.ReposSystemType || repos.Version != version {
+		t.Errorf("expected system repository with version %s but got %+v", version, repos)
+	}
+}
+
 func testReposPathWereAdded(t *testing.T, reposPath pathutil.ReposPath) {
 	t.Helper()
 	lockJSON, err := lockjson.Read()
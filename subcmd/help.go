@@ -37,12 +37,22 @@ func (cmd *helpCmd) FlagSet() *flag.FlagSet {
 				" '----------------'  '----------------'  '----------------'  '----------------'\n" +
 				`
 Usage
-  volt COMMAND ARGS
+  volt [-v | -q] COMMAND ARGS
+
+Global options
+  -v, -verbose
+    Show debug messages (same as VOLT_DEBUG=1)
+
+  -q, -quiet
+    Show only warning and error messages
 
 Command
   get [-l] [-u] [{repository} ...]
     Install or upgrade given {repository} list, or add local {repository} list as plugins
 
+  add [-name {repository}] [-link] {directory}
+    Import an existing local {directory} as a static repository
+
   rm [-r] [-p] {repository} [{repository2} ...]
     Remove vim plugin from ~/.vim/pack/volt/opt/ directory
 
@@ -50,6 +60,9 @@ Command
     Vim plugin information extractor.
     Unless -f flag was given, this command shows vim plugins of **current profile** (not all installed plugins) by default.
 
+  status
+    Show current profile, and installed repositories with their locked versions (and HEAD of system repositories)
+
   enable {repository} [{repository2} ...]
     This is shortcut of:
     volt profile add -current {repository} [{repository2} ...]
@@ -61,6 +74,15 @@ Command
   edit [-e|--editor {editor}] {repository} [{repository2} ...]
     Open the plugconf file(s) of one or more {repository} for editing.
 
+  plugconf diff {repository} [{repository2} ...]
+    Show differences between plugconf files and the latest plugconf templates
+
+  plugconf reset {repository} [{repository2} ...]
+    Overwrite plugconf files with the latest plugconf templates
+
+  plugconf update [{repository} ...]
+    Merge changes of plugconf templates into plugconf files
+
   profile set {name}
     Set profile name
 
@@ -85,18 +107,60 @@ Command
   profile rm {name} {repository} [{repository2} ...]
     Remove one or more repositories to profile
 
-  build [-full]
+  profile extend {name} {profile} [{profile2} ...]
+    Make profile inherit repositories and rc files from other profiles
+
+  profile unextend {name} {profile} [{profile2} ...]
+    Make profile stop inheriting from other profiles
+
+  config get {key}
+    Show the effective value of {key} in config files and environment variables
+
+  config set [-system | -project] {key} {value}
+    Set {key} to {value} in config file
+
+  config unset [-system | -project] {key}
+    Remove {key} from config file
+
+  config list
+    Show all effective values and where they come from
+
+  build [-full] [-dry-run] [-rollback]
     Build ~/.vim/pack/volt/ directory
 
+  run [-p {profile}] [+{repository} ...] [-- {vim args}]
+    Launch Vim with plugins of {profile} and given {repository} list, without modifying ~/.vim/pack/volt/ directory
+
+  bisect [-p {profile}] [-run {command}] [-- {vim args}]
+    Find the minimal set of plugins of {profile} which cause a problem, by launching Vim with each half of them
+
+  profile-startup [-n {count}] [-json] [-threshold {msec}] [-- {vim args}]
+    Measure startup time of each plugin, and suggest lazy loading for heavy plugins
+
+  lint [-fix] [{repository} ...]
+    Check plugconf files of given repositories, and fix problems if -fix was given
+
+  doctor [-fix] [{check} ...]
+    Check common problems of volt environment, and fix them if -fix was given
+
   migrate {migration operation}
     Perform miscellaneous migration operations.
     See 'volt migrate -help' for all available operations
 
+  completion {shell}
+    Output the completion script of {shell} (bash, zsh, or fish)
+
   self-upgrade [-check]
     Upgrade to the latest volt command, or if -check was given, it only checks the newer version is available
 
   version
     Show volt command version` + "\n\n")
+		if exts := listExternalCmds(); len(exts) > 0 {
+			fmt.Println("External command")
+			for i := range exts {
+				fmt.Printf("  %s\n    %s\n\n", exts[i].name, exts[i].path)
+			}
+		}
 		//cmd.helped = true
 	}
 	return fs
@@ -111,11 +175,17 @@ func (cmd *helpCmd) Run(args []string) *Error {
 		return &Error{Code: 47, Msg: "E478: Don't panic!"}
 	}
 
-	fs, exists := cmdMap[args[0]]
+	name := args[0]
+	args = append([]string{"-help"}, args[1:]...)
+	fs, exists := cmdMap[name]
 	if !exists {
-		return &Error{Code: 1, Msg: fmt.Sprintf("Unknown command '%s'", args[0])}
+		// Show help of external command by "volt-{name} -help"
+		ext, found := lookUpExternalCmd(name)
+		if !found {
+			return &Error{Code: 1, Msg: fmt.Sprintf("Unknown command '%s'", name)}
+		}
+		return ext.Run(args)
 	}
-	args = append([]string{"-help"}, args[1:]...)
 	fs.Run(args)
 	return nil
 }
@@ -0,0 +1,153 @@
+package subcmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/plugconf"
+)
+
+func init() {
+	cmdMap["lint"] = &lintCmd{}
+}
+
+type lintCmd struct {
+	helped bool
+	fix    bool
+}
+
+func (cmd *lintCmd) ProhibitRootExecution(args []string) bool { return true }
+
+func (cmd *lintCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  volt lint [-help] [-fix] [{repository} ...]
+
+Quick example
+  $ volt lint                  # check plugconf files of all installed repositories
+  $ volt lint tyru/caw.vim     # check plugconf file of tyru/caw.vim
+  $ volt lint -fix             # check plugconf files, and fix problems if possible
+
+Description
+  Check plugconf files of given repositories (default: all installed repositories), and report problems in "{file}:{line}:{column}: {error|warning}: {message}" format.
+  The following problems are reported:
+    * syntax errors
+    * duplicate or prohibited function names
+    * functions which are not hooks and never called (may be a typo of hook name, e.g. s:on_load())
+    * s:loaded_on() whose return value is not a valid string literal
+    * excmds of s:loaded_on() ("excmd=...") which the plugin does not define in plugin/ directory
+    * s:depends() whose repositories are not installed
+    * deprecated s:config()
+
+  If -fix option was given, volt rewrites plugconf files to fix the following problems:
+    * renames a typo of hook name to the hook name (e.g. s:on_load_pos() -> s:on_load_post())
+    * renames s:config() to s:on_load_pre()
+
+  This command exits with non-zero status if any problems remain.` + "\n\n")
+		fmt.Println("Options")
+		fs.PrintDefaults()
+		fmt.Println()
+		cmd.helped = true
+	}
+	fs.BoolVar(&cmd.fix, "fix", false, "fix problems if possible")
+	return fs
+}
+
+func (cmd *lintCmd) Run(args []string) *Error {
+	reposPathList, err := cmd.parseArgs(args)
+	if err == ErrShowedHelp {
+		return nil
+	}
+	if err != nil {
+		return &Error{Code: 10, Msg: "Failed to parse args: " + err.Error()}
+	}
+
+	count, err := cmd.doLint(reposPathList)
+	if err != nil {
+		return &Error{Code: 11, Msg: "Failed to lint: " + err.Error()}
+	}
+	if count > 0 {
+		return &Error{Code: 12, Msg: fmt.Sprintf("%d problem(s) found", count)}
+	}
+	return nil
+}
+
+func (cmd *lintCmd) parseArgs(args []string) (pathutil.ReposPathList, error) {
+	fs := cmd.FlagSet()
+	fs.Parse(args)
+	if cmd.helped {
+		return nil, ErrShowedHelp
+	}
+
+	var reposPathList pathutil.ReposPathList
+	for _, arg := range fs.Args() {
+		reposPath, err := pathutil.NormalizeRepos(arg)
+		if err != nil {
+			return nil, err
+		}
+		reposPathList = append(reposPathList, reposPath)
+	}
+	return reposPathList, nil
+}
+
+// doLint lints plugconf files of reposPathList (if it is empty, all installed
+// repositories), and returns the number of found problems.
+func (cmd *lintCmd) doLint(reposPathList pathutil.ReposPathList) (int, error) {
+	lockJSON, err := lockjson.Read()
+	if err != nil {
+		return 0, errors.Wrap(err, "could not read lock.json")
+	}
+
+	if len(reposPathList) == 0 {
+		for i := range lockJSON.Repos {
+			if pathutil.Exists(lockJSON.Repos[i].Path.Plugconf()) {
+				reposPathList = append(reposPathList, lockJSON.Repos[i].Path)
+			}
+		}
+	} else {
+		for _, reposPath := range reposPathList {
+			if !pathutil.Exists(reposPath.Plugconf()) {
+				return 0, errors.New("plugconf does not exist: " + reposPath.Plugconf())
+			}
+		}
+	}
+
+	var count int
+	for _, reposPath := range reposPathList {
+		problems, err := plugconf.Lint(reposPath, lockJSON)
+		if err != nil {
+			return 0, err
+		}
+		if cmd.fix {
+			fixed, err := plugconf.FixLintProblems(reposPath, problems)
+			if err != nil {
+				return 0, err
+			}
+			if fixed > 0 {
+				logger.Infof("Fixed %d problem(s) of %s", fixed, reposPath.Plugconf())
+				problems, err = plugconf.Lint(reposPath, lockJSON)
+				if err != nil {
+					return 0, err
+				}
+			}
+		}
+		for i := range problems {
+			if !cmd.fix && problems[i].Fixable() {
+				fmt.Println(problems[i].String() + " (fixable by 'volt lint -fix')")
+			} else {
+				fmt.Println(problems[i].String())
+			}
+		}
+		count += len(problems)
+	}
+	return count, nil
+}
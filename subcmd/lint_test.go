@@ -0,0 +1,152 @@
+package subcmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vim-volt/volt/internal/testutil"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// Checks:
+// (A) Does not show `[ERROR]`, `[WARN]` messages
+// (B) Exit with zero status
+// (C) Shows the expected problems with positions
+// (D) Fixes plugconf
+//
+// * Run `volt lint` (plugconf: valid) (A, B, !C, !D)
+// * Run `volt lint <repos>` (plugconf: valid) (A, B, !C, !D)
+// * Run `volt lint` (plugconf: has problems) (!A, !B, C, !D)
+// * Run `volt lint` (plugconf: has excmd which the plugin does not define) (!A, !B, C, !D)
+// * Run `volt lint -fix` (plugconf: has fixable problems only) (A, B, !C, D)
+// * Run `volt lint -fix` (plugconf: has fixable and unfixable problems) (!A, !B, C, D)
+// * Run `volt lint <repos>` (<repos>: has no plugconf) (!A, !B, !C, !D)
+func TestVoltLint(t *testing.T) {
+	const validPlugconf = `function! s:on_load_pre()
+  call s:helper()
+endfunction
+
+function! s:helper()
+endfunction
+
+function! s:loaded_on()
+  return 'excmd=Hello'
+endfunction
+
+function! s:depends()
+  return ['localhost/local/hello']
+endfunction
+`
+	const fixablePlugconf = `function! s:config()
+endfunction
+
+function! s:on_load_pos()
+endfunction
+`
+	const fixedPlugconf = `function! s:on_load_pre()
+endfunction
+
+function! s:on_load_post()
+endfunction
+`
+	const problemPlugconf = `function! s:on_load_pos()
+endfunction
+
+function! s:loaded_on()
+  return 'excmd=' . 'Hello'
+endfunction
+
+function! s:depends()
+  return ['localhost/local/not_installed']
+endfunction
+
+function! s:loaded_on()
+  return 'excmd=Hello,NotDefined'
+endfunction
+`
+	for _, tt := range []struct {
+		name     string
+		args     []string
+		plugconf string
+		success  bool
+		problems []string
+		fixed    string
+	}{
+		{"valid", []string{}, validPlugconf, true, nil, ""},
+		{"valid with repos", []string{"localhost/local/hello"}, validPlugconf, true, nil, ""},
+		{"problems", []string{}, problemPlugconf, false, []string{
+			"hello.vim:1:11: warning: s:on_load_pos() is not a hook of plugconf, and never called. did you mean s:on_load_post()?",
+			"hello.vim:5:3: error: the return value of s:loaded_on() must be a string literal",
+			"hello.vim:9:11: error: repository 'localhost/local/not_installed' is not installed",
+			"hello.vim:12:11: error: duplicate s:loaded_on()",
+		}, ""},
+		{"excmd not defined", []string{}, "function! s:loaded_on()\n  return 'excmd=Hello,NotDefined'\nendfunction\n", false, []string{
+			"hello.vim:2:10: warning: excmd 'NotDefined' is not defined in plugin/ directory of localhost/local/hello",
+		}, ""},
+		{"fixable problems", []string{"-fix"}, fixablePlugconf, true, nil, fixedPlugconf},
+		{"fixable and unfixable problems", []string{"-fix"}, fixablePlugconf + "\nfunction! s:depends()\n  return ['localhost/local/not_installed']\nendfunction\n", false, []string{
+			"hello.vim:8:11: error: repository 'localhost/local/not_installed' is not installed",
+		}, fixedPlugconf + "\nfunction! s:depends()\n  return ['localhost/local/not_installed']\nendfunction\n"},
+		{"no plugconf", []string{"localhost/local/not_installed"}, validPlugconf, false, nil, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			// =============== setup =============== //
+
+			testutil.SetUpEnv(t)
+			defer testutil.CleanUpEnv(t)
+			reposPath := pathutil.ReposPath("localhost/local/hello")
+			teardown := testutil.SetUpRepos(t, "hello", lockjson.ReposStaticType, []pathutil.ReposPath{reposPath}, "symlink")
+			defer teardown()
+
+			plugconf := reposPath.Plugconf()
+			os.MkdirAll(filepath.Dir(plugconf), 0777)
+			// Not the default mode, to check it is kept by -fix
+			if err := ioutil.WriteFile(plugconf, []byte(tt.plugconf), 0600); err != nil {
+				t.Fatal("failed to write plugconf: " + err.Error())
+			}
+
+			// =============== run =============== //
+
+			out, err := testutil.RunVolt(append([]string{"lint"}, tt.args...)...)
+			if tt.success {
+				// (A, B)
+				testutil.SuccessExit(t, out, err)
+			} else {
+				// (!A, !B)
+				testutil.FailExit(t, out, err)
+			}
+
+			// (C)
+			for _, problem := range tt.problems {
+				if !bytes.Contains(out, []byte(problem)) {
+					t.Errorf("expected output contains %q but got: %s", problem, string(out))
+				}
+			}
+			if n := bytes.Count(out, []byte("hello.vim:")); n != len(tt.problems) {
+				t.Errorf("expected %d problem(s) but got %d: %s", len(tt.problems), n, string(out))
+			}
+
+			// (D)
+			content, err := ioutil.ReadFile(plugconf)
+			if err != nil {
+				t.Fatal("failed to read plugconf: " + err.Error())
+			}
+			expected := tt.plugconf
+			if tt.fixed != "" {
+				expected = tt.fixed
+			}
+			if string(content) != expected {
+				t.Errorf("expected plugconf is %q but got %q", expected, string(content))
+			}
+			if fi, err := os.Stat(plugconf); err != nil {
+				t.Error("failed to stat plugconf: " + err.Error())
+			} else if fi.Mode().Perm() != 0600 {
+				t.Errorf("expected plugconf mode is 0600 but got %v", fi.Mode().Perm())
+			}
+		})
+	}
+}
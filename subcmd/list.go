@@ -37,6 +37,10 @@ Quick example
 
   $ volt list -f '{{ range .Repos }}{{ println .Path }}{{ end }}'
 
+  Show system repositories and their locked revisions:
+
+  $ volt list -f '{{ range .Repos }}{{ if eq .Type "system" }}{{ println .Path .Version }}{{ end }}{{ end }}'
+
   Show repositories used by current profile:
 
   $ volt list -f '{{ range .Profiles }}{{ if eq $.CurrentProfileName .Name }}{{ range .ReposPath }}{{ println . }}{{ end }}{{ end }}{{ end }}'
@@ -54,9 +58,13 @@ Template functions
   currentProfile (Profile (see "Structures"))
     Returns current profile
 
-  currentProfile (Profile (see "Structures"))
+  profile {name} (Profile (see "Structures"))
     Returns given name's profile
 
+  inheritedReposPath {name} ([]{Path, Profile})
+    Returns repositories which given name's profile inherits from the profiles it extends.
+    "Profile" is the name of the profile which has the repository.
+
   version (string)
     Returns volt version string. format is "v{major}.{minor}.{patch}" (e.g. "v0.3.0")
 
@@ -82,13 +90,13 @@ Structures
     // ("volt list" shows current profile's repositories, which is not the same as this)
     "repos": [
       {
-        // "git" (git repository) or "static" (static repository)
+        // "git" (git repository), "static" (static repository), or "system" (system repository)
         "type": <string>,
 
         // Repository path like "github.com/vim-volt/vim-volt"
         "path": <string>,
 
-        // Git commit hash. if "type" is "static" this property does not exist
+        // Git commit hash. if "type" is "static", or "system" and it is not a git repository, this property does not exist
         "version": <string>,
       },
     ],
@@ -98,6 +106,9 @@ Structures
       // Profile name (.e.g. "default")
       "name": <string>,
 
+      // Profile names which this profile inherits repositories and rc files from (optional)
+      "extends": [ <string> ],
+
       // Repositories ("volt list" shows these repositories)
       "repos_path": [ <string> ],
     ]
@@ -117,11 +128,32 @@ Description
 }
 
 func (*listCmd) defaultTemplate() string {
-	return `name: {{ .CurrentProfileName }}
+	return profileTemplate("currentProfile")
+}
+
+// profileTemplate returns the template string which shows the profile
+// returned by profileExpr (e.g. "currentProfile", "profile \"default\"").
+func profileTemplate(profileExpr string) string {
+	return `
+{{- with ` + profileExpr + ` -}}
+name: {{ .Name }}
+{{- if .Extends }}
+extends:
+{{- range .Extends }}
+  {{ . }}
+{{- end }}
+{{- end }}
 repos path:
-{{- range currentProfile.ReposPath }}
+{{- range .ReposPath }}
   {{ . }}
 {{- end }}
+{{- with inheritedReposPath .Name }}
+inherited repos path:
+{{- range . }}
+  {{ .Path }} (from {{ .Profile }})
+{{- end }}
+{{- end }}
+{{- end }}
 `
 }
 
@@ -178,6 +210,13 @@ func (*listCmd) funcMap(lockJSON *lockjson.LockJSON) template.FuncMap {
 			return profileOf(lockJSON.CurrentProfileName)
 		},
 		"profile": profileOf,
+		"inheritedReposPath": func(name string) []lockjson.InheritedReposPath {
+			inherited, err := lockJSON.GetInheritedReposPath(profileOf(name))
+			if err != nil {
+				return nil
+			}
+			return inherited
+		},
 		"version": func() string {
 			return voltVersion
 		},
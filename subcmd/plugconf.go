@@ -0,0 +1,489 @@
+package subcmd
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sergi/go-diff/diffmatchpatch"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/fileutil"
+	"github.com/vim-volt/volt/gitutil"
+	"github.com/vim-volt/volt/internal/builder"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/plugconf"
+	"github.com/vim-volt/volt/transaction"
+)
+
+type plugconfCmd struct {
+	helped    bool
+	templates *plugconf.TemplateFetcher
+}
+
+// plugconfSubCmd is a map from the name of plugconf subcommand to the function
+// which runs it.
+var plugconfSubCmd = make(map[string]func(*plugconfCmd, []string) error)
+
+func init() {
+	cmdMap["plugconf"] = &plugconfCmd{}
+	plugconfSubCmd["diff"] = (*plugconfCmd).doDiff
+	plugconfSubCmd["reset"] = (*plugconfCmd).doReset
+	plugconfSubCmd["update"] = (*plugconfCmd).doUpdate
+}
+
+func (cmd *plugconfCmd) ProhibitRootExecution(args []string) bool { return true }
+
+func (cmd *plugconfCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  plugconf [-help] {command}
+
+Command
+  plugconf diff {repository} [{repository2} ...]
+    Show differences between plugconf file of {repository} and the one
+    generated from the latest plugconf template.
+
+  plugconf reset {repository} [{repository2} ...]
+    Overwrite plugconf file of {repository} with the one generated from the
+    latest plugconf template. Local changes are discarded.
+
+  plugconf update [{repository} ...]
+    Merge changes of plugconf templates into plugconf files of {repository}
+    (or all repositories in lock.json if no {repository} is given).
+    See "Three-way merge" section for details.
+
+Quick example
+  $ volt plugconf diff tyru/caw.vim     # show what is changed in the template
+  --- .../plugconf/github.com/tyru/caw.vim.vim
+  +++ https://github.com/vim-volt/plugconf-templates@0123456 (template)
+  ...
+  $ volt plugconf update                # merge template changes of all plugconf files
+  * github.com/tyru/caw.vim > updated plugconf (https://github.com/vim-volt/plugconf-templates@0123456..89abcde)
+  ! github.com/tyru/open-browser.vim > conflicted: s:loaded_on()
+  $ volt plugconf reset tyru/caw.vim    # discard local changes
+
+Three-way merge
+  "volt plugconf update" merges each function (s:on_load_pre(),
+  s:on_load_post(), s:loaded_on(), s:depends(), and other functions) of
+  plugconf file with the template recorded in lock.json when the plugconf
+  was installed (base) and the latest template:
+
+  * If the function was not changed locally, the latest template is taken.
+  * If the function was not changed in the template, local one is kept.
+  * If the function was changed in both, local one is kept, and it is
+    reported as a conflict. Resolve it by "volt edit {repository}", or
+    discard local changes by "volt plugconf reset {repository}".
+
+  If there is no conflict, the latest template is recorded in lock.json.
+  If no template was recorded, the skeleton plugconf is used as the base.` + "\n\n")
+		cmd.helped = true
+	}
+	return fs
+}
+
+func (cmd *plugconfCmd) Run(args []string) *Error {
+	// Parse args
+	args, err := cmd.parseArgs(args)
+	if err == ErrShowedHelp {
+		return nil
+	}
+	if err != nil {
+		return &Error{Code: 10, Msg: err.Error()}
+	}
+
+	subCmd := args[0]
+	fn, exists := plugconfSubCmd[subCmd]
+	if !exists {
+		return &Error{Code: 11, Msg: "Unknown subcommand: " + subCmd}
+	}
+	err = fn(cmd, args[1:])
+	if err != nil {
+		return &Error{Code: 20, Msg: err.Error()}
+	}
+
+	return nil
+}
+
+func (cmd *plugconfCmd) parseArgs(args []string) ([]string, error) {
+	fs := cmd.FlagSet()
+	fs.Parse(args)
+	if cmd.helped {
+		return nil, ErrShowedHelp
+	}
+	if len(fs.Args()) == 0 {
+		fs.Usage()
+		logger.Error("must specify subcommand")
+		return nil, ErrShowedHelp
+	}
+	return fs.Args(), nil
+}
+
+// setUp reads config.toml and lock.json, and prepares template fetcher.
+func (cmd *plugconfCmd) setUp() (*lockjson.LockJSON, error) {
+	cfg, err := config.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read config.toml")
+	}
+	templates, err := newTemplateFetcher(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cmd.templates = templates
+	lockJSON, err := lockjson.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read lock.json")
+	}
+	return lockJSON, nil
+}
+
+// newTemplateFetcher returns the fetcher of plugconf.template_sources.
+func newTemplateFetcher(cfg *config.Config) (*plugconf.TemplateFetcher, error) {
+	git, err := gitutil.NewBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return plugconf.NewTemplateFetcher(cfg.Plugconf.TemplateSources, git), nil
+}
+
+func (*plugconfCmd) normalizeReposList(args []string) (pathutil.ReposPathList, error) {
+	reposPathList := make(pathutil.ReposPathList, 0, len(args))
+	for _, arg := range args {
+		reposPath, err := pathutil.NormalizeRepos(arg)
+		if err != nil {
+			return nil, err
+		}
+		reposPathList = append(reposPathList, reposPath)
+	}
+	return reposPathList, nil
+}
+
+// generateLatest generates plugconf from the latest template of reposPath.
+// If no template was found, it generates skeleton plugconf and returns nil
+// source.
+func (cmd *plugconfCmd) generateLatest(reposPath pathutil.ReposPath) ([]byte, *lockjson.PlugconfTemplate, error) {
+	tmpl, source, err := cmd.templates.Fetch(reposPath, nil)
+	if err != nil {
+		logger.Debug(err.Error())
+	}
+	content, merr := tmpl.Generate(reposPath.Plugconf())
+	if merr.ErrorOrNil() != nil {
+		return nil, nil, errors.Errorf("parse error in fetched plugconf %s: %s", reposPath, merr.Error())
+	}
+	return content, source, nil
+}
+
+func (cmd *plugconfCmd) doDiff(args []string) error {
+	if len(args) == 0 {
+		cmd.FlagSet().Usage()
+		logger.Error("'volt plugconf diff' receives one or more repositories.")
+		return nil
+	}
+	reposPathList, err := cmd.normalizeReposList(args)
+	if err != nil {
+		return err
+	}
+	if _, err := cmd.setUp(); err != nil {
+		return err
+	}
+
+	for _, reposPath := range reposPathList {
+		path := reposPath.Plugconf()
+		local, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errors.Errorf("plugconf of %s does not exist", reposPath)
+		}
+		latest, source, err := cmd.generateLatest(reposPath)
+		if err != nil {
+			return err
+		}
+		fmt.Print(unifiedDiff(path, formatTemplateSource(source)+" (template)", string(local), string(latest)))
+	}
+	return nil
+}
+
+func (cmd *plugconfCmd) doReset(args []string) (err error) {
+	if len(args) == 0 {
+		cmd.FlagSet().Usage()
+		logger.Error("'volt plugconf reset' receives one or more repositories.")
+		return nil
+	}
+	reposPathList, err := cmd.normalizeReposList(args)
+	if err != nil {
+		return err
+	}
+
+	// Begin transaction
+	trx, err := transaction.Start()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if e := trx.Done(); e != nil {
+			err = e
+		}
+	}()
+
+	lockJSON, err := cmd.setUp()
+	if err != nil {
+		return err
+	}
+
+	for _, reposPath := range reposPathList {
+		content, source, err := cmd.generateLatest(reposPath)
+		if err != nil {
+			return err
+		}
+		path := reposPath.Plugconf()
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := fileutil.WriteFileAtomic(path, content, 0644); err != nil {
+			return err
+		}
+		if repos := lockJSON.Repos.FindByPath(reposPath); repos != nil {
+			repos.PlugconfTemplate = source
+		}
+		fmt.Printf("* %s > reset plugconf (%s)\n", reposPath, formatTemplateSource(source))
+	}
+
+	if err = lockJSON.Write(); err != nil {
+		return errors.Wrap(err, "could not write to lock.json")
+	}
+	if err = builder.Build(false); err != nil {
+		return errors.Wrap(err, "could not build "+pathutil.VimVoltDir())
+	}
+	return nil
+}
+
+func (cmd *plugconfCmd) doUpdate(args []string) (err error) {
+	reposPathList, err := cmd.normalizeReposList(args)
+	if err != nil {
+		return err
+	}
+
+	// Begin transaction
+	trx, err := transaction.Start()
+	if err != nil {
+		return
+	}
+	defer func() {
+		if e := trx.Done(); e != nil {
+			err = e
+		}
+	}()
+
+	lockJSON, err := cmd.setUp()
+	if err != nil {
+		return err
+	}
+
+	// Update all plugconf files if no repository was given
+	if len(reposPathList) == 0 {
+		for i := range lockJSON.Repos {
+			if pathutil.Exists(lockJSON.Repos[i].Path.Plugconf()) {
+				reposPathList = append(reposPathList, lockJSON.Repos[i].Path)
+			}
+		}
+	}
+
+	var updated, failed bool
+	for _, reposPath := range reposPathList {
+		repos := lockJSON.Repos.FindByPath(reposPath)
+		if repos == nil {
+			fmt.Printf("! %s > not found in lock.json\n", reposPath)
+			failed = true
+			continue
+		}
+		source, conflicts, err := cmd.updatePlugconf(repos)
+		switch {
+		case err != nil:
+			fmt.Printf("! %s > update failed\n  * %s\n", reposPath, err.Error())
+			failed = true
+		case source == nil:
+			fmt.Printf("# %s > no change\n", reposPath)
+		case len(conflicts) > 0:
+			fmt.Printf("! %s > conflicted: %s()\n", reposPath, strings.Join(conflicts, "(), "))
+			updated = true
+			failed = true
+		default:
+			fmt.Printf("* %s > updated plugconf (%s..%s)\n",
+				reposPath, formatTemplateSource(repos.PlugconfTemplate), formatTemplateSource(source))
+			repos.PlugconfTemplate = source
+			updated = true
+		}
+	}
+
+	if updated {
+		if err = lockJSON.Write(); err != nil {
+			return errors.Wrap(err, "could not write to lock.json")
+		}
+		if err = builder.Build(false); err != nil {
+			return errors.Wrap(err, "could not build "+pathutil.VimVoltDir())
+		}
+	}
+	if failed {
+		return errors.New("failed to update some plugconf files")
+	}
+	return nil
+}
+
+// updatePlugconf merges changes of the template into plugconf file of repos.
+// It returns nil source if the template was not changed.
+func (cmd *plugconfCmd) updatePlugconf(repos *lockjson.Repos) (*lockjson.PlugconfTemplate, []string, error) {
+	path := repos.Path.Plugconf()
+	latest, source, err := cmd.templates.Fetch(repos.Path, nil)
+	if err != nil {
+		logger.Debug(err.Error())
+		return nil, nil, nil
+	}
+	recorded := repos.PlugconfTemplate
+	if recorded != nil && *recorded == *source {
+		return nil, nil, nil
+	}
+
+	var base *plugconf.Template
+	if recorded != nil {
+		base, err = cmd.templates.FetchRecorded(repos.Path, recorded)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "could not fetch recorded template (%s)", formatTemplateSource(recorded))
+		}
+	}
+	baseInfo, merr := base.Parse(path)
+	if merr.ErrorOrNil() != nil {
+		return nil, nil, merr
+	}
+	theirInfo, merr := latest.Parse(path)
+	if merr.ErrorOrNil() != nil {
+		return nil, nil, merr
+	}
+	localInfo, parseErr := plugconf.ParsePlugconfFile(path, 0, repos.Path)
+	if parseErr.HasErrs() {
+		return nil, nil, parseErr.ErrorsAndWarns()
+	}
+	if localInfo == nil {
+		return nil, nil, errors.New("could not read " + path)
+	}
+
+	content, conflicts, err := plugconf.Merge(baseInfo, localInfo, theirInfo)
+	if err != nil {
+		return nil, nil, err
+	}
+	if local, err := ioutil.ReadFile(path); err == nil && bytes.Equal(local, content) {
+		return source, conflicts, nil
+	}
+	if err := fileutil.WriteFileAtomic(path, content, 0644); err != nil {
+		return nil, nil, err
+	}
+	return source, conflicts, nil
+}
+
+// formatTemplateSource returns "{source}@{short version}".
+func formatTemplateSource(source *lockjson.PlugconfTemplate) string {
+	if source == nil {
+		return "skeleton"
+	}
+	if source.Version == "" {
+		return source.Source
+	}
+	version := source.Version
+	if len(version) > 7 {
+		version = version[:7]
+	}
+	return source.Source + "@" + version
+}
+
+// diffContextLines is the number of unchanged lines around changes.
+const diffContextLines = 3
+
+// unifiedDiff returns the differences between from and to in unified format.
+// It returns empty string if there is no difference.
+func unifiedDiff(fromName, toName, from, to string) string {
+	type diffLine struct {
+		op   byte
+		text string
+	}
+
+	dmp := diffmatchpatch.New()
+	fromChars, toChars, lineArray := dmp.DiffLinesToChars(from, to)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMain(fromChars, toChars, false), lineArray)
+	var lines []diffLine
+	changed := false
+	for _, d := range diffs {
+		op := byte(' ')
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			op = '-'
+			changed = true
+		case diffmatchpatch.DiffInsert:
+			op = '+'
+			changed = true
+		}
+		for _, text := range strings.SplitAfter(d.Text, "\n") {
+			if text != "" {
+				lines = append(lines, diffLine{op, strings.TrimSuffix(text, "\n")})
+			}
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "--- %s\n+++ %s\n", fromName, toName)
+	fromLine, toLine := 1, 1
+	for i := 0; i < len(lines); {
+		// Skip unchanged lines
+		for ; i < len(lines) && lines[i].op == ' '; i++ {
+			fromLine++
+			toLine++
+		}
+		if i == len(lines) {
+			break
+		}
+		// Find the end of the hunk: changes separated by a few lines are
+		// shown in one hunk
+		start := i - diffContextLines
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for j := i; j < len(lines) && j-end <= 2*diffContextLines; j++ {
+			if lines[j].op != ' ' {
+				end = j
+			}
+		}
+		stop := end + diffContextLines + 1
+		if stop > len(lines) {
+			stop = len(lines)
+		}
+
+		fromStart, toStart := fromLine-(i-start), toLine-(i-start)
+		var fromCount, toCount int
+		for _, l := range lines[start:stop] {
+			if l.op != '+' {
+				fromCount++
+			}
+			if l.op != '-' {
+				toCount++
+			}
+		}
+		fmt.Fprintf(&buf, "@@ -%d,%d +%d,%d @@\n", fromStart, fromCount, toStart, toCount)
+		for _, l := range lines[start:stop] {
+			buf.WriteByte(l.op)
+			buf.WriteString(l.text)
+			buf.WriteByte('\n')
+		}
+		fromLine, toLine = fromStart+fromCount, toStart+toCount
+		i = stop
+	}
+	return buf.String()
+}
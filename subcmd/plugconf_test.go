@@ -0,0 +1,193 @@
+package subcmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/internal/testutil"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// Checks:
+// (A) Does not show `[ERROR]`, `[WARN]` messages
+// (B) Exit with zero status
+// (C) Plugconf file is not changed
+// (D) Plugconf file is changed to the expected content
+// (E) The latest template is recorded to lock.json
+// (F) lock.json is not changed
+
+// * Run `volt plugconf diff {repos}` (A, B, C)
+//   - Shows nothing if the template was not changed
+//   - Shows the differences if the template was changed
+func TestVoltPlugconfDiff(t *testing.T) {
+	// =============== setup =============== //
+
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	reposPath, tmplDir := setUpPlugconfTemplate(t)
+	before := readPlugconf(t, reposPath)
+
+	// =============== run =============== //
+
+	out, err := testutil.RunVolt("plugconf", "diff", reposPath.String())
+	// (A, B)
+	testutil.SuccessExit(t, out, err)
+	if len(out) != 0 {
+		t.Errorf("expected no differences but got:\n%s", string(out))
+	}
+
+	writePlugconfTemplate(t, tmplDir, reposPath, "excmd=Hello")
+	out, err = testutil.RunVolt("plugconf", "diff", reposPath.String())
+	// (A, B)
+	testutil.SuccessExit(t, out, err)
+	for _, line := range []string{"-  return 'filetype=vim'\n", "+  return 'excmd=Hello'\n"} {
+		if !bytes.Contains(out, []byte(line)) {
+			t.Errorf("expected %q in the output but got:\n%s", line, string(out))
+		}
+	}
+
+	// (C)
+	if after := readPlugconf(t, reposPath); after != before {
+		t.Errorf("plugconf was changed:\n%s", after)
+	}
+}
+
+// * Run `volt plugconf reset {repos}` (A, B, D, E)
+func TestVoltPlugconfReset(t *testing.T) {
+	// =============== setup =============== //
+
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	reposPath, tmplDir := setUpPlugconfTemplate(t)
+	editPlugconf(t, reposPath, "function! s:on_load_pre()\n", "function! s:on_load_pre()\n  let g:foo = 1\n")
+	writePlugconfTemplate(t, tmplDir, reposPath, "excmd=Hello")
+	before := recordedTemplate(t, reposPath)
+
+	// =============== run =============== //
+
+	out, err := testutil.RunVolt("plugconf", "reset", reposPath.String())
+	// (A, B)
+	testutil.SuccessExit(t, out, err)
+
+	// (D)
+	plugconf := readPlugconf(t, reposPath)
+	if strings.Contains(plugconf, "g:foo") || !strings.Contains(plugconf, "excmd=Hello") {
+		t.Errorf("plugconf was not reset:\n%s", plugconf)
+	}
+	// (E)
+	if after := recordedTemplate(t, reposPath); after.Version == before.Version {
+		t.Errorf("expected recorded template was updated but got %+v", after)
+	}
+}
+
+// * Run `volt plugconf update` (A, B, D, E)
+//   - Changes of the template are merged into local changes
+//
+// * Run `volt plugconf update {repos}` (!A, !B, C, F)
+//   - A function was changed in both plugconf and template
+func TestVoltPlugconfUpdate(t *testing.T) {
+	// =============== setup =============== //
+
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	reposPath, tmplDir := setUpPlugconfTemplate(t)
+	editPlugconf(t, reposPath, "function! s:on_load_pre()\n", "function! s:on_load_pre()\n  let g:foo = 1\n")
+	writePlugconfTemplate(t, tmplDir, reposPath, "excmd=Hello")
+	before := recordedTemplate(t, reposPath)
+
+	// =============== run =============== //
+
+	out, err := testutil.RunVolt("plugconf", "update")
+	// (A, B)
+	testutil.SuccessExit(t, out, err)
+
+	// (D)
+	plugconf := readPlugconf(t, reposPath)
+	if !strings.Contains(plugconf, "let g:foo = 1") || !strings.Contains(plugconf, "excmd=Hello") {
+		t.Errorf("template was not merged:\n%s", plugconf)
+	}
+	// (E)
+	updated := recordedTemplate(t, reposPath)
+	if updated.Version == before.Version {
+		t.Errorf("expected recorded template was updated but got %+v", updated)
+	}
+
+	// =============== setup =============== //
+
+	editPlugconf(t, reposPath, "excmd=Hello", "filetype=python")
+	writePlugconfTemplate(t, tmplDir, reposPath, "filetype=go")
+	plugconf = readPlugconf(t, reposPath)
+
+	// =============== run =============== //
+
+	out, err = testutil.RunVolt("plugconf", "update", reposPath.String())
+	// (!A, !B)
+	testutil.FailExit(t, out, err)
+	if !bytes.Contains(out, []byte("conflicted: s:loaded_on()")) {
+		t.Errorf("expected conflict of s:loaded_on() but got:\n%s", string(out))
+	}
+
+	// (C)
+	if after := readPlugconf(t, reposPath); after != plugconf {
+		t.Errorf("plugconf was changed:\n%s", after)
+	}
+	// (F)
+	if after := recordedTemplate(t, reposPath); *after != *updated {
+		t.Errorf("expected recorded template %+v but got %+v", updated, after)
+	}
+}
+
+// setUpPlugconfTemplate installs a system repository with the plugconf
+// created from a template, and returns the repository and the template
+// source directory.
+func setUpPlugconfTemplate(t *testing.T) (pathutil.ReposPath, string) {
+	t.Helper()
+	reposPath := pathutil.ReposPath("localhost/system/hello")
+	setUpSystemRepos(t, reposPath, config.SymlinkBuilder)
+	tmplDir := filepath.Join(os.Getenv("HOME"), "templates", "team")
+	writePlugconfTemplate(t, tmplDir, reposPath, "filetype=vim")
+	setUpTemplateSources(t, tmplDir)
+	out, err := testutil.RunVolt("get", reposPath.String())
+	testutil.SuccessExit(t, out, err)
+	return reposPath, tmplDir
+}
+
+func readPlugconf(t *testing.T, reposPath pathutil.ReposPath) string {
+	t.Helper()
+	content, err := ioutil.ReadFile(reposPath.Plugconf())
+	if err != nil {
+		t.Fatal("failed to read plugconf: " + err.Error())
+	}
+	return string(content)
+}
+
+func editPlugconf(t *testing.T, reposPath pathutil.ReposPath, old, new string) {
+	t.Helper()
+	content := readPlugconf(t, reposPath)
+	if !strings.Contains(content, old) {
+		t.Fatalf("%q was not found in plugconf:\n%s", old, content)
+	}
+	content = strings.Replace(content, old, new, 1)
+	if err := ioutil.WriteFile(reposPath.Plugconf(), []byte(content), 0644); err != nil {
+		t.Fatal("failed to write plugconf: " + err.Error())
+	}
+}
+
+func recordedTemplate(t *testing.T, reposPath pathutil.ReposPath) *lockjson.PlugconfTemplate {
+	t.Helper()
+	lockJSON, err := lockjson.Read()
+	if err != nil {
+		t.Fatal("lockjson.Read() returned non-nil error: " + err.Error())
+	}
+	repos := lockJSON.Repos.FindByPath(reposPath)
+	if repos == nil || repos.PlugconfTemplate == nil {
+		t.Fatalf("plugconf template of %s was not recorded: %+v", reposPath, repos)
+	}
+	return repos.PlugconfTemplate
+}
@@ -1,17 +1,20 @@
 package subcmd
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/pkg/errors"
 
 	"github.com/hashicorp/go-multierror"
+	"github.com/vim-volt/volt/internal/builder"
 	"github.com/vim-volt/volt/lockjson"
 	"github.com/vim-volt/volt/logger"
 	"github.com/vim-volt/volt/pathutil"
-	"github.com/vim-volt/volt/subcmd/builder"
+	"github.com/vim-volt/volt/pkg/volt"
 	"github.com/vim-volt/volt/transaction"
 )
 
@@ -19,10 +22,22 @@ type profileCmd struct {
 	helped bool
 }
 
-var profileSubCmd = make(map[string]func([]string) error)
+// profileSubCmd is a map from the name of profile subcommand to the function
+// which runs it.
+var profileSubCmd = make(map[string]func(*profileCmd, []string) error)
 
 func init() {
 	cmdMap["profile"] = &profileCmd{}
+	profileSubCmd["set"] = (*profileCmd).doSet
+	profileSubCmd["show"] = (*profileCmd).doShow
+	profileSubCmd["list"] = (*profileCmd).doList
+	profileSubCmd["new"] = (*profileCmd).doNew
+	profileSubCmd["destroy"] = (*profileCmd).doDestroy
+	profileSubCmd["rename"] = (*profileCmd).doRename
+	profileSubCmd["add"] = (*profileCmd).doAdd
+	profileSubCmd["rm"] = (*profileCmd).doRm
+	profileSubCmd["extend"] = (*profileCmd).doExtend
+	profileSubCmd["unextend"] = (*profileCmd).doUnextend
 }
 
 func (cmd *profileCmd) ProhibitRootExecution(args []string) bool {
@@ -74,6 +89,12 @@ Command
   profile rm [-current | {name}] {repository} [{repository2} ...]
     Remove one or more repositories from profile {name}.
 
+  profile extend [-current | {name}] {profile} [{profile2} ...]
+    Make profile {name} inherit repositories and rc files from {profile}.
+
+  profile unextend [-current | {name}] {profile} [{profile2} ...]
+    Make profile {name} stop inheriting from {profile}.
+
 Quick example
   $ volt profile list   # default profile is "default"
   * default
@@ -94,7 +115,28 @@ Quick example
   $ volt disable tyru/caw.vim   # disable loading tyru/caw.vim on current profile
   $ volt profile rm foo tyru/caw.vim    # disable loading tyru/caw.vim on "foo" profile
 
-  $ volt profile destroy foo   # will delete profile "foo"` + "\n\n")
+  $ volt profile destroy foo   # will delete profile "foo"
+
+Profile inheritance
+  A profile can extend other profiles. The profile inherits all repositories
+  of the profiles it extends, in addition to its own repositories.
+  The rc files ($VOLTPATH/rc/{profile}/vimrc.vim and gvimrc.vim) are also
+  concatenated in inheritance order (the profile extended by others comes
+  first) when they are installed to ~/.vim/vimrc and ~/.vim/gvimrc.
+
+  $ volt profile new base
+  $ volt profile add base tyru/caw.vim
+  $ volt profile new go
+  $ volt profile extend go base    # "go" profile also loads tyru/caw.vim
+  $ volt profile add go fatih/vim-go
+  $ volt profile show go
+  name: go
+  extends:
+    base
+  repos path:
+    github.com/fatih/vim-go
+  inherited repos path:
+    github.com/tyru/caw.vim (from base)` + "\n\n")
 		cmd.helped = true
 	}
 	return fs
@@ -111,27 +153,11 @@ func (cmd *profileCmd) Run(args []string) *Error {
 	}
 
 	subCmd := args[0]
-	switch subCmd {
-	case "set":
-		err = cmd.doSet(args[1:])
-	case "show":
-		err = cmd.doShow(args[1:])
-	case "list":
-		err = cmd.doList(args[1:])
-	case "new":
-		err = cmd.doNew(args[1:])
-	case "destroy":
-		err = cmd.doDestroy(args[1:])
-	case "rename":
-		err = cmd.doRename(args[1:])
-	case "add":
-		err = cmd.doAdd(args[1:])
-	case "rm":
-		err = cmd.doRm(args[1:])
-	default:
+	fn, exists := profileSubCmd[subCmd]
+	if !exists {
 		return &Error{Code: 11, Msg: "Unknown subcommand: " + subCmd}
 	}
-
+	err = fn(cmd, args[1:])
 	if err != nil {
 		return &Error{Code: 20, Msg: err.Error()}
 	}
@@ -161,7 +187,7 @@ func (*profileCmd) getCurrentProfile() (string, error) {
 	return lockJSON.CurrentProfileName, nil
 }
 
-func (cmd *profileCmd) doSet(args []string) (err error) {
+func (cmd *profileCmd) doSet(args []string) error {
 	// Parse args
 	createProfile := false
 	if len(args) > 0 && args[0] == "-n" {
@@ -171,72 +197,16 @@ func (cmd *profileCmd) doSet(args []string) (err error) {
 	if len(args) == 0 {
 		cmd.FlagSet().Usage()
 		logger.Error("'volt profile set' receives profile name.")
-		return
+		return nil
 	}
 	profileName := args[0]
 
-	// Read lock.json
-	lockJSON, err := lockjson.Read()
-	if err != nil {
-		err = errors.Wrap(err, "failed to read lock.json")
-		return
-	}
-
-	// Exit if current profile is same as profileName
-	if lockJSON.CurrentProfileName == profileName {
-		err = errors.Errorf("'%s' is current profile", profileName)
-		return
-	}
-
-	// Create given profile unless the profile exists
-	if _, err = lockJSON.Profiles.FindByName(profileName); err != nil {
-		if !createProfile {
-			return
-		}
-		if err = cmd.doNew([]string{profileName}); err != nil {
-			return
-		}
-		// Read lock.json again
-		lockJSON, err = lockjson.Read()
-		if err != nil {
-			err = errors.Wrap(err, "failed to read lock.json")
-			return
-		}
-		if _, err = lockJSON.Profiles.FindByName(profileName); err != nil {
-			return
-		}
-	}
-
-	// Begin transaction
-	trx, err := transaction.Start()
-	if err != nil {
-		return
-	}
-	defer func() {
-		if e := trx.Done(); e != nil {
-			err = e
-		}
-	}()
-
-	// Set profile name
-	lockJSON.CurrentProfileName = profileName
-
-	// Write to lock.json
-	err = lockJSON.Write()
-	if err != nil {
-		return
-	}
-
-	logger.Info("Changed current profile: " + profileName)
-
-	// Build ~/.vim/pack/volt dir
-	err = builder.Build(false)
-	if err != nil {
-		err = errors.Wrap(err, "could not build "+pathutil.VimVoltDir())
-		return
-	}
-
-	return
+	_, err := volt.SetProfile(context.Background(), volt.SetProfileOptions{
+		Name:   profileName,
+		Create: createProfile,
+		Logger: logger.Default(),
+	})
+	return err
 }
 
 func (cmd *profileCmd) doShow(args []string) error {
@@ -262,14 +232,7 @@ func (cmd *profileCmd) doShow(args []string) error {
 		}
 	}
 
-	return (&listCmd{}).list(fmt.Sprintf(`name: %s
-repos path:
-{{- with profile %q -}}
-{{- range .ReposPath }}
-  {{ . }}
-{{- end -}}
-{{- end }}
-`, profileName, profileName))
+	return (&listCmd{}).list(profileTemplate(fmt.Sprintf("profile %q", profileName)))
 }
 
 func (cmd *profileCmd) doList(args []string) error {
@@ -370,6 +333,13 @@ func (cmd *profileCmd) doDestroy(args []string) (err error) {
 			merr = multierror.Append(merr, errors.New("profile '"+profileName+"' does not exist"))
 			continue
 		}
+		// Skip if other profiles extend profileName
+		if names := cmd.getExtendingProfiles(lockJSON, profileName); len(names) > 0 {
+			merr = multierror.Append(merr, errors.Errorf(
+				"cannot destroy profile '%s' because it's extended by '%s'",
+				profileName, strings.Join(names, "', '")))
+			continue
+		}
 
 		// Remove the specified profile
 		lockJSON.Profiles = append(lockJSON.Profiles[:index], lockJSON.Profiles[index+1:]...)
@@ -440,6 +410,13 @@ func (cmd *profileCmd) doRename(args []string) (err error) {
 	if lockJSON.CurrentProfileName == oldName {
 		lockJSON.CurrentProfileName = newName
 	}
+	for i := range lockJSON.Profiles {
+		for j := range lockJSON.Profiles[i].Extends {
+			if lockJSON.Profiles[i].Extends[j] == oldName {
+				lockJSON.Profiles[i].Extends[j] = newName
+			}
+		}
+	}
 
 	// Rename $VOLTPATH/rc/{profile} dir
 	oldRCDir := pathutil.RCDir(oldName)
@@ -484,6 +461,8 @@ func (cmd *profileCmd) doAdd(args []string) error {
 		for _, reposPath := range reposPathList {
 			if profile.ReposPath.Contains(reposPath) {
 				logger.Warn("repository '" + reposPath.String() + "' is already enabled")
+			} else if from := cmd.findInheritedFrom(lockJSON, profile, reposPath); from != "" {
+				logger.Warn("repository '" + reposPath.String() + "' is already enabled (inherited from profile '" + from + "')")
 			} else {
 				profile.ReposPath = append(profile.ReposPath, reposPath)
 				logger.Info("Enabled '" + reposPath.String() + "' on profile '" + profileName + "'")
@@ -529,6 +508,8 @@ func (cmd *profileCmd) doRm(args []string) error {
 				// Remove profile.ReposPath[index]
 				profile.ReposPath = append(profile.ReposPath[:index], profile.ReposPath[index+1:]...)
 				logger.Info("Disabled '" + reposPath.String() + "' from profile '" + profileName + "'")
+			} else if from := cmd.findInheritedFrom(lockJSON, profile, reposPath); from != "" {
+				logger.Warn("repository '" + reposPath.String() + "' is inherited from profile '" + from + "'. please remove it from profile '" + from + "', or run 'volt profile unextend " + profileName + " " + from + "'")
 			} else {
 				logger.Warn("repository '" + reposPath.String() + "' is already disabled")
 			}
@@ -547,6 +528,165 @@ func (cmd *profileCmd) doRm(args []string) error {
 	return nil
 }
 
+func (cmd *profileCmd) doExtend(args []string) error {
+	// Read lock.json
+	lockJSON, err := lockjson.Read()
+	if err != nil {
+		return errors.Wrap(err, "failed to read lock.json")
+	}
+
+	// Parse args
+	profileName, parentNames, err := cmd.parseExtendArgs(lockJSON, "extend", args)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse args")
+	}
+
+	// Read modified profile and write to lock.json
+	err = cmd.transactProfile(lockJSON, profileName, func(profile *lockjson.Profile) {
+		// Add profiles to extends if the profile does not exist
+		for _, name := range parentNames {
+			if cmd.containsName(profile.Extends, name) {
+				logger.Warn("profile '" + profileName + "' already extends '" + name + "'")
+			} else {
+				profile.Extends = append(profile.Extends, name)
+				logger.Info("Profile '" + profileName + "' extends '" + name + "'")
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// Build ~/.vim/pack/volt dir
+	err = builder.Build(false)
+	if err != nil {
+		return errors.Wrap(err, "could not build "+pathutil.VimVoltDir())
+	}
+
+	return nil
+}
+
+func (cmd *profileCmd) doUnextend(args []string) error {
+	// Read lock.json
+	lockJSON, err := lockjson.Read()
+	if err != nil {
+		return errors.Wrap(err, "failed to read lock.json")
+	}
+
+	// Parse args
+	profileName, parentNames, err := cmd.parseExtendArgs(lockJSON, "unextend", args)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse args")
+	}
+
+	// Read modified profile and write to lock.json
+	err = cmd.transactProfile(lockJSON, profileName, func(profile *lockjson.Profile) {
+		// Remove profiles from extends if the profile exists
+		for _, name := range parentNames {
+			if !cmd.containsName(profile.Extends, name) {
+				logger.Warn("profile '" + profileName + "' does not extend '" + name + "'")
+				continue
+			}
+			for i := range profile.Extends {
+				if profile.Extends[i] == name {
+					profile.Extends = append(profile.Extends[:i], profile.Extends[i+1:]...)
+					break
+				}
+			}
+			logger.Info("Profile '" + profileName + "' no longer extends '" + name + "'")
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// Build ~/.vim/pack/volt dir
+	err = builder.Build(false)
+	if err != nil {
+		return errors.Wrap(err, "could not build "+pathutil.VimVoltDir())
+	}
+
+	return nil
+}
+
+func (cmd *profileCmd) parseExtendArgs(lockJSON *lockjson.LockJSON, subCmd string, args []string) (string, []string, error) {
+	if len(args) < 2 {
+		cmd.FlagSet().Usage()
+		return "", nil, errors.Errorf("'volt profile %s' receives profile name and one or more profile names.", subCmd)
+	}
+
+	profileName := args[0]
+	if profileName == "-current" {
+		profileName = lockJSON.CurrentProfileName
+	}
+	parentNames := args[1:]
+
+	// Validate if all profiles exist in profiles[]
+	for _, name := range parentNames {
+		if lockJSON.Profiles.FindIndexByName(name) < 0 {
+			return "", nil, errors.New("profile '" + name + "' does not exist")
+		}
+		if name == profileName {
+			return "", nil, errors.New("profile '" + name + "' cannot extend itself")
+		}
+		if subCmd == "extend" {
+			// Validate if profileName is not an ancestor of name
+			parent, err := lockJSON.Profiles.FindByName(name)
+			if err != nil {
+				return "", nil, err
+			}
+			ancestors, err := lockJSON.ResolveProfiles(parent)
+			if err != nil {
+				return "", nil, err
+			}
+			for _, p := range ancestors {
+				if p.Name == profileName {
+					return "", nil, errors.Errorf("profile '%s' cannot extend '%s' because '%s' already extends '%s'", profileName, name, name, profileName)
+				}
+			}
+		}
+	}
+
+	return profileName, parentNames, nil
+}
+
+func (*profileCmd) containsName(names []string, name string) bool {
+	for i := range names {
+		if names[i] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// getExtendingProfiles returns the names of profiles which extend profileName
+// directly.
+func (cmd *profileCmd) getExtendingProfiles(lockJSON *lockjson.LockJSON, profileName string) []string {
+	var names []string
+	for i := range lockJSON.Profiles {
+		if cmd.containsName(lockJSON.Profiles[i].Extends, profileName) {
+			names = append(names, lockJSON.Profiles[i].Name)
+		}
+	}
+	return names
+}
+
+// findInheritedFrom returns the name of the profile which profile inherits
+// reposPath from. If profile does not inherit reposPath, returns an empty
+// string.
+func (*profileCmd) findInheritedFrom(lockJSON *lockjson.LockJSON, profile *lockjson.Profile, reposPath pathutil.ReposPath) string {
+	inherited, err := lockJSON.GetInheritedReposPath(profile)
+	if err != nil {
+		return ""
+	}
+	for i := range inherited {
+		if inherited[i].Path.Equals(reposPath) {
+			return inherited[i].Profile
+		}
+	}
+	return ""
+}
+
 func (cmd *profileCmd) parseAddArgs(lockJSON *lockjson.LockJSON, subCmd string, args []string) (string, []pathutil.ReposPath, error) {
 	if len(args) == 0 {
 		cmd.FlagSet().Usage()
@@ -0,0 +1,363 @@
+package subcmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/plugconf"
+)
+
+func init() {
+	cmdMap["profile-startup"] = &profileStartupCmd{}
+}
+
+type profileStartupCmd struct {
+	helped    bool
+	count     int
+	json      bool
+	threshold float64
+}
+
+// startupTimeResult is the result of "volt profile-startup".
+type startupTimeResult struct {
+	// Average startup time (msec)
+	StartupTime float64 `json:"startuptime"`
+	// The number of times Vim was executed
+	Runs int `json:"runs"`
+	// Sorted by Time (descending order)
+	Repos []reposStartupTime `json:"repos"`
+}
+
+type reposStartupTime struct {
+	Path pathutil.ReposPath `json:"path"`
+	// Average time of sourcing files of the repository (msec)
+	Time float64 `json:"time"`
+	// The return value of s:loaded_on() in plugconf.
+	// Empty if the repository is not in current profile.
+	LoadOn string `json:"load_on"`
+	// True if the repository loads at startup and takes threshold time or more
+	SuggestLazy bool `json:"suggest_lazy"`
+}
+
+func (cmd *profileStartupCmd) ProhibitRootExecution(args []string) bool { return true }
+
+func (cmd *profileStartupCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  volt profile-startup [-help] [-n {count}] [-json] [-threshold {msec}] [-- {vim args}]
+
+Quick example
+  $ volt profile-startup              # show startup time of each plugin
+  $ volt profile-startup -n 10 -json  # execute Vim 10 times, and show the result as JSON
+  $ volt profile-startup -- a.txt     # measure startup time with opening a.txt
+
+Description
+  Measure startup time of each plugin.
+
+  This command executes Vim {count} times with "--startuptime" option, and parses the logs. The time of sourcing files under ~/.vim/pack/volt/opt/ is attributed to each repository, and the average of them is shown in descending order. Vim is executed with "-c qall!" after {vim args}, so it exits immediately after startup.
+  Vim executable is looked up from $VOLT_VIM environment variable or $PATH.
+
+  For the plugins which load at startup and take {msec} or more, volt suggests loading them lazily by s:loaded_on() in plugconf (see "volt edit -help").
+
+JSON format
+  {
+    // Average startup time (msec)
+    "startuptime": <float>,
+    // The number of times Vim was executed
+    "runs": <int>,
+    "repos": [
+      {
+        "path": "{repository}",
+        // Average time of sourcing files of the repository (msec)
+        "time": <float>,
+        // The return value of s:loaded_on() in plugconf ("start", "filetype=...", "excmd=...").
+        // Empty if the repository is not in current profile.
+        "load_on": "{value}",
+        // True if the repository loads at startup and takes {msec} or more
+        "suggest_lazy": <bool>
+      },
+      ...
+    ]
+  }` + "\n\n")
+		fmt.Println("Options")
+		fs.PrintDefaults()
+		fmt.Println()
+		cmd.helped = true
+	}
+	fs.IntVar(&cmd.count, "n", 5, "the number of times to execute Vim")
+	fs.BoolVar(&cmd.json, "json", false, "show the result as JSON")
+	fs.Float64Var(&cmd.threshold, "threshold", 10, "suggest lazy loading for plugins which take this time (msec) or more")
+	return fs
+}
+
+func (cmd *profileStartupCmd) Run(args []string) *Error {
+	vimArgs, err := cmd.parseArgs(args)
+	if err == ErrShowedHelp {
+		return nil
+	}
+	if err != nil {
+		return &Error{Code: 10, Msg: "Failed to parse args: " + err.Error()}
+	}
+
+	result, err := cmd.profileStartup(vimArgs)
+	if err != nil {
+		return &Error{Code: 11, Msg: "Failed to measure startup time: " + err.Error()}
+	}
+
+	if cmd.json {
+		b, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return &Error{Code: 12, Msg: "Failed to output JSON: " + err.Error()}
+		}
+		fmt.Println(string(b))
+	} else {
+		cmd.printResult(result)
+	}
+	return nil
+}
+
+func (cmd *profileStartupCmd) parseArgs(args []string) ([]string, error) {
+	// Split arguments at "--" before flag.FlagSet.Parse() removes it
+	var vimArgs []string
+	for i := range args {
+		if args[i] == "--" {
+			vimArgs = args[i+1:]
+			args = args[:i]
+			break
+		}
+	}
+
+	fs := cmd.FlagSet()
+	fs.Parse(args)
+	if cmd.helped {
+		return nil, ErrShowedHelp
+	}
+	if len(fs.Args()) > 0 {
+		return nil, errors.Errorf("invalid argument '%s': use '--' to pass arguments to vim", fs.Args()[0])
+	}
+	if cmd.count < 1 {
+		return nil, errors.New("-n must be 1 or more")
+	}
+	return vimArgs, nil
+}
+
+func (cmd *profileStartupCmd) profileStartup(vimArgs []string) (*startupTimeResult, error) {
+	loadOnMap, err := cmd.getLoadOnMap()
+	if err != nil {
+		return nil, err
+	}
+	vimExePath, err := pathutil.VimExecutable()
+	if err != nil {
+		return nil, err
+	}
+
+	os.MkdirAll(pathutil.TempDir(), 0755)
+	tempDir, err := ioutil.TempDir(pathutil.TempDir(), "startuptime-")
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create temporary directory")
+	}
+	defer os.RemoveAll(tempDir)
+
+	var totalTime float64
+	totalReposTime := make(map[pathutil.ReposPath]float64)
+	for i := 0; i < cmd.count; i++ {
+		logger.Infof("Executing Vim (%d/%d) ...", i+1, cmd.count)
+		logFile := filepath.Join(tempDir, fmt.Sprintf("%d.log", i))
+		args := append([]string{"--startuptime", logFile}, vimArgs...)
+		args = append(args, "-c", "qall!")
+		logger.Debugf("Executing '%s %s' ...", vimExePath, strings.Join(args, " "))
+		vimCmd := exec.Command(vimExePath, args...)
+		vimCmd.Stdin = os.Stdin
+		vimCmd.Stdout = os.Stdout
+		vimCmd.Stderr = os.Stderr
+		if err := vimCmd.Run(); err != nil {
+			return nil, errors.Wrap(err, "vim exited with error")
+		}
+
+		startupTime, reposTime, err := readStartupTimeLog(logFile)
+		if err != nil {
+			return nil, err
+		}
+		totalTime += startupTime
+		for reposPath, t := range reposTime {
+			totalReposTime[reposPath] += t
+		}
+	}
+
+	result := &startupTimeResult{
+		StartupTime: totalTime / float64(cmd.count),
+		Runs:        cmd.count,
+		Repos:       make([]reposStartupTime, 0, len(totalReposTime)),
+	}
+	for reposPath, t := range totalReposTime {
+		t /= float64(cmd.count)
+		loadOn := loadOnMap[reposPath]
+		result.Repos = append(result.Repos, reposStartupTime{
+			Path:        reposPath,
+			Time:        t,
+			LoadOn:      loadOn,
+			SuggestLazy: loadOn == "start" && t >= cmd.threshold,
+		})
+	}
+	sort.Slice(result.Repos, func(i, j int) bool {
+		if result.Repos[i].Time != result.Repos[j].Time {
+			return result.Repos[i].Time > result.Repos[j].Time
+		}
+		return result.Repos[i].Path < result.Repos[j].Path
+	})
+	return result, nil
+}
+
+// getLoadOnMap returns a map whose key is a repository path of current
+// profile, and value is the return value of s:loaded_on() in its plugconf.
+func (cmd *profileStartupCmd) getLoadOnMap() (map[pathutil.ReposPath]string, error) {
+	lockJSON, err := lockjson.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read lock.json")
+	}
+	reposList, err := lockJSON.GetCurrentReposList()
+	if err != nil {
+		return nil, err
+	}
+
+	loadOnMap := make(map[pathutil.ReposPath]string, len(reposList))
+	for i := range reposList {
+		loadOnMap[reposList[i].Path] = "start"
+	}
+	// ParseMultiPlugconf() sorts the given slice
+	reposList = append([]lockjson.Repos{}, reposList...)
+	plugconfs, parseErr := plugconf.ParseMultiPlugconf(reposList)
+	if parseErr.HasErrs() {
+		logger.Error("Please fix the following errors of plugconf:")
+		for _, err := range parseErr.Errors().Errors {
+			logger.Error("  " + err.Error())
+		}
+		return nil, errors.New("failed to parse plugconf")
+	}
+	plugconfs.Each(func(reposPath pathutil.ReposPath, info *plugconf.ParsedInfo) {
+		loadOnMap[reposPath] = info.LoadOn()
+	})
+	return loadOnMap, nil
+}
+
+func (cmd *profileStartupCmd) printResult(result *startupTimeResult) {
+	fmt.Printf("Startup time: %.3f msec (average of %d runs)\n", result.StartupTime, result.Runs)
+	if len(result.Repos) == 0 {
+		fmt.Println("No plugins were loaded at startup.")
+		return
+	}
+
+	fmt.Println()
+	fmt.Printf("%10s  %6s  %-20s  %s\n", "TIME(msec)", "RATIO", "LOAD ON", "REPOSITORY")
+	var suggested pathutil.ReposPathList
+	for _, r := range result.Repos {
+		var ratio float64
+		if result.StartupTime > 0 {
+			ratio = r.Time / result.StartupTime * 100
+		}
+		loadOn := r.LoadOn
+		if loadOn == "" {
+			loadOn = "-"
+		}
+		fmt.Printf("%10.3f  %5.1f%%  %-20s  %s\n", r.Time, ratio, loadOn, r.Path)
+		if r.SuggestLazy {
+			suggested = append(suggested, r.Path)
+		}
+	}
+
+	if len(suggested) > 0 {
+		fmt.Println()
+		fmt.Printf("The following plugins load at startup and take %.3f msec or more.\n", cmd.threshold)
+		fmt.Println("Consider loading them lazily by s:loaded_on() in plugconf (e.g. return 'filetype=...' or 'excmd=...'):")
+		for _, reposPath := range suggested {
+			fmt.Printf("  volt edit %s\n", reposPath)
+		}
+	}
+}
+
+func readStartupTimeLog(path string) (float64, map[pathutil.ReposPath]float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "could not read startuptime log")
+	}
+	defer file.Close()
+	return parseStartupTimeLog(file, pathutil.VimVoltOptDir())
+}
+
+// parseStartupTimeLog parses the log written by Vim's "--startuptime" option.
+// Returns the startup time and a map whose key is a repository path, and value
+// is the time of sourcing the files of the repository under optDir.
+func parseStartupTimeLog(r io.Reader, optDir string) (float64, map[pathutil.ReposPath]float64, error) {
+	optDirs := []string{filepath.Clean(optDir)}
+	if dir, err := filepath.EvalSymlinks(optDir); err == nil && dir != optDirs[0] {
+		optDirs = append(optDirs, dir)
+	}
+
+	var startupTime float64
+	reposTime := make(map[pathutil.ReposPath]float64)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// Timing lines are:
+		//   {clock}  {elapsed}: {other lines}
+		//   {clock}  {self+sourced}  {self}: sourcing {script}
+		line := scanner.Text()
+		sep := strings.Index(line, ": ")
+		if sep == -1 {
+			continue
+		}
+		times := strings.Fields(line[:sep])
+		if len(times) < 2 || len(times) > 3 {
+			continue
+		}
+		clock, err := strconv.ParseFloat(times[0], 64)
+		if err != nil {
+			continue
+		}
+		startupTime = clock
+
+		const sourcing = "sourcing "
+		msg := line[sep+2:]
+		if len(times) != 3 || !strings.HasPrefix(msg, sourcing) {
+			continue
+		}
+		self, err := strconv.ParseFloat(times[2], 64)
+		if err != nil {
+			continue
+		}
+		script := filepath.Clean(strings.TrimPrefix(msg, sourcing))
+		for _, dir := range optDirs {
+			rel, err := filepath.Rel(dir, script)
+			if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+				continue
+			}
+			name := strings.Split(filepath.ToSlash(rel), "/")[0]
+			reposTime[pathutil.DecodeReposPath(name)] += self
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, nil, errors.Wrap(err, "could not read startuptime log")
+	}
+	if startupTime == 0 {
+		return 0, nil, errors.New("no timing information in startuptime log")
+	}
+	return startupTime, reposTime, nil
+}
@@ -0,0 +1,103 @@
+package subcmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/internal/testutil"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// Checks:
+// (A) Does not show `[ERROR]`, `[WARN]` messages
+// (B) Exit with zero status
+// (C) Shows the time of the plugin loaded at startup
+// (D) Suggests lazy loading for the plugin
+//
+// * Run `volt profile-startup -json` (A, B, C, !D)
+// * Run `volt profile-startup -json -threshold 0` (A, B, C, D)
+// * Run `volt profile-startup -threshold 0` (A, B, D)
+// * Run `volt profile-startup -n 0` (!A, !B)
+func TestVoltProfileStartup(t *testing.T) {
+	for _, tt := range []struct {
+		args    []string
+		json    bool
+		success bool
+		suggest bool
+	}{
+		{[]string{"-n", "2", "-json"}, true, true, false},
+		{[]string{"-n", "2", "-json", "-threshold", "0"}, true, true, true},
+		{[]string{"-n", "2", "-threshold", "0"}, false, true, true},
+		{[]string{"-n", "0"}, false, false, false},
+	} {
+		t.Run("volt profile-startup "+strings.Join(tt.args, " "), func(t *testing.T) {
+			testProfileMatrix(t, func(t *testing.T, strategy string) {
+				// =============== setup =============== //
+
+				testutil.SetUpEnv(t)
+				defer testutil.CleanUpEnv(t)
+				reposPath := pathutil.ReposPath("localhost/local/hello")
+				teardown := testutil.SetUpRepos(t, "hello", lockjson.ReposStaticType, []pathutil.ReposPath{reposPath}, strategy)
+				defer teardown()
+				testutil.InstallConfig(t, "strategy-"+strategy+".toml")
+				out, err := testutil.RunVolt("build")
+				testutil.SuccessExit(t, out, err)
+
+				// "-es" skips vimrc and plugins unless "-u" is given
+				vimrc := filepath.Join(pathutil.TempDir(), "profile-startup.vim")
+				os.MkdirAll(filepath.Dir(vimrc), 0777)
+				if err := ioutil.WriteFile(vimrc, []byte("set nocompatible\n"), 0644); err != nil {
+					t.Fatal("failed to write vimrc: " + err.Error())
+				}
+
+				// =============== run =============== //
+
+				args := append([]string{"profile-startup"}, tt.args...)
+				args = append(args, "--", "-es", "-u", vimrc)
+				out, err = testutil.RunVolt(args...)
+				if !tt.success {
+					// (!A, !B)
+					testutil.FailExit(t, out, err)
+					return
+				}
+				// (A, B)
+				testutil.SuccessExit(t, out, err)
+
+				if !tt.json {
+					// (D)
+					suggestion := "volt edit " + reposPath.String()
+					if bytes.Contains(out, []byte(suggestion)) != tt.suggest {
+						t.Errorf("expected output contains %q is %v but got: %s", suggestion, tt.suggest, string(out))
+					}
+					return
+				}
+
+				// Skip "[INFO] Executing Vim ..." lines
+				var result startupTimeResult
+				if i := bytes.IndexByte(out, '{'); i >= 0 {
+					out = out[i:]
+				}
+				if err := json.Unmarshal(out, &result); err != nil {
+					t.Fatalf("failed to parse JSON: %s: %s", err, string(out))
+				}
+				if result.Runs != 2 {
+					t.Errorf("expected runs is 2 but got %d", result.Runs)
+				}
+				// (C)
+				if len(result.Repos) != 1 || result.Repos[0].Path != reposPath || result.Repos[0].LoadOn != "start" {
+					t.Fatalf("expected repos is [%s] loaded on start but got: %s", reposPath, string(out))
+				}
+				// (D)
+				if result.Repos[0].SuggestLazy != tt.suggest {
+					t.Errorf("expected suggest_lazy is %v but got: %s", tt.suggest, string(out))
+				}
+			})
+		})
+	}
+}
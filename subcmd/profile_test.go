@@ -1171,6 +1171,217 @@ func TestVoltProfileRm(t *testing.T) {
 	})
 }
 
+// Checks:
+// (a) Profile extends specified profiles
+// (b) Repositories of extended profiles are inherited
+// (c) `volt profile show` shows inherited repositories
+//
+// * Run `volt profile extend <profile> <parent>` (<profile>, <parent>: exist) (A, B, a, b, c)
+// * Run `volt profile extend <profile> <parent>` (<parent> extends <profile>) (!A, !B, !a)
+// * Run `volt profile extend <profile> <parent>` (<parent>: not exist) (!A, !B, !a)
+// * Run `volt profile destroy <parent>` (<profile> extends <parent>) (!A, !B)
+func TestVoltProfileExtend(t *testing.T) {
+	t.Run("Run `volt profile extend <profile> <parent>` (<profile>, <parent>: exist)", func(t *testing.T) {
+		testProfileMatrix(t, func(t *testing.T, strategy string) {
+			// =============== setup =============== //
+
+			testutil.SetUpEnv(t)
+			defer testutil.CleanUpEnv(t)
+			reposPath := pathutil.ReposPath("localhost/local/hello")
+			teardown := testutil.SetUpRepos(t, "hello", lockjson.ReposStaticType, []pathutil.ReposPath{reposPath}, strategy)
+			defer teardown()
+			testutil.InstallConfig(t, "strategy-"+strategy+".toml")
+
+			out, err := testutil.RunVolt("profile", "new", "base")
+			testutil.SuccessExit(t, out, err)
+			out, err = testutil.RunVolt("profile", "add", "base", reposPath.String())
+			testutil.SuccessExit(t, out, err)
+			out, err = testutil.RunVolt("profile", "new", "foo")
+			testutil.SuccessExit(t, out, err)
+
+			// =============== run =============== //
+
+			out, err = testutil.RunVolt("profile", "extend", "foo", "base")
+			// (A, B)
+			testutil.SuccessExit(t, out, err)
+
+			lockJSON, err := lockjson.Read()
+			if err != nil {
+				t.Error("lockjson.Read() returned non-nil error: " + err.Error())
+			}
+			profile, err := lockJSON.Profiles.FindByName("foo")
+			if err != nil {
+				t.Fatal("lockJSON.Profiles.FindByName() returned non-nil error: " + err.Error())
+			}
+
+			// (a)
+			if len(profile.Extends) != 1 || profile.Extends[0] != "base" {
+				t.Errorf("expected profile '%s' extends '%s' but got: %v", "foo", "base", profile.Extends)
+			}
+			// (b)
+			if profile.ReposPath.Contains(reposPath) {
+				t.Errorf("expected '%s' is not added to profile '%s', but added", reposPath, "foo")
+			}
+			if reposList := getReposList(t, lockJSON, "foo"); !reposList.Contains(reposPath) {
+				t.Errorf("expected '%s' is inherited by profile '%s', but not inherited", reposPath, "foo")
+			}
+
+			// (c)
+			out, err = testutil.RunVolt("profile", "show", "foo")
+			testutil.SuccessExit(t, out, err)
+			expected := "name: foo\nextends:\n  base\nrepos path:\ninherited repos path:\n  localhost/local/hello (from base)\n"
+			if string(out) != expected {
+				t.Errorf("=== expected ===\n[%s]\n=== got ===\n[%s]", expected, string(out))
+			}
+		})
+	})
+
+	t.Run("Run `volt profile extend <profile> <parent>` (<parent> extends <profile>)", func(t *testing.T) {
+		testProfileMatrix(t, func(t *testing.T, strategy string) {
+			// =============== setup =============== //
+
+			testutil.SetUpEnv(t)
+			defer testutil.CleanUpEnv(t)
+			testutil.InstallConfig(t, "strategy-"+strategy+".toml")
+
+			out, err := testutil.RunVolt("profile", "new", "foo")
+			testutil.SuccessExit(t, out, err)
+			out, err = testutil.RunVolt("profile", "new", "bar")
+			testutil.SuccessExit(t, out, err)
+			out, err = testutil.RunVolt("profile", "extend", "bar", "foo")
+			testutil.SuccessExit(t, out, err)
+
+			oldLockJSON, err := lockjson.Read()
+			if err != nil {
+				t.Error("lockjson.Read() returned non-nil error: " + err.Error())
+			}
+
+			// =============== run =============== //
+
+			out, err = testutil.RunVolt("profile", "extend", "foo", "bar")
+			// (!A, !B)
+			testutil.FailExit(t, out, err)
+
+			lockJSON, err := lockjson.Read()
+			if err != nil {
+				t.Error("lockjson.Read() returned non-nil error: " + err.Error())
+			}
+			// (!a)
+			testNotChangedProfileExcept(t, oldLockJSON, lockJSON, "")
+		})
+	})
+
+	t.Run("Run `volt profile extend <profile> <parent>` (<parent>: not exist)", func(t *testing.T) {
+		testProfileMatrix(t, func(t *testing.T, strategy string) {
+			// =============== setup =============== //
+
+			testutil.SetUpEnv(t)
+			defer testutil.CleanUpEnv(t)
+			testutil.InstallConfig(t, "strategy-"+strategy+".toml")
+
+			oldLockJSON, err := lockjson.Read()
+			if err != nil {
+				t.Error("lockjson.Read() returned non-nil error: " + err.Error())
+			}
+
+			// =============== run =============== //
+
+			out, err := testutil.RunVolt("profile", "extend", "default", "not_existing_profile")
+			// (!A, !B)
+			testutil.FailExit(t, out, err)
+
+			lockJSON, err := lockjson.Read()
+			if err != nil {
+				t.Error("lockjson.Read() returned non-nil error: " + err.Error())
+			}
+			// (!a)
+			testNotChangedProfileExcept(t, oldLockJSON, lockJSON, "")
+		})
+	})
+
+	t.Run("Run `volt profile destroy <parent>` (<profile> extends <parent>)", func(t *testing.T) {
+		testProfileMatrix(t, func(t *testing.T, strategy string) {
+			// =============== setup =============== //
+
+			testutil.SetUpEnv(t)
+			defer testutil.CleanUpEnv(t)
+			testutil.InstallConfig(t, "strategy-"+strategy+".toml")
+
+			out, err := testutil.RunVolt("profile", "new", "base")
+			testutil.SuccessExit(t, out, err)
+			out, err = testutil.RunVolt("profile", "extend", "default", "base")
+			testutil.SuccessExit(t, out, err)
+
+			// =============== run =============== //
+
+			out, err = testutil.RunVolt("profile", "destroy", "base")
+			// (!A, !B)
+			testutil.FailExit(t, out, err)
+
+			lockJSON, err := lockjson.Read()
+			if err != nil {
+				t.Error("lockjson.Read() returned non-nil error: " + err.Error())
+			}
+			if lockJSON.Profiles.FindIndexByName("base") == -1 {
+				t.Errorf("expected profile '%s' does exist, but does not exist", "base")
+			}
+		})
+	})
+}
+
+// Checks:
+// (a) Profile does not extend specified profiles
+// (b) Repositories of unextended profiles are not inherited
+//
+// * Run `volt profile unextend <profile> <parent>` (<profile> extends <parent>) (A, B, a, b)
+func TestVoltProfileUnextend(t *testing.T) {
+	t.Run("Run `volt profile unextend <profile> <parent>` (<profile> extends <parent>)", func(t *testing.T) {
+		testProfileMatrix(t, func(t *testing.T, strategy string) {
+			// =============== setup =============== //
+
+			testutil.SetUpEnv(t)
+			defer testutil.CleanUpEnv(t)
+			reposPath := pathutil.ReposPath("localhost/local/hello")
+			teardown := testutil.SetUpRepos(t, "hello", lockjson.ReposStaticType, []pathutil.ReposPath{reposPath}, strategy)
+			defer teardown()
+			testutil.InstallConfig(t, "strategy-"+strategy+".toml")
+
+			out, err := testutil.RunVolt("profile", "new", "base")
+			testutil.SuccessExit(t, out, err)
+			out, err = testutil.RunVolt("profile", "add", "base", reposPath.String())
+			testutil.SuccessExit(t, out, err)
+			out, err = testutil.RunVolt("profile", "new", "foo")
+			testutil.SuccessExit(t, out, err)
+			out, err = testutil.RunVolt("profile", "extend", "foo", "base")
+			testutil.SuccessExit(t, out, err)
+
+			// =============== run =============== //
+
+			out, err = testutil.RunVolt("profile", "unextend", "foo", "base")
+			// (A, B)
+			testutil.SuccessExit(t, out, err)
+
+			lockJSON, err := lockjson.Read()
+			if err != nil {
+				t.Error("lockjson.Read() returned non-nil error: " + err.Error())
+			}
+			profile, err := lockJSON.Profiles.FindByName("foo")
+			if err != nil {
+				t.Fatal("lockJSON.Profiles.FindByName() returned non-nil error: " + err.Error())
+			}
+
+			// (a)
+			if len(profile.Extends) != 0 {
+				t.Errorf("expected profile '%s' extends nothing but got: %v", "foo", profile.Extends)
+			}
+			// (b)
+			if reposList := getReposList(t, lockJSON, "foo"); reposList.Contains(reposPath) {
+				t.Errorf("expected '%s' is not inherited by profile '%s', but inherited", reposPath, "foo")
+			}
+		})
+	})
+}
+
 // ============================================
 
 func getReposList(t *testing.T, lockJSON *lockjson.LockJSON, profileName string) lockjson.ReposList {
@@ -1,21 +1,16 @@
 package subcmd
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
-	"path/filepath"
-	"strings"
 
 	"github.com/pkg/errors"
 
-	"github.com/vim-volt/volt/fileutil"
-	"github.com/vim-volt/volt/lockjson"
 	"github.com/vim-volt/volt/logger"
 	"github.com/vim-volt/volt/pathutil"
-	"github.com/vim-volt/volt/plugconf"
-	"github.com/vim-volt/volt/subcmd/builder"
-	"github.com/vim-volt/volt/transaction"
+	"github.com/vim-volt/volt/pkg/volt"
 )
 
 func init() {
@@ -50,6 +45,7 @@ Description
   If {repository} is depended by other repositories, this command exits with an error.
 
   If -r option was given, remove also repository directories of specified repositories.
+  System repositories (see "volt get -help") are read-only, so -r cannot be used for them.
   If -p option was given, remove also plugconf files of specified repositories.
 
   {repository} is treated as same format as "volt get" (see "volt get -help").` + "\n\n")
@@ -72,21 +68,25 @@ func (cmd *rmCmd) Run(args []string) *Error {
 		return &Error{Code: 10, Msg: err.Error()}
 	}
 
-	err = cmd.doRemove(reposPathList)
-	if err != nil {
-		return &Error{Code: 11, Msg: "Failed to remove repository: " + err.Error()}
+	results, err := volt.Remove(context.Background(), volt.RemoveOptions{
+		Repos:          reposPathList.Strings(),
+		RemoveRepos:    cmd.rmRepos,
+		RemovePlugconf: cmd.rmPlugconf,
+		Logger:         logger.Default(),
+	})
+	for i := range results {
+		fmt.Printf("%+v\n", results[i].Path)
+		fmt.Printf("  fullpath:%+v\n", results[i].Path.FullPath())
+		fmt.Printf("  plugconf:%+v\n", results[i].Path.Plugconf())
 	}
-
-	// Build opt dir
-	err = builder.Build(false)
 	if err != nil {
-		return &Error{Code: 12, Msg: "Could not build " + pathutil.VimVoltDir() + ": " + err.Error()}
+		return &Error{Code: 11, Msg: "Failed to remove repository: " + err.Error()}
 	}
 
 	return nil
 }
 
-func (cmd *rmCmd) parseArgs(args []string) ([]pathutil.ReposPath, error) {
+func (cmd *rmCmd) parseArgs(args []string) (pathutil.ReposPathList, error) {
 	fs := cmd.FlagSet()
 	fs.Parse(args)
 	if cmd.helped {
@@ -98,7 +98,7 @@ func (cmd *rmCmd) parseArgs(args []string) ([]pathutil.ReposPath, error) {
 		return nil, errors.New("repository was not given")
 	}
 
-	var reposPathList []pathutil.ReposPath
+	var reposPathList pathutil.ReposPathList
 	for _, arg := range fs.Args() {
 		reposPath, err := pathutil.NormalizeRepos(arg)
 		if err != nil {
@@ -108,112 +108,3 @@ func (cmd *rmCmd) parseArgs(args []string) ([]pathutil.ReposPath, error) {
 	}
 	return reposPathList, nil
 }
-
-func (cmd *rmCmd) doRemove(reposPathList []pathutil.ReposPath) (err error) {
-	// Read lock.json
-	lockJSON, err := lockjson.Read()
-	if err != nil {
-		return
-	}
-
-	// Begin transaction
-	trx, err := transaction.Start()
-	if err != nil {
-		return
-	}
-	defer func() {
-		if e := trx.Done(); e != nil {
-			err = e
-		}
-	}()
-
-	// Get the existing entries if already have it
-	// (e.g. github.com/tyru/CaW.vim -> github.com/tyru/caw.vim)
-	for i := range reposPathList {
-		if r := lockJSON.Repos.FindByPath(reposPathList[i]); r != nil {
-			reposPathList[i] = r.Path
-		}
-		fmt.Printf("%+v\n", reposPathList[i])
-		fmt.Printf("  fullpath:%+v\n", reposPathList[i].FullPath())
-		fmt.Printf("  plugconf:%+v\n", reposPathList[i].Plugconf())
-	}
-
-	// Check if specified plugins are depended by some plugins
-	for _, reposPath := range reposPathList {
-		var rdeps pathutil.ReposPathList
-		rdeps, err = plugconf.RdepsOf(reposPath, lockJSON.Repos)
-		if err != nil {
-			return
-		}
-		if len(rdeps) > 0 {
-			err = errors.Errorf("cannot remove '%s' because it's depended by '%s'",
-				reposPath, strings.Join(rdeps.Strings(), "', '"))
-			return
-		}
-	}
-
-	removeCount := 0
-	for _, reposPath := range reposPathList {
-		// Remove repository directory
-		if cmd.rmRepos {
-			fullReposPath := reposPath.FullPath()
-			if pathutil.Exists(fullReposPath) {
-				if err = cmd.removeRepos(fullReposPath); err != nil {
-					return
-				}
-				removeCount++
-			} else {
-				logger.Debugf("No repository was installed for '%s' ... skip.", reposPath)
-			}
-		}
-
-		// Remove plugconf file
-		if cmd.rmPlugconf {
-			plugconfPath := reposPath.Plugconf()
-			if pathutil.Exists(plugconfPath) {
-				if err = cmd.removePlugconf(plugconfPath); err != nil {
-					return
-				}
-				removeCount++
-			} else {
-				logger.Debugf("No plugconf was installed for '%s' ... skip.", reposPath)
-			}
-		}
-
-		// Remove repository from lock.json
-		err = lockJSON.Repos.RemoveAllReposPath(reposPath)
-		err2 := lockJSON.Profiles.RemoveAllReposPath(reposPath)
-		if err == nil || err2 == nil {
-			removeCount++
-		}
-	}
-	if removeCount == 0 {
-		err = errors.New("no plugins are removed")
-		return
-	}
-
-	// Write to lock.json
-	err = lockJSON.Write()
-	return
-}
-
-// Remove repository directory
-func (cmd *rmCmd) removeRepos(fullReposPath string) error {
-	logger.Info("Removing " + fullReposPath + " ...")
-	if err := os.RemoveAll(fullReposPath); err != nil {
-		return err
-	}
-	fileutil.RemoveDirs(filepath.Dir(fullReposPath))
-	return nil
-}
-
-// Remove plugconf file
-func (*rmCmd) removePlugconf(plugconfPath string) error {
-	logger.Info("Removing plugconf files ...")
-	if err := os.Remove(plugconfPath); err != nil {
-		return err
-	}
-	// Remove parent directories of plugconf
-	fileutil.RemoveDirs(filepath.Dir(plugconfPath))
-	return nil
-}
@@ -1,8 +1,11 @@
 package subcmd
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/vim-volt/volt/internal/testutil"
@@ -17,6 +20,8 @@ import (
 // (D) Plugconf of `$VOLTPATH/plugconf/<repos>.vim` are removed
 // (E) Repositories are removed from `~/.vim/pack/volt/<repos>/`
 // (F) Specified entries in lock.json are removed
+// (G) lock.json before `volt rm` is saved as lock.json.prev
+// (H) System repositories are not removed
 
 // TODO: Add test cases
 // * [error] Run `volt rm <plugin>` when the plugin is depended by some plugins (!A, !B, !C, !D, !E, !F)
@@ -479,6 +484,65 @@ func TestErrVoltRmInvalidArgs(t *testing.T) {
 	testutil.FailExit(t, out, err)
 }
 
+// Run `volt rm <plugin>` (repos: exists, plugconf: not exist, vim repos: exists) (A, B, !C, E, F, G)
+// Run `volt list` when lock.json is broken (!A, !B): error message shows lock.json.prev
+func TestVoltRmSavesPrevLockJSON(t *testing.T) {
+	// =============== setup =============== //
+
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	reposPath := pathutil.ReposPath("localhost/local/hello")
+	plugin := filepath.Join(reposPath.FullPath(), "plugin", "hello.vim")
+	os.MkdirAll(filepath.Dir(plugin), 0777)
+	if err := ioutil.WriteFile(plugin, []byte("command! Hello echo 'hello'\n"), 0644); err != nil {
+		t.Fatal("failed to write plugin: " + err.Error())
+	}
+	out, err := testutil.RunVolt("get", reposPath.String())
+	testutil.SuccessExit(t, out, err)
+	oldLockJSON, err := ioutil.ReadFile(pathutil.LockJSON())
+	if err != nil {
+		t.Fatal("failed to read lock.json: " + err.Error())
+	}
+
+	// =============== run =============== //
+
+	out, err = testutil.RunVolt("rm", reposPath.String())
+	// (A, B)
+	testutil.SuccessExit(t, out, err)
+
+	// (!C)
+	if !pathutil.Exists(reposPath.FullPath()) {
+		t.Error("repos was removed: " + reposPath.FullPath())
+	}
+
+	// (E)
+	if pathutil.Exists(reposPath.EncodeToPlugDirName()) {
+		t.Error("vim repos was not removed: " + reposPath.EncodeToPlugDirName())
+	}
+
+	// (F)
+	testReposPathWereRemoved(t, reposPath)
+
+	// (G)
+	prev, err := ioutil.ReadFile(pathutil.LockJSONPrev())
+	if err != nil {
+		t.Fatal("failed to read lock.json.prev: " + err.Error())
+	}
+	if !bytes.Equal(prev, oldLockJSON) {
+		t.Errorf("expected lock.json.prev is %s but got %s", string(oldLockJSON), string(prev))
+	}
+
+	if err := ioutil.WriteFile(pathutil.LockJSON(), oldLockJSON[:len(oldLockJSON)/2], 0644); err != nil {
+		t.Fatal("failed to write lock.json: " + err.Error())
+	}
+	out, err = testutil.RunVolt("list")
+	// (!A, !B)
+	testutil.FailExit(t, out, err)
+	if !bytes.Contains(out, []byte(pathutil.LockJSONPrev())) {
+		t.Errorf("expected error message shows %s but got: %s", pathutil.LockJSONPrev(), string(out))
+	}
+}
+
 // [error] Specify plugin which does not exist (!A, !B)
 func TestErrVoltRmNotFound(t *testing.T) {
 	// =============== setup =============== //
@@ -556,3 +620,29 @@ func testRmMatrix(t *testing.T, f func(*testing.T, string)) {
 		})
 	}
 }
+
+// [error] Run `volt rm -r {system repos}` (!A, !B, H, !F)
+func TestErrVoltRmRoptSystemRepos(t *testing.T) {
+	// =============== setup =============== //
+
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	reposPath := pathutil.ReposPath("localhost/system/hello")
+	sysDir, _ := setUpSystemRepos(t, reposPath, "copy")
+	out, err := testutil.RunVolt("get", reposPath.String())
+	testutil.SuccessExit(t, out, err)
+
+	// =============== run =============== //
+
+	out, err = testutil.RunVolt("rm", "-r", reposPath.String())
+	// (!A, !B)
+	testutil.FailExit(t, out, err)
+
+	// (H)
+	if !pathutil.Exists(filepath.Join(sysDir, "localhost", "system", "hello")) {
+		t.Error("system repository was removed")
+	}
+
+	// (!F)
+	testReposPathWereAdded(t, reposPath)
+}
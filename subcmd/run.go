@@ -0,0 +1,279 @@
+package subcmd
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+
+	"github.com/vim-volt/volt/internal/builder"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/logger"
+	"github.com/vim-volt/volt/pathutil"
+	"github.com/vim-volt/volt/transaction"
+)
+
+func init() {
+	cmdMap["run"] = &runCmd{}
+}
+
+type runCmd struct {
+	helped  bool
+	profile string
+}
+
+func (cmd *runCmd) ProhibitRootExecution(args []string) bool { return true }
+
+func (cmd *runCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  volt run [-help] [-p {profile}] [+{repository} ...] [-- {vim args}]
+
+Quick example
+  $ volt run                           # launch Vim with plugins of current profile
+  $ volt run -p foo                    # launch Vim with plugins of "foo" profile
+  $ volt run +tyru/caw.vim             # launch Vim with plugins of current profile and tyru/caw.vim
+  $ volt run -p foo -- -O a.txt b.txt  # pass "-O a.txt b.txt" to Vim
+
+Description
+  Launch Vim with plugins of {profile} (default: current profile) and given {repository} list.
+  {repository} must be installed by "volt get" beforehand.
+
+  This command builds a temporary directory under $VOLTPATH/tmp/ like "volt build", and executes Vim with 'packpath' and 'runtimepath' pointing at it. vimrc and gvimrc of {profile} are used instead of ~/.vim/vimrc and ~/.vim/gvimrc .
+  ~/.vim/pack/volt/ directory and $VOLTPATH/lock.json are not modified, and the temporary directory is removed after Vim exits.` + "\n\n")
+		fmt.Println("Options")
+		fs.PrintDefaults()
+		fmt.Println()
+		cmd.helped = true
+	}
+	fs.StringVar(&cmd.profile, "p", "", "profile name (default: current profile)")
+	return fs
+}
+
+func (cmd *runCmd) Run(args []string) *Error {
+	reposPathList, vimArgs, err := cmd.parseArgs(args)
+	if err == ErrShowedHelp {
+		return nil
+	}
+	if err != nil {
+		return &Error{Code: 10, Msg: "Failed to parse args: " + err.Error()}
+	}
+
+	lockJSON, err := cmd.makeLockJSON(reposPathList)
+	if err != nil {
+		return &Error{Code: 11, Msg: err.Error()}
+	}
+
+	err = cmd.doRun(lockJSON, vimArgs)
+	if err != nil {
+		return &Error{Code: 12, Msg: "Failed to run vim: " + err.Error()}
+	}
+	return nil
+}
+
+func (cmd *runCmd) parseArgs(args []string) (pathutil.ReposPathList, []string, error) {
+	// Split arguments at "--" before flag.FlagSet.Parse() removes it
+	var vimArgs []string
+	for i := range args {
+		if args[i] == "--" {
+			vimArgs = args[i+1:]
+			args = args[:i]
+			break
+		}
+	}
+
+	fs := cmd.FlagSet()
+	fs.Parse(args)
+	if cmd.helped {
+		return nil, nil, ErrShowedHelp
+	}
+
+	reposPathList := make(pathutil.ReposPathList, 0, len(fs.Args()))
+	for _, arg := range fs.Args() {
+		if !strings.HasPrefix(arg, "+") {
+			return nil, nil, errors.Errorf("invalid argument '%s': repository must be prefixed with '+', and use '--' to pass arguments to vim", arg)
+		}
+		reposPath, err := pathutil.NormalizeRepos(arg[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		reposPathList = append(reposPathList, reposPath)
+	}
+	return reposPathList, vimArgs, nil
+}
+
+// makeLockJSON returns lock.json whose current profile is cmd.profile, and
+// has reposPathList in addition to repositories of the profile.
+// $VOLTPATH/lock.json is not modified.
+func (cmd *runCmd) makeLockJSON(reposPathList pathutil.ReposPathList) (*lockjson.LockJSON, error) {
+	lockJSON, err := lockjson.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read lock.json")
+	}
+
+	profileName := cmd.profile
+	if profileName == "" {
+		profileName = lockJSON.CurrentProfileName
+	}
+	profile, err := lockJSON.Profiles.FindByName(profileName)
+	if err != nil {
+		return nil, err
+	}
+	reposList, err := lockJSON.GetReposListByProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	runReposPathList := make(pathutil.ReposPathList, 0, len(reposList)+len(reposPathList))
+	for i := range reposList {
+		runReposPathList = append(runReposPathList, reposList[i].Path)
+	}
+	for _, reposPath := range reposPathList {
+		if !runReposPathList.Contains(reposPath) {
+			runReposPathList = append(runReposPathList, reposPath)
+		}
+	}
+	return makeTemporaryLockJSON(lockJSON, profileName, runReposPathList)
+}
+
+func (cmd *runCmd) doRun(lockJSON *lockjson.LockJSON, vimArgs []string) error {
+	vimExePath, err := pathutil.VimExecutable()
+	if err != nil {
+		return err
+	}
+	return buildTemporaryVimDir(lockJSON, func(vimDir, runVimrc string) error {
+		// Vim handles interrupts by itself.
+		// Catch them (and SIGTERM) here not to exit before removing the
+		// temporary directory.
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sigCh)
+
+		args := makeRunVimArgs(vimDir, runVimrc, vimArgs)
+		logger.Debugf("Executing '%s %s' ...", vimExePath, strings.Join(args, " "))
+		vimCmd := exec.Command(vimExePath, args...)
+		vimCmd.Stdin = os.Stdin
+		vimCmd.Stdout = os.Stdout
+		vimCmd.Stderr = os.Stderr
+		return vimCmd.Run()
+	})
+}
+
+// makeTemporaryLockJSON returns a copy of lockJSON whose current profile is
+// profileName, and the repositories of the profile are reposPathList.
+// The profiles which the profile extends are kept to use their rc files, but
+// their repositories are cleared.
+func makeTemporaryLockJSON(lockJSON *lockjson.LockJSON, profileName string, reposPathList pathutil.ReposPathList) (*lockjson.LockJSON, error) {
+	profile, err := lockJSON.Profiles.FindByName(profileName)
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := lockJSON.ResolveProfiles(profile)
+	if err != nil {
+		return nil, err
+	}
+	for _, reposPath := range reposPathList {
+		if lockJSON.Repos.FindByPath(reposPath) == nil {
+			return nil, errors.Errorf("repository '%s' is not installed. please run 'volt get %s' beforehand", reposPath, reposPath)
+		}
+	}
+
+	// Copy profiles not to modify profiles of lockJSON
+	tmpLockJSON := *lockJSON
+	tmpLockJSON.Profiles = make(lockjson.ProfileList, len(lockJSON.Profiles))
+	copy(tmpLockJSON.Profiles, lockJSON.Profiles)
+	for _, p := range profiles {
+		tmpProfile, err := tmpLockJSON.Profiles.FindByName(p.Name)
+		if err != nil {
+			return nil, err
+		}
+		// Allocate new array not to modify the array of lockJSON
+		tmpProfile.ReposPath = tmpProfile.ReposPath[:0:0]
+		if p.Name == profileName {
+			tmpProfile.ReposPath = append(tmpProfile.ReposPath, reposPathList...)
+		}
+	}
+	tmpLockJSON.CurrentProfileName = profileName
+	return &tmpLockJSON, nil
+}
+
+// buildTemporaryVimDir builds lockJSON into a temporary directory under
+// $VOLTPATH/tmp instead of ~/.vim, and calls f with the directory and the
+// vimrc for the directory (see writeRunVimrc()).
+// The temporary directory is removed after f returns.
+func buildTemporaryVimDir(lockJSON *lockjson.LockJSON, f func(vimDir, runVimrc string) error) error {
+	os.MkdirAll(pathutil.TempDir(), 0755)
+	vimDir, err := ioutil.TempDir(pathutil.TempDir(), "run-")
+	if err != nil {
+		return errors.Wrap(err, "could not create temporary directory")
+	}
+	defer os.RemoveAll(vimDir)
+
+	err = buildVimDir(lockJSON, vimDir)
+	if err != nil {
+		return errors.Wrap(err, "could not build "+vimDir)
+	}
+	runVimrc, err := writeRunVimrc(vimDir)
+	if err != nil {
+		return err
+	}
+	return f(vimDir, runVimrc)
+}
+
+// buildVimDir builds lockJSON into vimDir instead of ~/.vim .
+func buildVimDir(lockJSON *lockjson.LockJSON, vimDir string) (result error) {
+	// Begin transaction
+	trx, err := transaction.Start()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := trx.Done(); err != nil {
+			result = err
+		}
+	}()
+
+	return builder.BuildLockJSON(lockJSON, vimDir)
+}
+
+// writeRunVimrc writes a vimrc which makes 'packpath' and 'runtimepath' point
+// at vimDir, and sources vimrc installed in vimDir.
+// Returns the path of the written vimrc.
+func writeRunVimrc(vimDir string) (string, error) {
+	dir := strings.Replace(vimDir, "'", "''", -1)
+	lines := []string{
+		"set nocompatible",
+		"let &packpath = '" + dir + ",' . $VIMRUNTIME",
+		"let &runtimepath = '" + dir + ",' . &runtimepath",
+	}
+	vimrc := filepath.Join(vimDir, pathutil.Vimrc)
+	if pathutil.Exists(vimrc) {
+		vimrc = strings.Replace(vimrc, "'", "''", -1)
+		lines = append(lines, "execute 'source' fnameescape('"+vimrc+"')")
+	}
+	runVimrc := filepath.Join(vimDir, "run.vim")
+	content := []byte(strings.Join(lines, "\n") + "\n")
+	if err := ioutil.WriteFile(runVimrc, content, 0644); err != nil {
+		return "", err
+	}
+	return runVimrc, nil
+}
+
+func makeRunVimArgs(vimDir, runVimrc string, vimArgs []string) []string {
+	gvimrc := filepath.Join(vimDir, pathutil.Gvimrc)
+	if !pathutil.Exists(gvimrc) {
+		gvimrc = "NONE"
+	}
+	args := []string{"-u", runVimrc, "-U", gvimrc}
+	return append(args, vimArgs...)
+}
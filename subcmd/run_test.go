@@ -0,0 +1,103 @@
+package subcmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vim-volt/volt/internal/testutil"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// Checks:
+// (A) Does not show `[ERROR]`, `[WARN]` messages
+// (B) Exit with zero status
+// (C) Plugins of specified profile and repositories are loaded
+// (D) lock.json is not changed
+// (E) Temporary directory is removed
+// (F) ~/.vim/pack/volt is not changed
+//
+// * Run `volt run` (A, B, C, D, E, F)
+// * Run `volt run -p <profile>` (<profile>: exists) (A, B, C, D, E, F)
+// * Run `volt run -p <profile> +<repos>` (<profile>: exists, <repos>: installed) (A, B, C, D, E, F)
+// * Run `volt run -p <profile>` (<profile>: not exist) (!A, !B, D, E, F)
+// * Run `volt run +<repos>` (<repos>: not installed) (!A, !B, D, E, F)
+func TestVoltRun(t *testing.T) {
+	for _, tt := range []struct {
+		args    []string
+		success bool
+		loaded  bool
+	}{
+		{[]string{}, true, true},
+		{[]string{"-p", "empty"}, true, false},
+		{[]string{"-p", "empty", "+localhost/local/hello"}, true, true},
+		{[]string{"-p", "not_existing_profile"}, false, false},
+		{[]string{"+localhost/local/not_installed"}, false, false},
+	} {
+		t.Run("volt run "+strings.Join(tt.args, " "), func(t *testing.T) {
+			testProfileMatrix(t, func(t *testing.T, strategy string) {
+				// =============== setup =============== //
+
+				testutil.SetUpEnv(t)
+				defer testutil.CleanUpEnv(t)
+				reposPath := pathutil.ReposPath("localhost/local/hello")
+				teardown := testutil.SetUpRepos(t, "hello", lockjson.ReposStaticType, []pathutil.ReposPath{reposPath}, strategy)
+				defer teardown()
+				testutil.InstallConfig(t, "strategy-"+strategy+".toml")
+
+				out, err := testutil.RunVolt("profile", "new", "empty")
+				testutil.SuccessExit(t, out, err)
+
+				oldLockJSON, err := ioutil.ReadFile(pathutil.LockJSON())
+				if err != nil {
+					t.Fatal("failed to read lock.json: " + err.Error())
+				}
+				oldBuild, _ := os.Readlink(pathutil.VimVoltDir())
+
+				// =============== run =============== //
+
+				args := append([]string{"run"}, tt.args...)
+				args = append(args, "--", "-es", "-c", "verbose echo 'loaded:' . exists(':Hello')", "-c", "qa!")
+				out, err = testutil.RunVolt(args...)
+				if tt.success {
+					// (A, B)
+					testutil.SuccessExit(t, out, err)
+					// (C)
+					expected := "loaded:0"
+					if tt.loaded {
+						expected = "loaded:2"
+					}
+					if !bytes.Contains(out, []byte(expected)) {
+						t.Errorf("expected output contains %q but got: %s", expected, string(out))
+					}
+				} else {
+					// (!A, !B)
+					testutil.FailExit(t, out, err)
+				}
+
+				// (D)
+				lockJSON, err := ioutil.ReadFile(pathutil.LockJSON())
+				if err != nil {
+					t.Fatal("failed to read lock.json: " + err.Error())
+				}
+				if !bytes.Equal(oldLockJSON, lockJSON) {
+					t.Error("expected lock.json is not changed but changed")
+				}
+
+				// (E)
+				if matches, _ := filepath.Glob(filepath.Join(pathutil.TempDir(), "run-*")); len(matches) > 0 {
+					t.Errorf("expected temporary directory was removed but exists: %v", matches)
+				}
+
+				// (F)
+				if build, _ := os.Readlink(pathutil.VimVoltDir()); build != oldBuild {
+					t.Errorf("expected %s is not changed but it refers to %q", pathutil.VimVoltDir(), build)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package subcmd
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/gitutil"
+	"github.com/vim-volt/volt/lockjson"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func init() {
+	cmdMap["status"] = &statusCmd{}
+}
+
+type statusCmd struct {
+	helped bool
+}
+
+func (cmd *statusCmd) ProhibitRootExecution(args []string) bool { return false }
+
+func (cmd *statusCmd) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	fs.SetOutput(os.Stdout)
+	fs.Usage = func() {
+		fmt.Print(`
+Usage
+  volt status [-help]
+
+Quick example
+  $ volt status
+  current profile: default
+
+  repos:
+  * github.com/tyru/caw.vim (git) 0f7dd4c
+  * github.com/tyru/open-browser.vim (system) 8ff1d3c
+      path: /usr/share/volt/repos/github.com/tyru/open-browser.vim
+      HEAD: 5d8e1b9 (changed, run 'volt get -u github.com/tyru/open-browser.vim' to lock it)
+    localhost/local/hello (static)
+
+Description
+  Show current profile and all installed repositories with their types and locked versions.
+  Repositories marked with "*" are used by current profile.
+  For system repositories, this also shows the directory found in system.repos_path of config.toml and its HEAD commit.
+  If an administrator updated a system repository after it was locked, its HEAD differs from the locked version.` + "\n\n")
+		//fmt.Println("Options")
+		//fs.PrintDefaults()
+		fmt.Println()
+		cmd.helped = true
+	}
+	return fs
+}
+
+func (cmd *statusCmd) Run(args []string) *Error {
+	fs := cmd.FlagSet()
+	fs.Parse(args)
+	if cmd.helped {
+		return nil
+	}
+
+	lockJSON, err := lockjson.Read()
+	if err != nil {
+		return &Error{Code: 10, Msg: "Could not read lock.json: " + err.Error()}
+	}
+	cfg, err := config.Read()
+	if err != nil {
+		return &Error{Code: 11, Msg: "Could not read config.toml: " + err.Error()}
+	}
+	if err := cmd.showStatus(lockJSON, cfg); err != nil {
+		return &Error{Code: 12, Msg: "Failed to show status: " + err.Error()}
+	}
+	return nil
+}
+
+func (cmd *statusCmd) showStatus(lockJSON *lockjson.LockJSON, cfg *config.Config) error {
+	currentReposList, err := lockJSON.GetCurrentReposList()
+	if err != nil {
+		return err
+	}
+	git, err := gitutil.NewBackend(cfg)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("current profile: %s\n", lockJSON.CurrentProfileName)
+	fmt.Println()
+	fmt.Println("repos:")
+	for i := range lockJSON.Repos {
+		repos := &lockJSON.Repos[i]
+		mark := " "
+		if currentReposList.Contains(repos.Path) {
+			mark = "*"
+		}
+		if repos.Version != "" {
+			fmt.Printf("%s %s (%s) %s\n", mark, repos.Path, repos.Type, shortHash(repos.Version))
+		} else {
+			fmt.Printf("%s %s (%s)\n", mark, repos.Path, repos.Type)
+		}
+		if repos.Type == lockjson.ReposSystemType {
+			cmd.showSystemRepos(git, repos, cfg.System.ReposPath)
+		}
+	}
+	return nil
+}
+
+// showSystemRepos shows the directory and HEAD commit of a system repository.
+// System repositories are managed by an administrator, so HEAD may differ from
+// the version in lock.json.
+func (cmd *statusCmd) showSystemRepos(git gitutil.Backend, repos *lockjson.Repos, dirs []string) {
+	fullpath := repos.Path.SystemFullPath(dirs)
+	if fullpath == "" {
+		fmt.Println("    path: (not found in system.repos_path)")
+		return
+	}
+	fmt.Printf("    path: %s\n", fullpath)
+	if !pathutil.Exists(filepath.Join(fullpath, ".git")) {
+		return
+	}
+	head, err := gitutil.GetHEAD(git, fullpath)
+	if err != nil {
+		err = errors.Wrap(err, "failed to get HEAD commit hash")
+		fmt.Printf("    HEAD: (%s)\n", err)
+		return
+	}
+	if head == repos.Version {
+		fmt.Printf("    HEAD: %s\n", shortHash(head))
+		return
+	}
+	fmt.Printf("    HEAD: %s (changed, run 'volt get -u %s' to lock it)\n", shortHash(head), repos.Path)
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
@@ -0,0 +1,82 @@
+package subcmd
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vim-volt/volt/config"
+	"github.com/vim-volt/volt/internal/testutil"
+	"github.com/vim-volt/volt/pathutil"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// Checks:
+// (A) Does not show `[ERROR]`, `[WARN]` messages
+// (B) Exit with zero status
+// (C) Shows current profile
+// (D) Shows the type and locked version of the system repository, and its directory
+// (E) Shows HEAD of the system repository
+// (F) Shows that HEAD was changed if an administrator updated the system repository
+
+// Shows the locked version and HEAD of system repositories (A, B, C, D, E, F)
+func TestVoltStatusSystemRepos(t *testing.T) {
+	// =============== setup =============== //
+
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+	reposPath := pathutil.ReposPath("localhost/system/hello")
+	sysDir, r := setUpSystemRepos(t, reposPath, config.SymlinkBuilder)
+	out, err := testutil.RunVolt("get", reposPath.String())
+	testutil.SuccessExit(t, out, err)
+	head, err := r.Head()
+	if err != nil {
+		t.Fatal("failed to get HEAD: " + err.Error())
+	}
+	locked := head.Hash().String()[:7]
+
+	// =============== run =============== //
+
+	out, err = testutil.RunVolt("status")
+	// (A, B)
+	testutil.SuccessExit(t, out, err)
+
+	// (C)
+	checkStatusOutput(t, out, "current profile: default")
+	// (D)
+	checkStatusOutput(t, out, fmt.Sprintf("* %s (system) %s", reposPath, locked))
+	checkStatusOutput(t, out, "path: "+filepath.Join(sysDir, "localhost", "system", "hello"))
+	// (E)
+	checkStatusOutput(t, out, "HEAD: "+locked+"\n")
+
+	// Administrator upgrades the system repository
+	w, err := r.Worktree()
+	if err != nil {
+		t.Fatal("failed to get worktree: " + err.Error())
+	}
+	next, err := w.Commit("upgrade", &git.CommitOptions{
+		Author: &object.Signature{Name: "John Doe", Email: "john@doe.org", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal("failed to commit: " + err.Error())
+	}
+
+	out, err = testutil.RunVolt("status")
+	// (A, B)
+	testutil.SuccessExit(t, out, err)
+
+	// (D)
+	checkStatusOutput(t, out, fmt.Sprintf("* %s (system) %s", reposPath, locked))
+	// (E, F)
+	checkStatusOutput(t, out, fmt.Sprintf("HEAD: %s (changed, run 'volt get -u %s' to lock it)", next.String()[:7], reposPath))
+}
+
+func checkStatusOutput(t *testing.T, out []byte, expected string) {
+	t.Helper()
+	if !bytes.Contains(out, []byte(expected)) {
+		t.Errorf("Output does not contain %q\n%s", expected, string(out))
+	}
+}
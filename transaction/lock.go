@@ -0,0 +1,71 @@
+package transaction
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+// LockDir returns the fullpath of $VOLTPATH/trx/lock directory, which exists
+// while a transaction is running.
+func LockDir() string {
+	return filepath.Join(pathutil.TrxDir(), "lock")
+}
+
+func lockOwnerFile() string {
+	return filepath.Join(LockDir(), "owner")
+}
+
+// LockOwner is the process which began the running transaction.
+// It is written to $VOLTPATH/trx/lock/owner to find a stale lock left by a
+// crashed process.
+type LockOwner struct {
+	PID      int    `json:"pid"`
+	Hostname string `json:"hostname"`
+}
+
+func writeLockOwner() error {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	content, err := json.Marshal(&LockOwner{PID: os.Getpid(), Hostname: hostname})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(lockOwnerFile(), content, 0644)
+}
+
+// ReadLockOwner reads the owner of $VOLTPATH/trx/lock.
+func ReadLockOwner() (*LockOwner, error) {
+	content, err := ioutil.ReadFile(lockOwnerFile())
+	if err != nil {
+		return nil, err
+	}
+	var owner LockOwner
+	if err := json.Unmarshal(content, &owner); err != nil {
+		return nil, errors.Wrap(err, "could not parse "+lockOwnerFile())
+	}
+	if owner.PID <= 0 || owner.Hostname == "" {
+		return nil, errors.New("invalid owner in " + lockOwnerFile())
+	}
+	return &owner, nil
+}
+
+// IsRunning returns true if the owner process is still running.
+// It returns an error if the owner is a process on other host, because it
+// cannot be checked.
+func (owner *LockOwner) IsRunning() (bool, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return false, err
+	}
+	if owner.Hostname != hostname {
+		return false, errors.Errorf("the process is on other host (%s)", owner.Hostname)
+	}
+	return processExists(owner.PID)
+}
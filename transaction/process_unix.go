@@ -0,0 +1,19 @@
+// +build !windows
+
+package transaction
+
+import (
+	"syscall"
+)
+
+func processExists(pid int) (bool, error) {
+	err := syscall.Kill(pid, syscall.Signal(0))
+	switch err {
+	case nil, syscall.EPERM:
+		return true, nil
+	case syscall.ESRCH:
+		return false, nil
+	default:
+		return false, err
+	}
+}
@@ -0,0 +1,30 @@
+// +build windows
+
+package transaction
+
+import (
+	"syscall"
+)
+
+const (
+	processQueryLimitedInformation = 0x1000
+	errorInvalidParameter          = syscall.Errno(87)
+	stillActive                    = 259
+)
+
+func processExists(pid int) (bool, error) {
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		// ERROR_INVALID_PARAMETER is returned if the process does not exist
+		if err == errorInvalidParameter {
+			return false, nil
+		}
+		return false, err
+	}
+	defer syscall.CloseHandle(h)
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return false, err
+	}
+	return code == stillActive, nil
+}
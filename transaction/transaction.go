@@ -8,20 +8,28 @@ import (
 	"unicode"
 
 	"github.com/pkg/errors"
+	"github.com/vim-volt/volt/logger"
 	"github.com/vim-volt/volt/pathutil"
 )
 
-// Start creates $VOLTPATH/trx/lock directory.
+// Start creates $VOLTPATH/trx/lock directory, and writes the owner process to
+// it.
 func Start() (Transaction, error) {
 	os.MkdirAll(pathutil.TrxDir(), 0755)
-	lockDir := filepath.Join(pathutil.TrxDir(), "lock")
+	lockDir := LockDir()
 	if err := os.Mkdir(lockDir, 0755); err != nil {
-		return nil, errors.Wrap(err, "failed to begin transaction: "+lockDir+" exists: if no other volt process is currently running, this probably means a volt process crashed earlier. Make sure no other volt process is running and remove the file manually to continue")
+		return nil, errors.Wrap(err, "failed to begin transaction: "+lockDir+" exists: if no other volt process is currently running, this probably means a volt process crashed earlier. Make sure no other volt process is running and remove the file manually (or run \"volt doctor -fix\") to continue")
+	}
+	if err := writeLockOwner(); err != nil {
+		os.RemoveAll(lockDir)
+		return nil, errors.Wrap(err, "failed to begin transaction")
 	}
 	trxID, err := genNewTrxID()
 	if err != nil {
+		os.RemoveAll(lockDir)
 		return nil, errors.Wrap(err, "could not allocate a new transaction ID")
 	}
+	logger.SetTransactionID(string(trxID))
 	return &transaction{id: trxID}, nil
 }
 
@@ -42,10 +50,38 @@ func (trx *transaction) ID() TrxID {
 	return trx.id
 }
 
-// Done removes $VOLTPATH/trx/lock directory.
+// Done renames $VOLTPATH/trx/lock directory to $VOLTPATH/trx/{trxid}, so that
+// the next transaction gets the next ID. Older "{trxid}" directories are
+// removed.
 func (trx *transaction) Done() error {
-	lockDir := filepath.Join(pathutil.TrxDir(), "lock")
-	return os.Remove(lockDir)
+	logger.SetTransactionID("")
+	lockDir := LockDir()
+	idDir := filepath.Join(pathutil.TrxDir(), string(trx.id))
+	os.RemoveAll(idDir)
+	os.Remove(lockOwnerFile())
+	if err := os.Rename(lockDir, idDir); err != nil {
+		return err
+	}
+	removeOldTrxDirs(string(trx.id))
+	return nil
+}
+
+// removeOldTrxDirs removes "{trxid}" directories whose ID is less than id.
+func removeOldTrxDirs(id string) {
+	trxDir, err := os.Open(pathutil.TrxDir())
+	if err != nil {
+		return
+	}
+	names, err := trxDir.Readdirnames(0)
+	trxDir.Close()
+	if err != nil {
+		return
+	}
+	for i := range names {
+		if isTrxDirName(names[i]) && greaterThan(id, names[i]) {
+			os.RemoveAll(filepath.Join(pathutil.TrxDir(), names[i]))
+		}
+	}
 }
 
 // genNewTrxID gets unallocated transaction ID looking $VOLTPATH/trx/ directory.
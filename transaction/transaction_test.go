@@ -0,0 +1,48 @@
+package transaction
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vim-volt/volt/internal/testutil"
+	"github.com/vim-volt/volt/pathutil"
+)
+
+func TestTransactionID(t *testing.T) {
+	testutil.SetUpEnv(t)
+	defer testutil.CleanUpEnv(t)
+
+	ids := make([]string, 0, 3)
+	for i := 0; i < 3; i++ {
+		trx, err := Start()
+		if err != nil {
+			t.Fatal("Start() failed: " + err.Error())
+		}
+		ids = append(ids, string(trx.ID()))
+		if err := trx.Done(); err != nil {
+			t.Fatal("Done() failed: " + err.Error())
+		}
+	}
+	if ids[0] == ids[1] || ids[1] == ids[2] || ids[0] == ids[2] {
+		t.Errorf("expected transaction IDs differ but got %v", ids)
+	}
+	for i := 1; i < len(ids); i++ {
+		if !greaterThan(ids[i], ids[i-1]) {
+			t.Errorf("expected transaction IDs increase but got %v", ids)
+		}
+	}
+
+	// Only the last transaction directory is kept
+	entries, err := ioutil.ReadDir(pathutil.TrxDir())
+	if err != nil {
+		t.Fatal("failed to read trx directory: " + err.Error())
+	}
+	if len(entries) != 1 || entries[0].Name() != ids[len(ids)-1] {
+		t.Errorf("expected only %s in %s but got %d entries", ids[len(ids)-1], pathutil.TrxDir(), len(entries))
+	}
+	if _, err := os.Stat(filepath.Join(pathutil.TrxDir(), "lock")); !os.IsNotExist(err) {
+		t.Error("expected lock directory is removed")
+	}
+}